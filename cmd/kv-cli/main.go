@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
@@ -11,8 +13,11 @@ import (
 	"time"
 
 	"github.com/heysubinoy/pyazdb/api/proto"
+	"github.com/heysubinoy/pyazdb/internal/netutil"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 type LeaderInfo struct {
@@ -24,7 +29,14 @@ type LeaderInfo struct {
 }
 
 func getLeaderGRPCAddr(mandiAddr string) (string, error) {
-	resp, err := http.Get(mandiAddr + "/leader")
+	req, err := http.NewRequest(http.MethodGet, mandiAddr+"/leader", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to query mandi: %w", err)
+	}
+	if token := os.Getenv("MANDI_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to query mandi: %w", err)
 	}
@@ -62,71 +74,205 @@ func getLeaderGRPCAddr(mandiAddr string) (string, error) {
 }
 
 func main() {
-	if len(os.Args) < 2 {
+	addr := flag.String("addr", os.Getenv("PYAZ_ADDR"), "gRPC address of a node to talk to directly (default: discover the leader via mandi)")
+	timeout := flag.Duration("timeout", 5*time.Second, "timeout for each attempt")
+	retries := flag.Int("retries", 3, "retries after a failed attempt (twice as many while no leader is known)")
+	tlsCA := flag.String("tls-ca", os.Getenv("PYAZ_TLS_CA"), "CA certificate (PEM) to verify a TLS server with; enables TLS")
+	flag.Usage = printUsage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 1 {
 		printUsage()
 		os.Exit(1)
 	}
-
-	// Get mandi address from environment or use default
-	mandiAddr := os.Getenv("MANDI_ADDR")
-	if mandiAddr == "" {
-		mandiAddr = "http://127.0.0.1:7000"
-	}
-
-	// Discover leader from mandi
-	leaderAddr, err := getLeaderGRPCAddr(mandiAddr)
-	if err != nil {
-		log.Fatalf("Failed to discover leader: %v", err)
-	}
-
-	fmt.Printf("Connecting to leader at %s\n", leaderAddr)
-
-	// Connect to gRPC server using passthrough resolver for direct address connection
-	conn, err := grpc.NewClient("passthrough:///"+leaderAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		log.Fatalf("Failed to connect: %v", err)
+	if *retries < 0 {
+		log.Fatalf("--retries must not be negative")
 	}
-	defer conn.Close()
-
-	client := proto.NewKVServiceClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
 
-	command := os.Args[1]
+	var name string
+	var call func(ctx context.Context, client proto.KVServiceClient) error
+	command := args[0]
 
 	switch command {
 	case "get":
-		if len(os.Args) < 3 {
+		if len(args) < 2 {
 			fmt.Println("Usage: kv-cli get <key>")
 			os.Exit(1)
 		}
-		handleGet(ctx, client, os.Args[2])
+		name = "Get"
+		call = func(ctx context.Context, client proto.KVServiceClient) error {
+			return handleGet(ctx, client, args[1])
+		}
 
 	case "set":
-		if len(os.Args) < 4 {
+		if len(args) < 3 {
 			fmt.Println("Usage: kv-cli set <key> <value>")
 			os.Exit(1)
 		}
-		handleSet(ctx, client, os.Args[2], os.Args[3])
+		name = "Set"
+		call = func(ctx context.Context, client proto.KVServiceClient) error {
+			return handleSet(ctx, client, args[1], args[2])
+		}
 
 	case "delete":
-		if len(os.Args) < 3 {
+		if len(args) < 2 {
 			fmt.Println("Usage: kv-cli delete <key>")
 			os.Exit(1)
 		}
-		handleDelete(ctx, client, os.Args[2])
+		name = "Delete"
+		call = func(ctx context.Context, client proto.KVServiceClient) error {
+			return handleDelete(ctx, client, args[1])
+		}
+
+	case "scan", "keys":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		values := fs.Bool("values", false, "print each key's value after a tab")
+		limit := fs.Int("limit", 0, "print at most this many keys (0 = all)")
+		fs.Parse(args[1:])
+		prefix := ""
+		if command == "scan" {
+			if fs.NArg() < 1 {
+				fmt.Println("Usage: kv-cli scan [--values] [--limit N] <prefix>")
+				os.Exit(1)
+			}
+			prefix = fs.Arg(0)
+		}
+		if *limit < 0 {
+			log.Fatalf("--limit must not be negative")
+		}
+		name = "Scan"
+		call = func(ctx context.Context, client proto.KVServiceClient) error {
+			return handleScan(ctx, client, prefix, *limit, *values)
+		}
+
+	case "ping":
+		name = "Ping"
+		call = func(ctx context.Context, client proto.KVServiceClient) error {
+			return handlePing(ctx, client)
+		}
 
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 		printUsage()
 		os.Exit(1)
 	}
+
+	creds := insecure.NewCredentials()
+	if *tlsCA != "" {
+		var err error
+		creds, err = netutil.ClientTLS(*tlsCA)
+		if err != nil {
+			log.Fatalf("Failed to set up TLS: %v", err)
+		}
+	}
+
+	// Get mandi address from environment or use default
+	mandiAddr := os.Getenv("MANDI_ADDR")
+	if mandiAddr == "" {
+		mandiAddr = "http://127.0.0.1:7000"
+	}
+
+	// Each attempt rediscovers the leader, so a retry after an election
+	// reaches the new one.
+	lastAddr := ""
+	attempts, err := withRetries(*retries, func() error {
+		leaderAddr := *addr
+		if leaderAddr == "" {
+			var err error
+			leaderAddr, err = getLeaderGRPCAddr(mandiAddr)
+			if err != nil {
+				return &discoveryError{err: err}
+			}
+		}
+		if leaderAddr != lastAddr {
+			fmt.Fprintf(os.Stderr, "Connecting to %s\n", leaderAddr)
+			lastAddr = leaderAddr
+		}
+
+		// Connect to gRPC server using passthrough resolver for direct address connection
+		conn, err := grpc.NewClient("passthrough:///"+leaderAddr, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return fmt.Errorf("failed to connect: %w", err)
+		}
+		defer conn.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		return call(ctx, proto.NewKVServiceClient(conn))
+	})
+	if err != nil {
+		log.Fatalf("%s failed after %d attempt(s): %v", name, attempts, err)
+	}
+}
+
+// discoveryError marks a failed leader lookup, which during an election
+// usually means no leader is known yet.
+type discoveryError struct {
+	err error
+}
+
+func (e *discoveryError) Error() string {
+	return "failed to discover leader: " + e.err.Error()
+}
+
+func (e *discoveryError) Unwrap() error {
+	return e.err
+}
+
+// Retry backoff: doubling from retryBackoff up to maxRetryBackoff.
+const (
+	retryBackoff    = 250 * time.Millisecond
+	maxRetryBackoff = 5 * time.Second
+)
+
+// withRetries runs attempt until it succeeds, fails with an error that
+// retrying won't fix, or runs out of retries, sleeping with exponential
+// backoff in between. Errors meaning no leader is known get twice the
+// retries, since an election usually settles within a few seconds.
+// Returns the number of attempts made and the last error.
+func withRetries(retries int, attempt func() error) (int, error) {
+	backoff := retryBackoff
+	for n := 1; ; n++ {
+		err := attempt()
+		if err == nil {
+			return n, nil
+		}
+		limit := retries
+		if leaderUnknown(err) {
+			limit = 2 * retries
+		} else if !retryable(err) {
+			return n, err
+		}
+		if n > limit {
+			return n, err
+		}
+		fmt.Fprintf(os.Stderr, "Attempt %d failed: %v; retrying in %s\n", n, err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
 }
 
-func handleGet(ctx context.Context, client proto.KVServiceClient, key string) {
+// leaderUnknown reports whether err means the cluster has no reachable
+// leader right now: mandi has none to offer, or the node is unavailable.
+func leaderUnknown(err error) bool {
+	var de *discoveryError
+	return errors.As(err, &de) || status.Code(err) == codes.Unavailable
+}
+
+// retryable reports whether err may go away on its own, such as an
+// attempt timing out while the cluster is busy.
+func retryable(err error) bool {
+	return status.Code(err) == codes.DeadlineExceeded
+}
+
+func handleGet(ctx context.Context, client proto.KVServiceClient, key string) error {
 	resp, err := client.Get(ctx, &proto.GetRequest{Key: key})
 	if err != nil {
-		log.Fatalf("Get failed: %v", err)
+		return err
 	}
 
 	if resp.Found {
@@ -135,39 +281,101 @@ func handleGet(ctx context.Context, client proto.KVServiceClient, key string) {
 		fmt.Printf("Key '%s' not found\n", key)
 		os.Exit(1)
 	}
+	return nil
 }
 
-func handleSet(ctx context.Context, client proto.KVServiceClient, key, value string) {
+func handleSet(ctx context.Context, client proto.KVServiceClient, key, value string) error {
 	resp, err := client.Set(ctx, &proto.SetRequest{
 		Key:   key,
 		Value: value,
 	})
 	if err != nil {
-		log.Fatalf("Set failed: %v", err)
+		return err
 	}
 
 	if resp.Success {
 		fmt.Printf("Set '%s' = '%s'\n", key, value)
 	}
+	return nil
 }
 
-func handleDelete(ctx context.Context, client proto.KVServiceClient, key string) {
+func handleDelete(ctx context.Context, client proto.KVServiceClient, key string) error {
 	resp, err := client.Delete(ctx, &proto.DeleteRequest{Key: key})
 	if err != nil {
-		log.Fatalf("Delete failed: %v", err)
+		return err
 	}
 
-	if resp.Success {
+	if resp.Success && resp.Existed {
 		fmt.Printf("Deleted '%s'\n", key)
+	} else if resp.Success {
+		fmt.Printf("Key '%s' did not exist\n", key)
 	}
+	return nil
+}
+
+// handleScan prints the pairs under prefix one per line as the server
+// streams them, so large results are never held in memory. Once anything
+// has been printed a failure is not retried, since a retry would print the
+// same keys again.
+func handleScan(ctx context.Context, client proto.KVServiceClient, prefix string, limit int, values bool) error {
+	stream, err := client.Scan(ctx, &proto.ScanRequest{Prefix: prefix, Limit: int32(limit)})
+	if err != nil {
+		return err
+	}
+	printed := 0
+	for {
+		pair, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if printed > 0 {
+				return fmt.Errorf("scan interrupted after %d key(s): %v", printed, err)
+			}
+			return err
+		}
+		if values {
+			fmt.Printf("%s\t%s\n", pair.Key, pair.Value)
+		} else {
+			fmt.Println(pair.Key)
+		}
+		printed++
+	}
+}
+
+// handlePing prints the status of the node it reached. With --addr that is
+// the given node; otherwise it is the leader mandi advertises.
+func handlePing(ctx context.Context, client proto.KVServiceClient) error {
+	resp, err := client.Ping(ctx, &proto.PingRequest{})
+	if err != nil {
+		return err
+	}
+	leader := resp.LeaderAddr
+	if leader == "" {
+		leader = "unknown"
+	}
+	fmt.Printf("node=%s state=%s leader=%s\n", resp.NodeId, resp.RaftState, leader)
+	return nil
 }
 
 func printUsage() {
 	fmt.Println("Usage:")
-	fmt.Println("  kv-cli get <key>")
-	fmt.Println("  kv-cli set <key> <value>")
-	fmt.Println("  kv-cli delete <key>")
+	fmt.Println("  kv-cli [flags] get <key>")
+	fmt.Println("  kv-cli [flags] set <key> <value>")
+	fmt.Println("  kv-cli [flags] delete <key>")
+	fmt.Println("  kv-cli [flags] scan [--values] [--limit N] <prefix>")
+	fmt.Println("  kv-cli [flags] keys [--values] [--limit N]")
+	fmt.Println("  kv-cli [flags] ping")
+	fmt.Println("")
+	fmt.Println("Flags:")
+	fmt.Println("  --addr     gRPC address of a node to talk to directly (default: discover the leader via mandi)")
+	fmt.Println("  --timeout  Timeout for each attempt (default: 5s)")
+	fmt.Println("  --retries  Retries after a failed attempt, with exponential backoff; doubled while no leader is known (default: 3)")
+	fmt.Println("  --tls-ca   CA certificate (PEM) to verify a TLS server with; enables TLS")
 	fmt.Println("")
 	fmt.Println("Environment variables:")
+	fmt.Println("  PYAZ_ADDR  - Default for --addr")
+	fmt.Println("  PYAZ_TLS_CA - Default for --tls-ca")
 	fmt.Println("  MANDI_ADDR - Mandi discovery service address (default: http://127.0.0.1:7000)")
+	fmt.Println("  MANDI_TOKEN - Bearer token for mandi, if it protects reads")
 }
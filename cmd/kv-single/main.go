@@ -2,12 +2,19 @@ package main
 
 import (
 	"bytes"
+	"cmp"
+	"context"
 	"encoding/json"
-	"log"
-	"net"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/hashicorp/raft"
@@ -15,10 +22,15 @@ import (
 
 	"github.com/heysubinoy/pyazdb/api/proto"
 	"github.com/heysubinoy/pyazdb/internal/api"
+	"github.com/heysubinoy/pyazdb/internal/export"
+	"github.com/heysubinoy/pyazdb/internal/logging"
+	"github.com/heysubinoy/pyazdb/internal/netutil"
 	"github.com/heysubinoy/pyazdb/internal/store"
 	"github.com/heysubinoy/pyazdb/pkg/config"
+	"github.com/heysubinoy/pyazdb/pkg/kv"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 /* ---------------- Discovery Types ---------------- */
@@ -33,24 +45,37 @@ type LeaderInfo struct {
 }
 
 type JoinRequest struct {
-	ID        string    `json:"id"`
-	Addr      string    `json:"addr"`
-	StartedAt time.Time `json:"started_at"`
+	ID         string    `json:"id"`
+	Addr       string    `json:"addr"`
+	StartedAt  time.Time `json:"started_at"`
+	TTLSeconds int       `json:"ttl_seconds,omitempty"`
 }
 
 /* ---------------- Raft Setup ---------------- */
 
-func setupRaft(mem *store.MemStore, nodeID, bindAddr, dataDir string, bootstrap bool) *store.RaftStore {
+func setupRaft(mem *store.MemStore, nodeCfg *config.Config) *store.RaftStore {
+	nodeID, bindAddr, dataDir, bootstrap := nodeCfg.NodeID, nodeCfg.RaftAddr, nodeCfg.RaftData, nodeCfg.RaftLeader
 	_ = os.MkdirAll(dataDir, 0700)
 
 	cfg := raft.DefaultConfig()
 	cfg.LocalID = raft.ServerID(nodeID)
 
-	// Safer defaults
-	cfg.HeartbeatTimeout = 2 * time.Second
-	cfg.ElectionTimeout = 3 * time.Second
-	cfg.LeaderLeaseTimeout = 1 * time.Second
-	cfg.CommitTimeout = 500 * time.Millisecond
+	// Failure detection; config fills in the defaults.
+	cfg.HeartbeatTimeout = nodeCfg.HeartbeatTimeout
+	cfg.ElectionTimeout = nodeCfg.ElectionTimeout
+	cfg.LeaderLeaseTimeout = nodeCfg.LeaderLeaseTimeout
+	cfg.CommitTimeout = nodeCfg.CommitTimeout
+
+	// Log compaction; zero keeps Raft's default.
+	if nodeCfg.SnapshotInterval > 0 {
+		cfg.SnapshotInterval = nodeCfg.SnapshotInterval
+	}
+	if nodeCfg.SnapshotThreshold > 0 {
+		cfg.SnapshotThreshold = uint64(nodeCfg.SnapshotThreshold)
+	}
+	if nodeCfg.TrailingLogs > 0 {
+		cfg.TrailingLogs = uint64(nodeCfg.TrailingLogs)
+	}
 
 	logStore, _ := raftboltdb.NewBoltStore(filepath.Join(dataDir, "raft-log.bolt"))
 	stableStore, _ := raftboltdb.NewBoltStore(filepath.Join(dataDir, "raft-stable.bolt"))
@@ -58,26 +83,42 @@ func setupRaft(mem *store.MemStore, nodeID, bindAddr, dataDir string, bootstrap
 
 	transport, err := raft.NewTCPTransport(bindAddr, nil, 3, 10*time.Second, os.Stdout)
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal("Failed to start Raft transport", "addr", bindAddr, "error", err)
 	}
 
-	fsm := store.NewRaftStore(mem, nil)
-	r, err := raft.NewRaft(cfg, fsm, logStore, stableStore, snapshots, transport)
+	// The same RaftStore is raft's FSM and the API's store, so state kept
+	// on it (watchers, snapshot settings) is shared by both sides.
+	rs := store.NewRaftStore(mem, nil)
+	// The tracker sees followers' acknowledgments, for min_replicas.
+	tracker := store.NewReplicationTracker(transport)
+	rs.SetReplicationTracker(tracker)
+	r, err := raft.NewRaft(cfg, rs, logStore, stableStore, snapshots, tracker)
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal("Failed to start Raft", "error", err)
 	}
+	rs.SetRaft(r)
 
-	rs := store.NewRaftStore(mem, r)
-
-	hasState, _ := raft.HasExistingState(logStore, stableStore, snapshots)
+	// Only bootstrap a brand-new leader. Bootstrapping over existing state,
+	// or on a node meant to join, would form a separate one-node cluster.
+	hasState, err := raft.HasExistingState(logStore, stableStore, snapshots)
+	if err != nil {
+		logging.Fatal("Failed to inspect Raft state", "dir", dataDir, "error", err)
+	}
 
-	if bootstrap && !hasState {
-		r.BootstrapCluster(raft.Configuration{
+	switch {
+	case !bootstrap:
+		slog.Info("Not configured as leader; skipping bootstrap and waiting to join")
+	case hasState:
+		slog.Info("Existing Raft state found; skipping bootstrap and resuming", "dir", dataDir)
+	default:
+		if err := r.BootstrapCluster(raft.Configuration{
 			Servers: []raft.Server{
 				{ID: raft.ServerID(nodeID), Address: raft.ServerAddress(bindAddr)},
 			},
-		})
-		log.Println("Cluster bootstrapped")
+		}).Error(); err != nil {
+			logging.Fatal("Failed to bootstrap cluster", "error", err)
+		}
+		slog.Info("No Raft state found; cluster bootstrapped")
 	}
 
 	return rs
@@ -85,6 +126,32 @@ func setupRaft(mem *store.MemStore, nodeID, bindAddr, dataDir string, bootstrap
 
 /* ---------------- Discovery Helpers ---------------- */
 
+// mandiToken is sent as a bearer token on every mandi request.
+var mandiToken string
+
+// mandiClient bounds every call to mandi, so the registration and join
+// loops keep ticking when mandi hangs.
+var mandiClient = &http.Client{Timeout: 5 * time.Second}
+
+// mandiRequest sends a request to mandi. A non-nil body is sent as JSON.
+func mandiRequest(method, url string, body []byte) (*http.Response, error) {
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, rd)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if mandiToken != "" {
+		req.Header.Set("Authorization", "Bearer "+mandiToken)
+	}
+	return mandiClient.Do(req)
+}
+
 func registerLeader(mandi, nodeID, addr, httpAddr, grpcAddr string, r *raft.Raft) error {
 	// Extract hostname from raft addr (e.g., "pyazdb-node1:12000" -> "pyazdb-node1")
 	hostname := "localhost"
@@ -118,21 +185,82 @@ func registerLeader(mandi, nodeID, addr, httpAddr, grpcAddr string, r *raft.Raft
 	}
 
 	data, _ := json.Marshal(info)
-	req, _ := http.NewRequest(http.MethodPut, mandi+"/leader", bytes.NewBuffer(data))
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := mandiRequest(http.MethodPut, mandi+"/leader", data)
 	if err != nil {
 		return err
 	}
 	resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("mandi rejected leader registration: check MANDI_TOKEN")
+	}
 	return nil
 }
 
-func postJoin(mandi, nodeID, addr string) {
-	j := JoinRequest{ID: nodeID, Addr: addr}
+func postJoin(mandi, nodeID, addr string, ttl time.Duration) {
+	j := JoinRequest{ID: nodeID, Addr: addr, TTLSeconds: int(ttl / time.Second)}
 	b, _ := json.Marshal(j)
-	http.Post(mandi+"/join-requests", "application/json", bytes.NewBuffer(b))
+	resp, err := mandiRequest(http.MethodPost, mandi+"/join-requests", b)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		slog.Warn("mandi rejected join request: check MANDI_TOKEN")
+	}
+}
+
+// leaveMandi withdraws the node from discovery as it shuts down: it drops
+// any pending join request and, if the node was leader, the leader record,
+// so nobody is pointed at it until the records expire. Failures are only
+// logged, since mandi forgets both on its own eventually.
+func leaveMandi(mandi, nodeID string, wasLeader bool) {
+	paths := []string{"/join-requests"}
+	if wasLeader {
+		paths = append(paths, "/leader")
+	}
+	for _, path := range paths {
+		resp, err := mandiRequest(http.MethodDelete, mandi+path+"?id="+url.QueryEscape(nodeID), nil)
+		if err != nil {
+			slog.Warn("Failed to deregister from mandi", "path", path, "error", err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			slog.Warn("Failed to deregister from mandi", "path", path, "status", resp.StatusCode)
+		}
+	}
+}
+
+// discoverLeader polls mandi for a leader record, backing off exponentially,
+// until one is found or timeout elapses. It lets a node that boots before
+// mandi (or before the leader registers) wait instead of starting blind.
+func discoverLeader(mandi string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	backoff := 250 * time.Millisecond
+
+	for attempt := 1; ; attempt++ {
+		resp, err := mandiRequest(http.MethodGet, mandi+"/leader", nil)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				slog.Info("Discovered leader via mandi", "attempt", attempt)
+				return true
+			}
+			err = fmt.Errorf("status %d", resp.StatusCode)
+		}
+
+		if time.Now().Add(backoff).After(deadline) {
+			slog.Warn("Mandi discovery failed; giving up", "attempt", attempt, "error", err)
+			return false
+		}
+		slog.Info("Mandi discovery failed; retrying", "attempt", attempt, "error", err, "backoff", backoff)
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if backoff > 5*time.Second {
+			backoff = 5 * time.Second
+		}
+	}
 }
 
 /* ---------------- Leader Loop ---------------- */
@@ -146,12 +274,12 @@ func monitorLeadership(mandi, nodeID, raftAddr, httpAddr, grpcAddr string, r *ra
 			time.Sleep(500 * time.Millisecond)
 		}
 
-		log.Println("Became leader, starting leader duties")
+		slog.Info("Became leader, starting leader duties", "term", r.CurrentTerm())
 
 		// Run leader duties until we lose leadership
 		runLeaderDuties(mandi, nodeID, raftAddr, httpAddr, grpcAddr, r)
 
-		log.Println("Lost leadership, waiting for next election")
+		slog.Info("Lost leadership, waiting for next election", "term", r.CurrentTerm())
 	}
 }
 
@@ -172,7 +300,7 @@ func runLeaderDuties(mandi, nodeID, raftAddr, httpAddr, grpcAddr string, r *raft
 			_ = registerLeader(mandi, nodeID, raftAddr, httpAddr, grpcAddr, r)
 
 		case <-joinTicker.C:
-			resp, err := http.Get(mandi + "/join-requests")
+			resp, err := mandiRequest(http.MethodGet, mandi+"/join-requests", nil)
 			if err != nil {
 				continue
 			}
@@ -186,7 +314,7 @@ func runLeaderDuties(mandi, nodeID, raftAddr, httpAddr, grpcAddr string, r *raft
 					continue
 				}
 
-				log.Printf("Adding non-voter %s", j.ID)
+				slog.Info("Adding non-voter", "peer_id", j.ID, "peer_addr", j.Addr)
 
 				f := r.AddNonvoter(
 					raft.ServerID(j.ID),
@@ -194,7 +322,8 @@ func runLeaderDuties(mandi, nodeID, raftAddr, httpAddr, grpcAddr string, r *raft
 					0,
 					10*time.Second,
 				)
-				if f.Error() != nil {
+				if err := f.Error(); err != nil {
+					slog.Warn("Failed to add non-voter", "peer_id", j.ID, "error", err)
 					continue
 				}
 
@@ -207,18 +336,19 @@ func runLeaderDuties(mandi, nodeID, raftAddr, httpAddr, grpcAddr string, r *raft
 					10*time.Second,
 				)
 				if err := p.Error(); err != nil {
-					log.Printf("Failed to promote %s to voter: %v", j.ID, err)
+					slog.Warn("Failed to promote to voter", "peer_id", j.ID, "error", err)
 					continue
 				}
 
-				req, _ := http.NewRequest(
+				if resp, err := mandiRequest(
 					http.MethodDelete,
-					mandi+"/join-requests?id="+j.ID,
+					mandi+"/join-requests?id="+url.QueryEscape(j.ID),
 					nil,
-				)
-				http.DefaultClient.Do(req)
+				); err == nil {
+					resp.Body.Close()
+				}
 
-				log.Printf("Node %s promoted to voter", j.ID)
+				slog.Info("Node promoted to voter", "peer_id", j.ID)
 			}
 		}
 	}
@@ -226,18 +356,18 @@ func runLeaderDuties(mandi, nodeID, raftAddr, httpAddr, grpcAddr string, r *raft
 
 /* ---------------- Non-Leader Loop ---------------- */
 
-func nonLeaderLoop(mandi, nodeID, raftAddr string, r *raft.Raft) {
+func nonLeaderLoop(mandi, nodeID, raftAddr string, joinTTL time.Duration, r *raft.Raft) {
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
 
 	for {
-		postJoin(mandi, nodeID, raftAddr)
+		postJoin(mandi, nodeID, raftAddr, joinTTL)
 
 		cfg := r.GetConfiguration()
 		if cfg.Error() == nil {
 			for _, s := range cfg.Configuration().Servers {
 				if string(s.ID) == nodeID {
-					log.Println("Joined cluster successfully")
+					slog.Info("Joined cluster successfully")
 					return
 				}
 			}
@@ -247,44 +377,341 @@ func nonLeaderLoop(mandi, nodeID, raftAddr string, r *raft.Raft) {
 	}
 }
 
+// startRaft sets up the in-memory store replicated with Raft, applies the
+// Raft-only settings from cfg, and starts the leadership and join loops.
+func startRaft(cfg *config.Config) (*store.RaftStore, *raft.Raft) {
+	mem := store.NewMemStore()
+	rs := setupRaft(mem, cfg)
+	rs.SetTTLJitter(cfg.TTLJitterPercent)
+	rs.SetSoftDeleteWindow(cfg.SoftDeleteWindow)
+	rs.SetMaxEntryBytes(cfg.MaxEntryBytes)
+	rs.SetApplyTimeout(cfg.ApplyTimeout)
+	if err := rs.SetSnapshotCompression(cfg.SnapshotCompression); err != nil {
+		logging.Fatal("Invalid snapshot compression", "error", err)
+	}
+	if len(cfg.NamespaceQuotas) > 0 {
+		quotas := make(map[string]store.NamespaceQuota, len(cfg.NamespaceQuotas))
+		for ns, q := range cfg.NamespaceQuotas {
+			quotas[ns] = store.NamespaceQuota{MaxKeys: q.MaxKeys, MaxBytes: q.MaxBytes}
+		}
+		rs.SetQuotas(quotas)
+	}
+	go rs.RunSweeper(time.Second)
+
+	r := rs.GetRaft()
+
+	// Always monitor for leadership changes - any node can become leader
+	go monitorLeadership(cfg.MandiAddr, cfg.NodeID, cfg.RaftAddr, cfg.HTTPAddr, cfg.GRPCAddr, r)
+
+	// Non-leader nodes should try to join the cluster
+	if !cfg.RaftLeader {
+		if cfg.MandiStartupTimeout > 0 && !discoverLeader(cfg.MandiAddr, cfg.MandiStartupTimeout) {
+			if cfg.FallbackLeaderHTTPAddr != "" || cfg.FallbackLeaderGRPCAddr != "" {
+				slog.Warn("Mandi unreachable; forwarding to static leader",
+					"http_addr", cfg.FallbackLeaderHTTPAddr, "grpc_addr", cfg.FallbackLeaderGRPCAddr)
+			} else {
+				slog.Warn("Mandi unreachable and no fallback leader configured; writes will fail until a leader registers")
+			}
+		}
+		go nonLeaderLoop(cfg.MandiAddr, cfg.NodeID, cfg.RaftAddr, cfg.JoinRequestTTL, r)
+	}
+
+	return rs, r
+}
+
 /* ---------------- Main ---------------- */
 
 func main() {
-	mem := store.NewMemStore()
-
 	// NODE_CONFIG is now optional - if not set, will use environment variables
 	cfgPath := os.Getenv("NODE_CONFIG")
 
 	cfg, err := config.LoadConfig(cfgPath)
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		logging.Fatal("Failed to load config", "error", err)
 	}
+	if err := logging.Setup(cfg.LogLevel, cfg.LogFormat, "node_id", cfg.NodeID); err != nil {
+		logging.Fatal("Failed to set up logging", "error", err)
+	}
+	mandiToken = cfg.MandiToken
 
-	rs := setupRaft(mem, cfg.NodeID, cfg.RaftAddr, cfg.RaftData, cfg.RaftLeader)
-
+	var kvStore kv.Store
+	var rs *store.RaftStore
 	var r *raft.Raft
-	if g, ok := interface{}(rs).(interface{ GetRaft() *raft.Raft }); ok {
-		r = g.GetRaft()
+	var boltStore *store.BoltStore
+	switch cfg.StorageBackend {
+	case "bolt":
+		boltStore, err = store.NewBoltStore(cfg.StoragePath)
+		if err != nil {
+			logging.Fatal("Failed to open storage", "path", cfg.StoragePath, "error", err)
+		}
+		slog.Info("Storing data in bolt; running as a single node without Raft", "path", cfg.StoragePath)
+		kvStore = boltStore
+	case "cache":
+		mem := store.NewMemStoreLRU(cfg.StorageMaxEntries)
+		go mem.RunSweeper(time.Second)
+		slog.Info("Caching keys in memory; running as a single node without Raft", "max_entries", cfg.StorageMaxEntries)
+		kvStore = mem
+	default:
+		rs, r = startRaft(cfg)
+		kvStore = rs
 	}
 
-	// Always monitor for leadership changes - any node can become leader
-	go monitorLeadership(cfg.MandiAddr, cfg.NodeID, cfg.RaftAddr, cfg.HTTPAddr, cfg.GRPCAddr, r)
+	var readCache *store.ReadCacheStore
+	if cfg.ReadCacheTTL > 0 {
+		maxEntries := cfg.ReadCacheMaxEntries
+		if maxEntries <= 0 {
+			maxEntries = 10000
+		}
+		readCache = store.NewReadCacheStore(kvStore, cfg.ReadCacheTTL, maxEntries)
+		go readCache.RunSweeper(time.Second)
+		kvStore = readCache
+		slog.Info("Read cache enabled; reads may be stale", "ttl", cfg.ReadCacheTTL, "max_entries", maxEntries)
+	}
+	if cfg.CaseInsensitiveKeys {
+		// Fold before Raft so every replica applies the same key.
+		kvStore = store.NewCaseFoldStore(kvStore)
+		slog.Info("Case-insensitive keys enabled")
+	}
+	instrumented := store.NewInstrumentedStore(kvStore)
+	if cfg.HotKeysCapacity > 0 {
+		instrumented.EnableHotKeys(cfg.HotKeysCapacity)
+		slog.Info("Tracking hot keys", "capacity", cfg.HotKeysCapacity)
+	}
+	if readCache != nil {
+		instrumented.SetReadCache(readCache)
+	}
 
-	// Non-leader nodes should try to join the cluster
-	if !cfg.RaftLeader {
-		go nonLeaderLoop(cfg.MandiAddr, cfg.NodeID, cfg.RaftAddr, r)
+	if cfg.MetricsExporter != "" {
+		exp, err := export.New(cfg.MetricsExporter, cfg.MetricsExportAddr)
+		if err != nil {
+			logging.Fatal("Failed to create metrics exporter", "error", err)
+		}
+		interval := cfg.MetricsExportInterval
+		if interval <= 0 {
+			interval = 10 * time.Second
+		}
+		go export.Run(instrumented, exp, interval)
+		slog.Info("Exporting metrics", "exporter", cfg.MetricsExporter, "addr", cfg.MetricsExportAddr, "interval", interval)
+	}
+
+	if cfg.MetricsLogInterval > 0 {
+		go export.RunLogger(instrumented, cfg.MetricsLogInterval, cfg.MetricsLogReset)
+	}
+
+	var lease *api.LeaseTracker
+	if r != nil {
+		lease = api.NewLeaseTracker(r)
+	}
+
+	listenOpts := netutil.ListenOptions{Backlog: cfg.ListenBacklog, ReusePort: cfg.ReusePort}
+
+	var grpcOpts []grpc.ServerOption
+	var dialCreds credentials.TransportCredentials
+	if cfg.TLSCertFile != "" {
+		serverCreds, err := netutil.ServerTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			logging.Fatal("Failed to set up gRPC TLS", "error", err)
+		}
+		dialCreds, err = netutil.ClientTLS(cfg.TLSCAFile)
+		if err != nil {
+			logging.Fatal("Failed to set up gRPC TLS", "error", err)
+		}
+		grpcOpts = append(grpcOpts, grpc.Creds(serverCreds))
+		ca := cfg.TLSCAFile
+		if ca == "" {
+			ca = "system roots"
+		}
+		slog.Info("gRPC TLS enabled", "cert", cfg.TLSCertFile, "ca", ca)
+	} else {
+		slog.Info("gRPC TLS disabled; serving and forwarding without transport security")
 	}
 
+	grpcOpts = append(grpcOpts, grpc.UnaryInterceptor(api.ConcurrencyLimiter(cfg.MaxConcurrentRequests)))
+	grpcServer := grpc.NewServer(grpcOpts...)
+	grpcSrv := api.NewGRPCServer(instrumented, r, cfg.GRPCAddr, cfg.MandiAddr)
+	grpcSrv.NodeID = cfg.NodeID
+	grpcSrv.MandiToken = cfg.MandiToken
+	grpcSrv.DialCreds = dialCreds
+	grpcSrv.MaxKeyBytes = cfg.MaxKeyBytes
+	grpcSrv.MaxValueBytes = cfg.MaxValueBytes
+	grpcSrv.Metrics = instrumented
+	grpcSrv.MaxForwardHops = cfg.MaxForwardHops
+	grpcSrv.FallbackLeaderAddr = cfg.FallbackLeaderGRPCAddr
+	grpcSrv.Lease = lease
+	grpcSrv.StaleReads = cfg.StaleReads
+	proto.RegisterKVServiceServer(grpcServer, grpcSrv)
+
 	go func() {
-		lis, _ := net.Listen("tcp", cfg.GRPCAddr)
-		s := grpc.NewServer()
-		proto.RegisterKVServiceServer(s, api.NewGRPCServer(rs, r, cfg.GRPCAddr, cfg.MandiAddr))
-		s.Serve(lis)
+		lis, err := netutil.Listen(cfg.GRPCAddr, listenOpts)
+		if err != nil {
+			logging.Fatal("Failed to listen", "addr", cfg.GRPCAddr, "error", err)
+		}
+		grpcServer.Serve(lis)
 	}()
 
-	httpSrv := api.NewServer(rs, r, cfg.MandiAddr, cfg.HTTPAddr)
+	httpSrv := api.NewServer(instrumented, r, cfg.MandiAddr, cfg.HTTPAddr)
+	httpSrv.NodeID = cfg.NodeID
+	httpSrv.RaftAddr = cfg.RaftAddr
+	httpSrv.MandiToken = cfg.MandiToken
+	httpSrv.MaxKeyBytes = cfg.MaxKeyBytes
+	httpSrv.MaxValueBytes = cfg.MaxValueBytes
+	httpSrv.MaxEntryBytes = cfg.MaxEntryBytes
+	httpSrv.MaxForwardHops = cfg.MaxForwardHops
+	httpSrv.ForwardClient = &http.Client{Timeout: cmp.Or(cfg.ForwardTimeout, api.DefaultForwardTimeout)}
+	httpSrv.FallbackLeaderAddr = cfg.FallbackLeaderHTTPAddr
+	httpSrv.Lease = lease
+	httpSrv.StaleReads = cfg.StaleReads
+	httpSrv.AccessLog = cfg.AccessLog
+	httpSrv.GzipMinBytes = cfg.GzipMinBytes
 	mux := http.NewServeMux()
 	httpSrv.RegisterRoutes(mux)
+	httpSrv.RegisterMembershipRoutes(mux, cfg.AdminToken)
+
+	// Metrics, stats and liveness share the data mux unless ADMIN_ADDR
+	// gives them their own.
+	opsMux := mux
+	if cfg.AdminAddr != "" {
+		opsMux = http.NewServeMux()
+	}
+	httpSrv.RegisterOpsRoutes(opsMux)
+	if cfg.MetricsFormat == "prometheus" {
+		opsMux.HandleFunc("/metrics", api.MetricsHandlerPrometheus(instrumented, r))
+	} else {
+		opsMux.HandleFunc("/metrics", api.MetricsHandler(instrumented))
+	}
+	opsMux.HandleFunc("/metrics/hotkeys", api.HotKeysHandler(instrumented))
+	opsMux.HandleFunc("/metrics/reset", api.RequireToken(cfg.AdminToken, api.MetricsResetHandler(instrumented)))
+	mux.HandleFunc("/admin/config", api.RequireToken(cfg.AdminToken, api.ConfigHandler(cfg)))
+	mux.HandleFunc("/admin/dump", api.RequireToken(cfg.AdminToken, api.DumpHandler(instrumented, r)))
+	mux.HandleFunc("/admin/restore", api.RequireToken(cfg.AdminToken, api.RestoreHandler(instrumented, r, cfg.MaxKeyBytes, cfg.MaxValueBytes)))
+	if r != nil {
+		mux.HandleFunc("/admin/snapshot", api.RequireToken(cfg.AdminToken, api.SnapshotHandler(r)))
+		mux.HandleFunc("/admin/transfer-leadership", api.RequireToken(cfg.AdminToken, api.TransferLeadershipHandler(r)))
+	}
+	if cfg.EnableRawCommands && rs != nil {
+		slog.Warn("/admin/raw-command is enabled; raw Raft commands bypass all validation")
+		mux.HandleFunc("/admin/raw-command", api.RequireToken(cfg.AdminToken, api.RawCommandHandler(rs)))
+	}
+
+	var handler http.Handler = mux
+	if cfg.RateLimit > 0 {
+		limiter := api.NewRateLimiter(cfg.RateLimit, cfg.RateLimitBurst)
+		limiter.TrustForwardedFor = cfg.TrustForwardedFor
+		handler = limiter.Wrap(handler)
+		slog.Info("HTTP rate limit enabled", "rate", cfg.RateLimit, "burst", cfg.RateLimitBurst,
+			"trust_forwarded_for", cfg.TrustForwardedFor)
+	}
 
-	log.Fatal(http.ListenAndServe(cfg.HTTPAddr, mux))
+	httpLis, err := netutil.Listen(cfg.HTTPAddr, listenOpts)
+	if err != nil {
+		logging.Fatal("Failed to listen", "addr", cfg.HTTPAddr, "error", err)
+	}
+	httpServer := &http.Server{
+		Handler:      handler,
+		ReadTimeout:  cmp.Or(cfg.HTTPReadTimeout, 30*time.Second),
+		WriteTimeout: cmp.Or(cfg.HTTPWriteTimeout, 60*time.Second),
+		IdleTimeout:  cmp.Or(cfg.HTTPIdleTimeout, 120*time.Second),
+	}
+	go func() {
+		if err := httpServer.Serve(httpLis); err != http.ErrServerClosed {
+			logging.Fatal("HTTP server failed", "error", err)
+		}
+	}()
+
+	var adminServer *http.Server
+	if cfg.AdminAddr != "" {
+		adminLis, err := netutil.Listen(cfg.AdminAddr, listenOpts)
+		if err != nil {
+			logging.Fatal("Failed to listen", "addr", cfg.AdminAddr, "error", err)
+		}
+		adminServer = &http.Server{
+			Handler:      opsMux,
+			ReadTimeout:  cmp.Or(cfg.HTTPReadTimeout, 30*time.Second),
+			WriteTimeout: cmp.Or(cfg.HTTPWriteTimeout, 60*time.Second),
+			IdleTimeout:  cmp.Or(cfg.HTTPIdleTimeout, 120*time.Second),
+		}
+		go func() {
+			if err := adminServer.Serve(adminLis); err != http.ErrServerClosed {
+				logging.Fatal("Admin HTTP server failed", "error", err)
+			}
+		}()
+		slog.Info("Serving metrics, stats and healthz on the admin address", "addr", cfg.AdminAddr)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	<-ctx.Done()
+	stop()
+
+	// Raft reports Shutdown once stopped, so note leadership now. mandi is
+	// only told after Raft stops, so the leader loop cannot re-register.
+	wasLeader := r != nil && r.State() == raft.Leader
+
+	timeout := cfg.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	shutdown(httpServer, adminServer, grpcServer, grpcSrv, r, boltStore, timeout)
+	if r != nil {
+		leaveMandi(cfg.MandiAddr, cfg.NodeID, wasLeader)
+	}
+}
+
+/* ---------------- Shutdown ---------------- */
+
+// shutdown drains the HTTP and gRPC servers, and the admin server if there
+// is one, giving in-flight requests up to timeout before remaining
+// connections (e.g. watch streams) are cut, then snapshots and stops Raft
+// so a restart replays as little as possible, or closes the bolt file when
+// running without Raft.
+func shutdown(httpServer, adminServer *http.Server, grpcServer *grpc.Server, grpcSrv *api.GRPCServer, r *raft.Raft, boltStore *store.BoltStore, timeout time.Duration) {
+	slog.Info("Shutting down", "drain_timeout", timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, srv := range []*http.Server{httpServer, adminServer} {
+		if srv == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := srv.Shutdown(ctx); err != nil {
+				slog.Warn("HTTP drain incomplete; closing connections", "error", err)
+				srv.Close()
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			slog.Warn("gRPC drain incomplete; closing connections")
+			grpcServer.Stop()
+		}
+	}()
+	wg.Wait()
+	grpcSrv.Close()
+
+	if r != nil {
+		if err := r.Snapshot().Error(); err != nil && err != raft.ErrNothingNewToSnapshot {
+			slog.Error("Final snapshot failed", "error", err)
+		}
+		if err := r.Shutdown().Error(); err != nil {
+			slog.Error("Raft shutdown failed", "error", err)
+		}
+	}
+	if boltStore != nil {
+		if err := boltStore.Close(); err != nil {
+			slog.Error("Closing storage failed", "error", err)
+		}
+	}
+	slog.Info("Shutdown complete")
 }
@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/heysubinoy/pyazdb/internal/logging"
 )
 
 /*
@@ -18,8 +26,20 @@ It is NOT authoritative and NOT part of Raft correctness.
 
 const (
 	leaderTTL      = 10 * time.Second
-	joinRequestTTL = 30 * time.Second
+	joinRequestTTL = 30 * time.Second // when a request sets no TTL
+	maxJoinTTL     = time.Hour
 	cleanupEvery   = 5 * time.Second
+
+	// defaultLeaderWait and maxLeaderWait bound a GET /leader?wait=true
+	// long poll.
+	defaultLeaderWait = 30 * time.Second
+	maxLeaderWait     = 60 * time.Second
+
+	// Server timeouts, so slow or stalled clients cannot hold connections
+	// open indefinitely.
+	readTimeout  = 10 * time.Second
+	writeTimeout = 10 * time.Second
+	idleTimeout  = 120 * time.Second
 )
 
 // -------------------- Types --------------------
@@ -37,34 +57,277 @@ type JoinRequest struct {
 	ID        string    `json:"id"`
 	Addr      string    `json:"addr"`
 	StartedAt time.Time `json:"started_at"`
+	// TTLSeconds keeps the request for this long after StartedAt
+	// (0 = joinRequestTTL), for nodes whose join takes a while.
+	TTLSeconds int `json:"ttl_seconds,omitempty"`
+}
+
+// expired reports whether jr's TTL has run out.
+func (jr JoinRequest) expired() bool {
+	ttl := joinRequestTTL
+	if jr.TTLSeconds > 0 {
+		ttl = time.Duration(jr.TTLSeconds) * time.Second
+	}
+	return time.Since(jr.StartedAt) > ttl
 }
 
 // -------------------- In-memory Store --------------------
 
-type Store struct {
-	mu           sync.Mutex
+// defaultCluster is the cluster of requests that name none, so callers
+// that predate multi-cluster support keep working unchanged.
+const defaultCluster = "default"
+
+// cluster is the discovery state of one PyazDB cluster.
+type cluster struct {
 	leader       *LeaderInfo
 	joinRequests map[string]JoinRequest
+
+	// leaderChanged is closed and replaced whenever the leader record is
+	// set or cleared, waking every long poll to recheck the term.
+	leaderChanged chan struct{}
+}
+
+func newCluster() *cluster {
+	return &cluster{
+		joinRequests:  make(map[string]JoinRequest),
+		leaderChanged: make(chan struct{}),
+	}
+}
+
+// notifyLeader wakes the long polls waiting on c's leader.
+func (c *cluster) notifyLeader() {
+	close(c.leaderChanged)
+	c.leaderChanged = make(chan struct{})
+}
+
+// leaderTerm returns the current leader's term, or 0 when no leader is
+// available.
+func (c *cluster) leaderTerm() uint64 {
+	if c.leader == nil || time.Since(c.leader.UpdatedAt) > leaderTTL {
+		return 0
+	}
+	return c.leader.Term
+}
+
+// empty reports whether c holds nothing worth keeping.
+func (c *cluster) empty() bool {
+	return c.leader == nil && len(c.joinRequests) == 0
+}
+
+// Store holds the state of every cluster, keyed by name. A cluster is
+// created on first use and dropped by the cleanup loop once it is empty.
+type Store struct {
+	mu       sync.Mutex
+	clusters map[string]*cluster
+
+	// statePath, if set, is a write-through copy of the state so a
+	// restarted mandi can answer immediately. Memory stays authoritative.
+	statePath string
 }
 
 func NewStore() *Store {
 	return &Store{
-		joinRequests: make(map[string]JoinRequest),
+		clusters: make(map[string]*cluster),
+	}
+}
+
+// clusterLocked returns the named cluster, creating it if needed.
+// Callers must hold s.mu.
+func (s *Store) clusterLocked(name string) *cluster {
+	c, ok := s.clusters[name]
+	if !ok {
+		c = newCluster()
+		s.clusters[name] = c
+	}
+	return c
+}
+
+// clusterName returns the cluster r addresses: the {cluster} path segment
+// of /clusters/{cluster}/..., else the cluster query parameter, else
+// defaultCluster.
+func clusterName(r *http.Request) string {
+	if name := r.PathValue("cluster"); name != "" {
+		return name
+	}
+	if name := r.URL.Query().Get("cluster"); name != "" {
+		return name
+	}
+	return defaultCluster
+}
+
+// waitLeader blocks until the leader's term in the named cluster differs
+// from since (a lost leader counts as term 0), timeout passes or ctx is
+// done.
+func (s *Store) waitLeader(ctx context.Context, name string, since uint64, timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		s.mu.Lock()
+		c := s.clusterLocked(name)
+		term := c.leaderTerm()
+		changed := c.leaderChanged
+		s.mu.Unlock()
+
+		if term != since {
+			return
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// -------------------- Persistence --------------------
+
+type persistedState struct {
+	Clusters map[string]persistedCluster `json:"clusters,omitempty"`
+
+	// Leader and JoinRequests hold the only cluster of files written
+	// before mandi served several; they load into defaultCluster.
+	Leader       *LeaderInfo   `json:"leader,omitempty"`
+	JoinRequests []JoinRequest `json:"join_requests,omitempty"`
+}
+
+type persistedCluster struct {
+	Leader       *LeaderInfo   `json:"leader,omitempty"`
+	JoinRequests []JoinRequest `json:"join_requests,omitempty"`
+}
+
+// loadState restores state saved at path, dropping entries whose TTL ran
+// out while mandi was down. An unreadable file is logged and ignored.
+func (s *Store) loadState(path string) {
+	s.statePath = path
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		slog.Warn("mandi: ignoring unreadable state file", "path", path, "error", err)
+		return
+	}
+	var st persistedState
+	if err := json.Unmarshal(data, &st); err != nil {
+		slog.Warn("mandi: ignoring corrupt state file", "path", path, "error", err)
+		return
+	}
+	if st.Leader != nil || len(st.JoinRequests) > 0 {
+		if st.Clusters == nil {
+			st.Clusters = make(map[string]persistedCluster)
+		}
+		st.Clusters[defaultCluster] = persistedCluster{Leader: st.Leader, JoinRequests: st.JoinRequests}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, pc := range st.Clusters {
+		c := newCluster()
+		if pc.Leader != nil && time.Since(pc.Leader.UpdatedAt) <= leaderTTL {
+			c.leader = pc.Leader
+		}
+		for _, jr := range pc.JoinRequests {
+			if !jr.expired() {
+				c.joinRequests[jr.ID] = jr
+			}
+		}
+		if !c.empty() {
+			s.clusters[name] = c
+		}
+	}
+	slog.Info("mandi: restored state", "path", path, "clusters", len(s.clusters))
+}
+
+// saveLocked writes the state to statePath, replacing the file atomically.
+// Failures are logged; the in-memory state is unaffected.
+// Callers must hold s.mu.
+func (s *Store) saveLocked() {
+	if s.statePath == "" {
+		return
+	}
+	st := persistedState{Clusters: make(map[string]persistedCluster, len(s.clusters))}
+	for name, c := range s.clusters {
+		if c.empty() {
+			continue
+		}
+		pc := persistedCluster{Leader: c.leader}
+		for _, jr := range c.joinRequests {
+			pc.JoinRequests = append(pc.JoinRequests, jr)
+		}
+		st.Clusters[name] = pc
+	}
+	data, err := json.Marshal(st)
+	if err != nil {
+		slog.Error("mandi: encoding state failed", "error", err)
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.statePath), ".mandi-state-*")
+	if err != nil {
+		slog.Error("mandi: saving state failed", "path", s.statePath, "error", err)
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.statePath)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		slog.Error("mandi: saving state failed", "path", s.statePath, "error", err)
 	}
 }
 
 // -------------------- HTTP Handlers --------------------
 
-func (s *Store) getLeader(w http.ResponseWriter, _ *http.Request) {
+// getLeader returns the current leader. With wait=true it long-polls
+// first: it answers as soon as the leader's term differs from since
+// (default 0), or after timeout (default defaultLeaderWait, at most
+// maxLeaderWait) with whatever leader there is then.
+func (s *Store) getLeader(w http.ResponseWriter, r *http.Request) {
+	name := clusterName(r)
+	q := r.URL.Query()
+	if q.Get("wait") == "true" {
+		var since uint64
+		if v := q.Get("since"); v != "" {
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid since", http.StatusBadRequest)
+				return
+			}
+			since = n
+		}
+		timeout := defaultLeaderWait
+		if v := q.Get("timeout"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				http.Error(w, "invalid timeout", http.StatusBadRequest)
+				return
+			}
+			timeout = min(d, maxLeaderWait)
+		}
+		// The server's read timeout would otherwise cancel the request
+		// context part-way through the poll.
+		http.NewResponseController(w).SetReadDeadline(time.Time{})
+		s.waitLeader(r.Context(), name, since, timeout)
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if s.leader == nil || time.Since(s.leader.UpdatedAt) > leaderTTL {
+	c, ok := s.clusters[name]
+	if !ok || c.leader == nil || time.Since(c.leader.UpdatedAt) > leaderTTL {
 		http.Error(w, "leader not available", http.StatusNotFound)
 		return
 	}
 
-	_ = json.NewEncoder(w).Encode(s.leader)
+	_ = json.NewEncoder(w).Encode(c.leader)
 }
 
 func (s *Store) putLeader(w http.ResponseWriter, r *http.Request) {
@@ -76,8 +339,39 @@ func (s *Store) putLeader(w http.ResponseWriter, r *http.Request) {
 
 	info.UpdatedAt = time.Now()
 
+	name := clusterName(r)
 	s.mu.Lock()
-	s.leader = &info
+	c := s.clusterLocked(name)
+	if c.leader == nil || c.leader.ID != info.ID || c.leader.Term != info.Term {
+		slog.Info("mandi: leader changed", "cluster", name, "node_id", info.ID,
+			"term", info.Term, "http_addr", info.HTTPAddr, "grpc_addr", info.GRPCAddr)
+	}
+	c.leader = &info
+	c.notifyLeader()
+	s.saveLocked()
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteLeader clears the leader record, so a leader shutting down stops
+// being advertised before its TTL runs out. The record is only cleared
+// while it still names id, so a late call cannot remove a newer leader.
+func (s *Store) deleteLeader(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "missing id", http.StatusBadRequest)
+		return
+	}
+
+	name := clusterName(r)
+	s.mu.Lock()
+	if c, ok := s.clusters[name]; ok && c.leader != nil && c.leader.ID == id {
+		slog.Info("mandi: leader left", "cluster", name, "node_id", id, "term", c.leader.Term)
+		c.leader = nil
+		c.notifyLeader()
+		s.saveLocked()
+	}
 	s.mu.Unlock()
 
 	w.WriteHeader(http.StatusNoContent)
@@ -89,23 +383,30 @@ func (s *Store) postJoinRequest(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if maxSecs := int(maxJoinTTL / time.Second); jr.TTLSeconds < 0 || jr.TTLSeconds > maxSecs {
+		http.Error(w, fmt.Sprintf("ttl_seconds must be between 0 and %d", maxSecs), http.StatusBadRequest)
+		return
+	}
 
 	jr.StartedAt = time.Now()
 
 	s.mu.Lock()
-	s.joinRequests[jr.ID] = jr
+	s.clusterLocked(clusterName(r)).joinRequests[jr.ID] = jr
+	s.saveLocked()
 	s.mu.Unlock()
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (s *Store) listJoinRequests(w http.ResponseWriter, _ *http.Request) {
+func (s *Store) listJoinRequests(w http.ResponseWriter, r *http.Request) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	var list []JoinRequest
-	for _, jr := range s.joinRequests {
-		list = append(list, jr)
+	if c, ok := s.clusters[clusterName(r)]; ok {
+		for _, jr := range c.joinRequests {
+			list = append(list, jr)
+		}
 	}
 
 	_ = json.NewEncoder(w).Encode(list)
@@ -119,72 +420,164 @@ func (s *Store) deleteJoinRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	s.mu.Lock()
-	delete(s.joinRequests, id)
+	if c, ok := s.clusters[clusterName(r)]; ok {
+		delete(c.joinRequests, id)
+	}
+	s.saveLocked()
 	s.mu.Unlock()
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// -------------------- Auth --------------------
+
+// authorized reports whether r carries "Authorization: Bearer <token>".
+// An empty token disables the check.
+func authorized(r *http.Request, token string) bool {
+	if token == "" {
+		return true
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
 // -------------------- Cleanup Loop --------------------
 
 func (s *Store) cleanupLoop() {
 	ticker := time.NewTicker(cleanupEvery)
 	for range ticker.C {
 		s.mu.Lock()
+		changed := false
+
+		for name, c := range s.clusters {
+			// Expire leader
+			if c.leader != nil && time.Since(c.leader.UpdatedAt) > leaderTTL {
+				slog.Warn("mandi: leader expired", "cluster", name, "node_id", c.leader.ID, "term", c.leader.Term)
+				c.leader = nil
+				c.notifyLeader()
+				changed = true
+			}
 
-		// Expire leader
-		if s.leader != nil && time.Since(s.leader.UpdatedAt) > leaderTTL {
-			s.leader = nil
-		}
+			// Expire join requests
+			for id, jr := range c.joinRequests {
+				if jr.expired() {
+					delete(c.joinRequests, id)
+					changed = true
+				}
+			}
 
-		// Expire join requests
-		for id, jr := range s.joinRequests {
-			if time.Since(jr.StartedAt) > joinRequestTTL {
-				delete(s.joinRequests, id)
+			// Drop the cluster once empty; its waiters move on to the
+			// cluster created on their next check.
+			if c.empty() {
+				c.notifyLeader()
+				delete(s.clusters, name)
 			}
 		}
 
+		if changed {
+			s.saveLocked()
+		}
 		s.mu.Unlock()
 	}
 }
 
-// -------------------- main --------------------
+// -------------------- Routes --------------------
 
-func main() {
-	addr := ":7000"
-	if v := os.Getenv("MANDI_ADDR"); v != "" {
-		addr = v
+// routes returns mandi's HTTP handler. token guards the mutating
+// endpoints, and the GETs too when protectReads is set; an empty token
+// disables the check.
+func (s *Store) routes(token string, protectReads bool) http.Handler {
+	allow := func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == http.MethodGet && !protectReads {
+			return true
+		}
+		if !authorized(r, token) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return false
+		}
+		return true
 	}
 
-	store := NewStore()
-	go store.cleanupLoop()
-
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("/leader", func(w http.ResponseWriter, r *http.Request) {
+	// Every endpoint is served for the cluster named by ?cluster= (default
+	// "default") and under /clusters/{cluster}/, so a node can be pointed
+	// at its cluster through MANDI_ADDR alone.
+	leader := func(w http.ResponseWriter, r *http.Request) {
+		if !allow(w, r) {
+			return
+		}
 		switch r.Method {
 		case http.MethodGet:
-			store.getLeader(w, r)
+			s.getLeader(w, r)
 		case http.MethodPut:
-			store.putLeader(w, r)
+			s.putLeader(w, r)
+		case http.MethodDelete:
+			s.deleteLeader(w, r)
 		default:
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		}
-	})
+	}
 
-	mux.HandleFunc("/join-requests", func(w http.ResponseWriter, r *http.Request) {
+	joinRequests := func(w http.ResponseWriter, r *http.Request) {
+		if !allow(w, r) {
+			return
+		}
 		switch r.Method {
 		case http.MethodPost:
-			store.postJoinRequest(w, r)
+			s.postJoinRequest(w, r)
 		case http.MethodGet:
-			store.listJoinRequests(w, r)
+			s.listJoinRequests(w, r)
 		case http.MethodDelete:
-			store.deleteJoinRequest(w, r)
+			s.deleteJoinRequest(w, r)
 		default:
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		}
-	})
+	}
+
+	mux.HandleFunc("/leader", leader)
+	mux.HandleFunc("/join-requests", joinRequests)
+	mux.HandleFunc("/clusters/{cluster}/leader", leader)
+	mux.HandleFunc("/clusters/{cluster}/join-requests", joinRequests)
+
+	return mux
+}
+
+// -------------------- main --------------------
+
+func main() {
+	// LOG_LEVEL and LOG_FORMAT match the node's settings.
+	if err := logging.Setup(os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT")); err != nil {
+		logging.Fatal("mandi: failed to set up logging", "error", err)
+	}
+
+	addr := ":7000"
+	if v := os.Getenv("MANDI_ADDR"); v != "" {
+		addr = v
+	}
 
-	log.Printf("mandi listening on %s\n", addr)
-	log.Fatal(http.ListenAndServe(addr, mux))
+	// MANDI_TOKEN guards the mutating endpoints; MANDI_PROTECT_READS
+	// extends it to GETs.
+	token := os.Getenv("MANDI_TOKEN")
+	protectReads := os.Getenv("MANDI_PROTECT_READS") == "true"
+	if token == "" {
+		slog.Warn("mandi: MANDI_TOKEN not set; endpoints are unauthenticated")
+	}
+
+	store := NewStore()
+	if path := os.Getenv("MANDI_STATE_FILE"); path != "" {
+		store.loadState(path)
+	}
+	go store.cleanupLoop()
+
+	slog.Info("mandi listening", "addr", addr)
+	srv := &http.Server{
+		Addr:        addr,
+		Handler:     store.routes(token, protectReads),
+		ReadTimeout: readTimeout,
+		// Long polls may legitimately take up to maxLeaderWait.
+		WriteTimeout: maxLeaderWait + writeTimeout,
+		IdleTimeout:  idleTimeout,
+	}
+	logging.Fatal("mandi: server failed", "error", srv.ListenAndServe())
 }
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuthorized(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		want   bool
+	}{
+		{"auth disabled", "", "", true},
+		{"auth disabled ignores header", "", "Bearer anything", true},
+		{"valid", "s3cret", "Bearer s3cret", true},
+		{"missing header", "s3cret", "", false},
+		{"wrong token", "s3cret", "Bearer nope", false},
+		{"bare token without scheme", "s3cret", "s3cret", false},
+		{"other scheme", "s3cret", "Basic s3cret", false},
+		{"token prefix only", "s3cret", "Bearer s3c", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/leader", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if got := authorized(req, tt.token); got != tt.want {
+				t.Errorf("authorized = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// serve sends one request through the routes and returns the status code.
+func serve(h http.Handler, method, path, body, auth string) int {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestPutLeaderRequiresToken(t *testing.T) {
+	h := NewStore().routes("s3cret", false)
+	const body = `{"id":"node1","addr":"127.0.0.1:9001","http_addr":":8081","term":1}`
+
+	if code := serve(h, http.MethodPut, "/leader", body, ""); code != http.StatusUnauthorized {
+		t.Fatalf("PUT /leader without a token = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := serve(h, http.MethodPut, "/leader", body, "Bearer nope"); code != http.StatusUnauthorized {
+		t.Fatalf("PUT /leader with a wrong token = %d, want %d", code, http.StatusUnauthorized)
+	}
+	// Reads stay open, and the rejected PUTs left no leader behind.
+	if code := serve(h, http.MethodGet, "/leader", "", ""); code != http.StatusNotFound {
+		t.Fatalf("GET /leader after rejected PUTs = %d, want %d", code, http.StatusNotFound)
+	}
+
+	if code := serve(h, http.MethodPut, "/leader", body, "Bearer s3cret"); code != http.StatusNoContent {
+		t.Fatalf("PUT /leader with the token = %d, want %d", code, http.StatusNoContent)
+	}
+	if code := serve(h, http.MethodGet, "/leader", "", ""); code != http.StatusOK {
+		t.Fatalf("GET /leader after an accepted PUT = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestProtectReads(t *testing.T) {
+	h := NewStore().routes("s3cret", true)
+
+	if code := serve(h, http.MethodGet, "/join-requests", "", ""); code != http.StatusUnauthorized {
+		t.Fatalf("GET /join-requests without a token = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := serve(h, http.MethodGet, "/join-requests", "", "Bearer s3cret"); code != http.StatusOK {
+		t.Fatalf("GET /join-requests with the token = %d, want %d", code, http.StatusOK)
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Access log formats accepted by Server.AccessLog.
+const (
+	AccessLogText = "text" // a record on the default slog logger (default)
+	AccessLogJSON = "json" // one JSON object per line
+	AccessLogOff  = "off"
+)
+
+// responseWriter records the status code and body size written by a
+// handler. It passes Flush through so streaming handlers such as /watch
+// keep working.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// logged wraps h so each request is logged in s.AccessLog's format once
+// the handler returns. With AccessLogOff, h is returned unchanged.
+func (s *Server) logged(h http.HandlerFunc) http.HandlerFunc {
+	if s.AccessLog == AccessLogOff {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &responseWriter{ResponseWriter: w}
+		h(rw, r)
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+		logRequest(s.AccessLog, r, rw.status, rw.bytes, time.Since(start))
+	}
+}
+
+func logRequest(format string, r *http.Request, status int, bytes int64, d time.Duration) {
+	if format != AccessLogJSON {
+		slog.Info("http", "method", r.Method, "path", r.URL.Path,
+			"status", status, "bytes", bytes, "duration", d)
+		return
+	}
+	line, err := json.Marshal(struct {
+		Time       string  `json:"time"`
+		Method     string  `json:"method"`
+		Path       string  `json:"path"`
+		Status     int     `json:"status"`
+		Bytes      int64   `json:"bytes"`
+		DurationMS float64 `json:"duration_ms"`
+	}{
+		Time:       time.Now().UTC().Format(time.RFC3339Nano),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Status:     status,
+		Bytes:      bytes,
+		DurationMS: float64(d.Microseconds()) / 1000,
+	})
+	if err != nil {
+		return
+	}
+	os.Stderr.Write(append(line, '\n'))
+}
@@ -0,0 +1,365 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/heysubinoy/pyazdb/internal/store"
+	"github.com/heysubinoy/pyazdb/pkg/config"
+	"github.com/heysubinoy/pyazdb/pkg/kv"
+)
+
+// RequireToken wraps an admin handler so it is only reachable with
+// "Authorization: Bearer <token>". When no token is configured the
+// handler is disabled entirely rather than left open.
+func RequireToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			http.Error(w, "Admin endpoints are disabled (no admin token configured)", http.StatusForbidden)
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// ConfigHandler returns the effective runtime config (after env overrides
+// and defaults) as JSON, with secrets redacted.
+func ConfigHandler(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(cfg.Redacted())
+	}
+}
+
+// RawCommandHandler submits a raw RaftCommand straight to the Raft log.
+// It bypasses all API validation apart from rejecting unknown ops, so it
+// is only registered when raw commands are explicitly enabled, and every
+// use is logged. It must be sent to the leader; it is never forwarded.
+func RawCommandHandler(rs *store.RaftStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var cmd store.RaftCommand
+		if err := decodeStrict(r, &cmd); err != nil {
+			http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		slog.Warn("ADMIN raw command", "remote_addr", r.RemoteAddr,
+			"op", cmd.Op, "key", cmd.Key, "expires_at", cmd.ExpiresAt)
+
+		index, result, err := rs.ApplyRaw(cmd)
+		switch {
+		case errors.Is(err, store.ErrUnknownOp), errors.Is(err, kv.ErrTooLarge):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, raft.ErrNotLeader):
+			http.Error(w, "Not the leader; send raw commands to the leader directly", http.StatusServiceUnavailable)
+			return
+		case err != nil:
+			slog.Error("ADMIN raw command failed", "op", cmd.Op, "key", cmd.Key, "error", err)
+			http.Error(w, "Failed to apply command: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		slog.Warn("ADMIN raw command applied", "op", cmd.Op, "key", cmd.Key, "index", index)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"index":  index,
+			"result": result,
+		})
+	}
+}
+
+// SnapshotHandler forces a Raft snapshot on the leader and returns the
+// index and term it covers, so operators can make state durable before
+// risky operations or script point-in-time backups. Other nodes answer
+// 421; it is never forwarded, so the caller knows which node it hit.
+// Returns: {"index": 1042, "term": 3, "created": true}, where created is
+// false if Raft had nothing to snapshot yet; the last snapshot, if any, is
+// reported instead.
+func SnapshotHandler(r *raft.Raft) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if r.State() != raft.Leader {
+			leaderAddr, _ := r.LeaderWithID()
+			http.Error(w, fmt.Sprintf("Not the leader; send snapshot requests to the leader (raft address %q)", leaderAddr), http.StatusMisdirectedRequest)
+			return
+		}
+
+		resp := struct {
+			Index   uint64 `json:"index"`
+			Term    uint64 `json:"term"`
+			Created bool   `json:"created"`
+		}{}
+		f := r.Snapshot()
+		switch err := f.Error(); {
+		case errors.Is(err, raft.ErrNothingNewToSnapshot):
+			stats := r.Stats()
+			resp.Index, _ = strconv.ParseUint(stats["last_snapshot_index"], 10, 64)
+			resp.Term, _ = strconv.ParseUint(stats["last_snapshot_term"], 10, 64)
+		case err != nil:
+			http.Error(w, "Failed to snapshot: "+err.Error(), http.StatusInternalServerError)
+			return
+		default:
+			meta, rc, err := f.Open()
+			if err != nil {
+				http.Error(w, "Failed to read snapshot metadata: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			rc.Close()
+			resp.Index, resp.Term, resp.Created = meta.Index, meta.Term, true
+		}
+		slog.Info("ADMIN snapshot", "index", resp.Index, "term", resp.Term, "created", resp.Created)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// transferLeaderWait bounds how long TransferLeadershipHandler waits to
+// learn the new leader after handing over.
+const transferLeaderWait = 2 * time.Second
+
+// TransferLeadershipHandler hands leadership to another voter so the
+// leader can be restarted without the write stall of an unplanned
+// election. The body may name the target, {"id": "node2"}; without one
+// Raft picks the most up-to-date follower. It waits for the transfer and
+// returns the new leader, or empty strings if this node has not heard
+// from it yet: {"leader_id": "node2", "leader_addr": "10.0.0.2:12000"}. Like
+// SnapshotHandler it only runs on the leader and answers 421 elsewhere.
+func TransferLeadershipHandler(r *raft.Raft) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if r.State() != raft.Leader {
+			leaderAddr, _ := r.LeaderWithID()
+			http.Error(w, fmt.Sprintf("Not the leader; send leadership transfers to the leader (raft address %q)", leaderAddr), http.StatusMisdirectedRequest)
+			return
+		}
+
+		var body struct {
+			ID string `json:"id"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil && err != io.EOF {
+			http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var f raft.Future
+		if body.ID == "" {
+			f = r.LeadershipTransfer()
+		} else {
+			cf := r.GetConfiguration()
+			if err := cf.Error(); err != nil {
+				http.Error(w, "Failed to read Raft configuration: "+err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			var target *raft.Server
+			for _, srv := range cf.Configuration().Servers {
+				if srv.ID == raft.ServerID(body.ID) {
+					target = &srv
+					break
+				}
+			}
+			if target == nil || target.Suffrage != raft.Voter {
+				http.Error(w, fmt.Sprintf("%q is not a voter in this cluster", body.ID), http.StatusBadRequest)
+				return
+			}
+			f = r.LeadershipTransferToServer(target.ID, target.Address)
+		}
+
+		switch err := f.Error(); {
+		case errors.Is(err, raft.ErrLeadershipTransferInProgress):
+			http.Error(w, "A leadership transfer is already in progress", http.StatusConflict)
+			return
+		case errors.Is(err, raft.ErrNotLeader):
+			http.Error(w, "Lost leadership before the transfer started", http.StatusMisdirectedRequest)
+			return
+		case err != nil:
+			http.Error(w, "Failed to transfer leadership: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// The old leader steps down as soon as the target wins, before it
+		// has heard from it, so give the first heartbeat a moment.
+		leaderAddr, leaderID := r.LeaderWithID()
+		for deadline := time.Now().Add(transferLeaderWait); leaderID == "" && time.Now().Before(deadline); {
+			time.Sleep(50 * time.Millisecond)
+			leaderAddr, leaderID = r.LeaderWithID()
+		}
+		slog.Info("ADMIN leadership transferred", "leader_id", leaderID, "leader_addr", leaderAddr)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			LeaderID   string `json:"leader_id"`
+			LeaderAddr string `json:"leader_addr"`
+		}{string(leaderID), string(leaderAddr)})
+	}
+}
+
+// dumpRecord is one line of a dump: a key and its value.
+type dumpRecord struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// DumpHandler streams every key-value pair as newline-delimited JSON
+// records, {"key": ..., "value": ...}, sorted by key. The pairs come from
+// one Scan, which reads all shards under a single lock, so writes racing
+// with the dump are either wholly in it or wholly out. TTLs and labels
+// are not included. Like SnapshotHandler it only runs on the leader and
+// answers 421 elsewhere.
+func DumpHandler(st kv.Store, r *raft.Raft) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if r != nil && r.State() != raft.Leader {
+			leaderAddr, _ := r.LeaderWithID()
+			http.Error(w, fmt.Sprintf("Not the leader; send dump requests to the leader (raft address %q)", leaderAddr), http.StatusMisdirectedRequest)
+			return
+		}
+
+		pairs, err := st.Scan("", 0)
+		if err != nil {
+			http.Error(w, "Failed to scan keys: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		slog.Info("ADMIN dump", "keys", len(pairs), "remote_addr", req.RemoteAddr)
+		clearDeadlines(w)
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		for _, p := range pairs {
+			if err := enc.Encode(dumpRecord{Key: p.Key, Value: p.Value}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+var errRecordTooLarge = errors.New("record too large")
+
+// recordLimiter fails reads once more than limit bytes have been read
+// since the last reset, so a restore refuses an oversized record instead
+// of buffering it in full.
+type recordLimiter struct {
+	r     io.Reader
+	limit int64
+	n     int64
+}
+
+func (l *recordLimiter) Read(p []byte) (int, error) {
+	if l.n >= l.limit {
+		return 0, errRecordTooLarge
+	}
+	if rem := l.limit - l.n; int64(len(p)) > rem {
+		p = p[:rem]
+	}
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	return n, err
+}
+
+// reset starts counting the next record.
+func (l *recordLimiter) reset() {
+	l.n = 0
+}
+
+// RestoreHandler loads records in the format DumpHandler writes, applying
+// each one as a replicated set, and returns {"loaded": N}. Sets overwrite,
+// so restoring the same dump twice leaves the same state and a restore
+// that failed part-way can simply be rerun. On a bad record it stops and
+// reports the 1-based record number along with how many were loaded
+// before it. Keys not in the dump are left alone. Leader only, like
+// DumpHandler.
+func RestoreHandler(st kv.Store, r *raft.Raft, maxKey, maxValue int) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if r != nil && r.State() != raft.Leader {
+			leaderAddr, _ := r.LeaderWithID()
+			http.Error(w, fmt.Sprintf("Not the leader; send restore requests to the leader (raft address %q)", leaderAddr), http.StatusMisdirectedRequest)
+			return
+		}
+
+		clearDeadlines(w)
+		// A dump has no size limit, but each record is held to the same
+		// bound as a /set body.
+		body := &recordLimiter{r: req.Body, limit: maxSetBody(maxKey, maxValue)}
+		dec := json.NewDecoder(body)
+		dec.DisallowUnknownFields()
+		loaded := 0
+		fail := func(status int, msg string) {
+			slog.Warn("ADMIN restore stopped", "remote_addr", req.RemoteAddr, "loaded", loaded, "error", msg)
+			http.Error(w, fmt.Sprintf("record %d: %s (%d loaded before it)", loaded+1, msg, loaded), status)
+		}
+		for {
+			var rec dumpRecord
+			err := dec.Decode(&rec)
+			if err == io.EOF {
+				break
+			}
+			if errors.Is(err, errRecordTooLarge) {
+				fail(http.StatusRequestEntityTooLarge, err.Error())
+				return
+			}
+			if err != nil {
+				fail(http.StatusBadRequest, "invalid JSON: "+strings.TrimPrefix(err.Error(), "json: "))
+				return
+			}
+			body.reset()
+			if err := kv.ValidateKey(rec.Key); err != nil {
+				fail(http.StatusBadRequest, err.Error())
+				return
+			}
+			if err := checkSize(rec.Key, rec.Value, maxKey, maxValue); err != nil {
+				fail(http.StatusRequestEntityTooLarge, err.Error())
+				return
+			}
+			if _, err := setIndexed(req.Context(), st, rec.Key, rec.Value); err != nil {
+				status, _ := errorStatus(err)
+				fail(status, "failed to set key: "+err.Error())
+				return
+			}
+			loaded++
+		}
+		slog.Info("ADMIN restore", "keys", loaded, "remote_addr", req.RemoteAddr)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{"loaded": loaded})
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/heysubinoy/pyazdb/pkg/kv"
+)
+
+// getResolved reads key, first following it through kv.AliasStore when
+// available so an alias reads as the key it names.
+func getResolved(st kv.Store, key string) (string, bool, error) {
+	if as, ok := st.(kv.AliasStore); ok {
+		target, err := as.ResolveAlias(key)
+		if err != nil {
+			return "", false, err
+		}
+		key = target
+	}
+	value, ok := st.Get(key)
+	return value, ok, nil
+}
+
+// existsResolved is Exists through the same alias resolution as getResolved.
+// A dangling alias does not exist.
+func existsResolved(st kv.Store, key string) (bool, error) {
+	if as, ok := st.(kv.AliasStore); ok {
+		target, err := as.ResolveAlias(key)
+		if errors.Is(err, kv.ErrDanglingAlias) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		key = target
+	}
+	return st.Exists(key)
+}
+
+// multiGetResolved is MultiGet through the same alias resolution as
+// getResolved. It returns values and found flags in the order of keys;
+// a dangling alias is not found.
+func multiGetResolved(st kv.Store, keys []string) ([]string, []bool, error) {
+	targets := keys
+	if as, ok := st.(kv.AliasStore); ok {
+		targets = make([]string, len(keys))
+		for i, key := range keys {
+			target, err := as.ResolveAlias(key)
+			if errors.Is(err, kv.ErrDanglingAlias) {
+				target = ""
+			} else if err != nil {
+				return nil, nil, err
+			}
+			targets[i] = target
+		}
+	}
+	got, err := st.MultiGet(targets)
+	if err != nil {
+		return nil, nil, err
+	}
+	values := make([]string, len(keys))
+	found := make([]bool, len(keys))
+	for i, target := range targets {
+		if target == "" {
+			continue
+		}
+		values[i], found[i] = got[target]
+	}
+	return values, found, nil
+}
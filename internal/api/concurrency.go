@@ -0,0 +1,31 @@
+package api
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultMaxConcurrentRequests bounds in-flight unary gRPC calls when no
+// limit is configured.
+const DefaultMaxConcurrentRequests = 1024
+
+// ConcurrencyLimiter returns a unary interceptor that lets at most max
+// calls (0 = DefaultMaxConcurrentRequests) run at once. Calls beyond that
+// fail straight away with ResourceExhausted instead of queueing, so a
+// burst on a follower is shed there rather than forwarded on to the
+// leader. Streaming calls are not counted.
+func ConcurrencyLimiter(max int) grpc.UnaryServerInterceptor {
+	sem := make(chan struct{}, limitOr(max, DefaultMaxConcurrentRequests))
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		select {
+		case sem <- struct{}{}:
+		default:
+			return nil, status.Errorf(codes.ResourceExhausted, "too many concurrent requests (limit %d)", cap(sem))
+		}
+		defer func() { <-sem }()
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/hashicorp/raft"
+	"github.com/heysubinoy/pyazdb/pkg/kv"
+	"google.golang.org/grpc/codes"
+)
+
+// storeErrors maps the errors a store call can fail with to the HTTP
+// status and gRPC code they are answered with. Anything else is a 500 /
+// Internal.
+var storeErrors = []struct {
+	err    error
+	status int
+	code   codes.Code
+}{
+	{kv.ErrNotSupported, http.StatusNotImplemented, codes.Unimplemented},
+	{kv.ErrQuotaExceeded, http.StatusInsufficientStorage, codes.ResourceExhausted},
+	{kv.ErrTooLarge, http.StatusRequestEntityTooLarge, codes.InvalidArgument},
+	{kv.ErrApplyTimeout, http.StatusGatewayTimeout, codes.DeadlineExceeded},
+	{kv.ErrNotInteger, http.StatusConflict, codes.FailedPrecondition},
+	{kv.ErrAliasCycle, http.StatusConflict, codes.FailedPrecondition},
+	{kv.ErrDanglingAlias, http.StatusConflict, codes.FailedPrecondition},
+	{kv.ErrAliasIsKey, http.StatusConflict, codes.FailedPrecondition},
+	{raft.ErrNotLeader, http.StatusServiceUnavailable, codes.Unavailable},
+	{raft.ErrLeadershipLost, http.StatusServiceUnavailable, codes.Unavailable},
+	{raft.ErrLeadershipTransferInProgress, http.StatusServiceUnavailable, codes.Unavailable},
+	{raft.ErrAbortedByRestore, http.StatusServiceUnavailable, codes.Unavailable},
+	{raft.ErrRaftShutdown, http.StatusServiceUnavailable, codes.Unavailable},
+}
+
+// errorStatus returns the HTTP status and gRPC code err is answered with.
+func errorStatus(err error) (int, codes.Code) {
+	for _, e := range storeErrors {
+		if errors.Is(err, e.err) {
+			return e.status, e.code
+		}
+	}
+	return http.StatusInternalServerError, codes.Internal
+}
+
+// serverFault reports whether a failure with this HTTP status is the
+// node's doing rather than the request's, and so worth logging.
+func serverFault(status int) bool {
+	switch status {
+	case http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/raft"
+	"github.com/heysubinoy/pyazdb/pkg/kv"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   codes.Code
+	}{
+		{kv.ErrNotSupported, http.StatusNotImplemented, codes.Unimplemented},
+		{fmt.Errorf("ns a: %w", kv.ErrQuotaExceeded), http.StatusInsufficientStorage, codes.ResourceExhausted},
+		{kv.ErrTooLarge, http.StatusRequestEntityTooLarge, codes.InvalidArgument},
+		{kv.ErrApplyTimeout, http.StatusGatewayTimeout, codes.DeadlineExceeded},
+		{kv.ErrNotInteger, http.StatusConflict, codes.FailedPrecondition},
+		{kv.ErrAliasIsKey, http.StatusConflict, codes.FailedPrecondition},
+		{raft.ErrLeadershipLost, http.StatusServiceUnavailable, codes.Unavailable},
+		{errors.New("disk on fire"), http.StatusInternalServerError, codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeFailed(rec, "Failed to set key", "k", tt.err)
+			if rec.Code != tt.status {
+				t.Errorf("writeFailed status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := status.Code(writeError(tt.err, "set key", "k")); got != tt.code {
+				t.Errorf("writeError code = %s, want %s", got, tt.code)
+			}
+		})
+	}
+}
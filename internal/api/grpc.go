@@ -3,15 +3,23 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/raft"
 	"github.com/heysubinoy/pyazdb/api/proto"
+	"github.com/heysubinoy/pyazdb/internal/store"
 	"github.com/heysubinoy/pyazdb/pkg/kv"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
@@ -23,8 +31,58 @@ type GRPCServer struct {
 	Raft      *raft.Raft
 	GRPCPort  string
 	MandiAddr string
+
+	// NodeID identifies this node in Ping responses.
+	NodeID string
+
+	// MandiToken is sent as a bearer token on mandi lookups.
+	MandiToken string
+
+	// DialCreds secures connections to the leader when forwarding
+	// (nil = insecure).
+	DialCreds credentials.TransportCredentials
+
+	// MaxKeyBytes and MaxValueBytes bound the keys and values writes
+	// accept (0 = DefaultMaxKeyBytes / DefaultMaxValueBytes).
+	MaxKeyBytes   int
+	MaxValueBytes int
+
+	// Metrics, when set, backs the MetricsStream RPC.
+	Metrics *store.InstrumentedStore
+
+	// MaxForwardHops bounds how many times a call may be forwarded
+	// between nodes before it is rejected as a loop (0 = default).
+	MaxForwardHops int
+
+	// FallbackLeaderAddr is forwarded to when mandi has no leader to offer.
+	FallbackLeaderAddr string
+
+	// Lease, when set, lets reads detect a leader that lost quorum contact;
+	// StaleReads picks what happens then (one of the StaleReads* policies).
+	Lease      *LeaseTracker
+	StaleReads string
+
+	// leaderConn is reused by every forwarded call until the leader's
+	// address changes.
+	connMu         sync.Mutex
+	leaderConn     *grpc.ClientConn
+	leaderConnAddr string
 }
 
+const (
+	// forwardCountKey is the gRPC metadata key mirroring ForwardCountHeader.
+	forwardCountKey = "x-pyaz-forward-count"
+	// staleKey is the gRPC header metadata key mirroring StaleHeader.
+	staleKey = "x-pyaz-stale"
+)
+
+const (
+	// defaultMetricsInterval is used when a MetricsStream request sets no interval.
+	defaultMetricsInterval = time.Second
+	// minMetricsInterval caps how often a client may ask for snapshots.
+	minMetricsInterval = 250 * time.Millisecond
+)
+
 // NewGRPCServer creates a new gRPC server with the given store.
 func NewGRPCServer(store kv.Store, raftNode *raft.Raft, grpcPort, mandiAddr string) *GRPCServer {
 	return &GRPCServer{
@@ -35,93 +93,626 @@ func NewGRPCServer(store kv.Store, raftNode *raft.Raft, grpcPort, mandiAddr stri
 	}
 }
 
+// leaderClient returns a client for the leader at addr, reusing the cached
+// connection when the leader hasn't changed. A connection to a previous
+// leader is closed; calls still using it fail and are retried by clients.
+func (s *GRPCServer) leaderClient(addr string) (proto.KVServiceClient, error) {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+
+	if s.leaderConn != nil && s.leaderConnAddr == addr {
+		return proto.NewKVServiceClient(s.leaderConn), nil
+	}
+	creds := s.DialCreds
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+	if s.leaderConn != nil {
+		s.leaderConn.Close()
+	}
+	s.leaderConn = conn
+	s.leaderConnAddr = addr
+	return proto.NewKVServiceClient(conn), nil
+}
+
+// Close releases the cached leader connection.
+func (s *GRPCServer) Close() error {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+
+	if s.leaderConn == nil {
+		return nil
+	}
+	err := s.leaderConn.Close()
+	s.leaderConn = nil
+	s.leaderConnAddr = ""
+	return err
+}
+
 // Get retrieves a value by key.
 func (s *GRPCServer) Get(ctx context.Context, req *proto.GetRequest) (*proto.GetResponse, error) {
-	if req.Key == "" {
-		return nil, status.Error(codes.InvalidArgument, "key is required")
+	if err := kv.ValidateKey(req.Key); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-	if s.Raft != nil && s.Raft.State() != raft.Leader {
-		// Automatically forward to leader
-		leaderAddr := s.getLeaderGRPCAddr()
-		if leaderAddr == "" {
-			return nil, status.Error(codes.Unavailable, "Not leader and no leader known")
-		}
-		conn, err := grpc.Dial(leaderAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	// A consistent read goes through the barrier instead of trusting the
+	// lease, so the stale-read policy does not apply to it.
+	stale := !req.Consistent && s.leaseExpired()
+	if stale && s.StaleReads == StaleReadsError {
+		return nil, status.Error(codes.Unavailable, "leader lease expired; refusing possibly stale read")
+	}
+	if s.Raft != nil && (s.Raft.State() != raft.Leader || (stale && s.StaleReads == StaleReadsForward)) {
+		client, fwdCtx, err := s.forwardToLeader(ctx)
 		if err != nil {
-			return nil, status.Errorf(codes.Unavailable, "Cannot connect to leader: %v", err)
+			return nil, err
 		}
-		defer conn.Close()
-		client := proto.NewKVServiceClient(conn)
-		return client.Get(ctx, req)
+		return client.Get(fwdCtx, req)
+	}
+	if stale && s.StaleReads == StaleReadsMark {
+		grpc.SetHeader(ctx, metadata.Pairs(staleKey, "true"))
+	}
+	if req.Consistent {
+		if err := consistentRead(s.Raft); err != nil {
+			return nil, status.Errorf(codes.Unavailable, "consistent read failed: %v", err)
+		}
+	}
+	st, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	value, found, err := getResolved(st, req.Key)
+	if errors.Is(err, kv.ErrDanglingAlias) {
+		// A read of a dangling alias finds nothing rather than conflicting.
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, writeError(err, "get key", req.Key)
 	}
-	value, found := s.Store.Get(req.Key)
 	return &proto.GetResponse{
 		Value: value,
 		Found: found,
 	}, nil
 }
 
+// MultiGet retrieves several keys in one call.
+func (s *GRPCServer) MultiGet(ctx context.Context, req *proto.MultiGetRequest) (*proto.MultiGetResponse, error) {
+	for _, key := range req.Keys {
+		if key == "" {
+			return nil, status.Error(codes.InvalidArgument, "keys must not be empty")
+		}
+		if err := kv.ValidateKey(key); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	stale := s.leaseExpired()
+	if stale && s.StaleReads == StaleReadsError {
+		return nil, status.Error(codes.Unavailable, "leader lease expired; refusing possibly stale read")
+	}
+	if s.Raft != nil && (s.Raft.State() != raft.Leader || (stale && s.StaleReads == StaleReadsForward)) {
+		client, fwdCtx, err := s.forwardToLeader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return client.MultiGet(fwdCtx, req)
+	}
+	if stale && s.StaleReads == StaleReadsMark {
+		grpc.SetHeader(ctx, metadata.Pairs(staleKey, "true"))
+	}
+	st, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	values, found, err := multiGetResolved(st, req.Keys)
+	if err != nil {
+		return nil, writeError(err, "get keys", "")
+	}
+	return &proto.MultiGetResponse{
+		Values:     values,
+		FoundFlags: found,
+	}, nil
+}
+
 // Set stores a key-value pair.
 func (s *GRPCServer) Set(ctx context.Context, req *proto.SetRequest) (*proto.SetResponse, error) {
-	if req.Key == "" {
-		return nil, status.Error(codes.InvalidArgument, "key is required")
+	if err := kv.ValidateKey(req.Key); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if req.RequestId != "" {
+		if err := kv.ValidateRequestID(req.RequestId); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if req.TtlSeconds > 0 || req.MinReplicas > 0 {
+			return nil, status.Error(codes.InvalidArgument, "request_id cannot be combined with ttl_seconds or min_replicas")
+		}
 	}
 	if s.Raft != nil && s.Raft.State() != raft.Leader {
-		// Automatically forward to leader
-		leaderAddr := s.getLeaderGRPCAddr()
-		if leaderAddr == "" {
-			return nil, status.Error(codes.Unavailable, "Not leader and no leader known")
+		client, fwdCtx, err := s.forwardToLeader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return client.Set(fwdCtx, req)
+	}
+	st, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSize(req.Key, req.Value, s.MaxKeyBytes, s.MaxValueBytes); err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if req.TtlSeconds > 0 {
+		if req.MinReplicas > 0 {
+			return nil, status.Error(codes.InvalidArgument, "min_replicas cannot be combined with ttl_seconds")
+		}
+		ts, ok := st.(kv.TTLStore)
+		if !ok {
+			return nil, status.Error(codes.Unimplemented, "ttl_seconds is not supported by this store")
+		}
+		if err := ts.SetWithTTL(req.Key, req.Value, time.Duration(req.TtlSeconds)*time.Second); err != nil {
+			return nil, writeError(err, "set key", req.Key)
+		}
+		return &proto.SetResponse{
+			Success: true,
+		}, nil
+	}
+	if req.MinReplicas > 0 {
+		rs, ok := st.(kv.ReplicatedStore)
+		if !ok {
+			return nil, status.Error(codes.Unimplemented, "min_replicas is not supported by this store")
 		}
-		conn, err := grpc.Dial(leaderAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		replicas, err := rs.SetReplicated(req.Key, req.Value, int(req.MinReplicas))
 		if err != nil {
-			return nil, status.Errorf(codes.Unavailable, "Cannot connect to leader: %v", err)
+			return nil, writeError(err, "replicate key", req.Key)
 		}
-		defer conn.Close()
-		client := proto.NewKVServiceClient(conn)
-		return client.Set(ctx, req)
+		// Falling short of min_replicas still succeeds: the write is
+		// committed, and replicas tells the client by how much.
+		return &proto.SetResponse{
+			Success:  true,
+			Replicas: int32(replicas),
+		}, nil
 	}
-	if err := s.Store.Set(req.Key, req.Value); err != nil {
-		return nil, status.Error(codes.Internal, "failed to set key")
+	var index uint64
+	if req.RequestId != "" {
+		is, ok := st.(kv.IdempotentStore)
+		if !ok {
+			return nil, status.Error(codes.Unimplemented, "request_id is not supported by this store")
+		}
+		index, err = is.SetIdempotent(ctx, req.RequestId, req.Key, req.Value)
+		if errors.Is(err, kv.ErrNotSupported) {
+			return nil, status.Error(codes.Unimplemented, "request_id is not supported by this store")
+		}
+	} else {
+		index, err = setIndexed(ctx, st, req.Key, req.Value)
+	}
+	if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		return nil, status.FromContextError(err).Err()
+	}
+	if err != nil {
+		return nil, writeError(err, "set key", req.Key)
 	}
 	return &proto.SetResponse{
 		Success: true,
+		Index:   index,
 	}, nil
 }
 
 // Delete removes a key from the store.
 func (s *GRPCServer) Delete(ctx context.Context, req *proto.DeleteRequest) (*proto.DeleteResponse, error) {
-	if req.Key == "" {
-		return nil, status.Error(codes.InvalidArgument, "key is required")
+	if err := kv.ValidateKey(req.Key); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 	if s.Raft != nil && s.Raft.State() != raft.Leader {
-		// Automatically forward to leader
-		leaderAddr := s.getLeaderGRPCAddr()
-		if leaderAddr == "" {
-			return nil, status.Error(codes.Unavailable, "Not leader and no leader known")
-		}
-		conn, err := grpc.Dial(leaderAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		client, fwdCtx, err := s.forwardToLeader(ctx)
 		if err != nil {
-			return nil, status.Errorf(codes.Unavailable, "Cannot connect to leader: %v", err)
+			return nil, err
 		}
-		defer conn.Close()
-		client := proto.NewKVServiceClient(conn)
-		return client.Delete(ctx, req)
+		return client.Delete(fwdCtx, req)
 	}
-	if err := s.Store.Delete(req.Key); err != nil {
-		return nil, status.Error(codes.Internal, "failed to delete key")
+	st, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existed, index, err := deleteReported(ctx, st, req.Key)
+	if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		return nil, status.FromContextError(err).Err()
+	}
+	if err != nil {
+		return nil, writeError(err, "delete key", req.Key)
 	}
 	return &proto.DeleteResponse{
 		Success: true,
+		Index:   index,
+		Existed: existed,
+	}, nil
+}
+
+// Scan streams key-value pairs whose key starts with req.Prefix, sorted by key.
+// Matching pairs are captured in one snapshot before streaming begins, so
+// concurrent writes cannot affect an in-progress scan. Send blocks on gRPC
+// flow control, which applies backpressure when the client reads slowly,
+// and a client that stops early simply cancels the stream.
+func (s *GRPCServer) Scan(req *proto.ScanRequest, stream proto.KVService_ScanServer) error {
+	if s.Raft != nil && s.Raft.State() != raft.Leader {
+		client, fwdCtx, err := s.forwardToLeader(stream.Context())
+		if err != nil {
+			return err
+		}
+		leaderStream, err := client.Scan(fwdCtx, req)
+		if err != nil {
+			return err
+		}
+		for {
+			pair, err := leaderStream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(pair); err != nil {
+				return err
+			}
+		}
+	}
+	st, err := s.store(stream.Context())
+	if err != nil {
+		return err
+	}
+	pairs, err := st.Scan(req.Prefix, int(req.Limit))
+	if err != nil {
+		return writeError(err, "scan keys", req.Prefix)
+	}
+	for _, p := range pairs {
+		if err := stream.Send(&proto.KeyValue{Key: p.Key, Value: p.Value}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MetricsStream pushes a snapshot of this node's store metrics every interval
+// until the client disconnects. Metrics are local, so it is never forwarded.
+func (s *GRPCServer) MetricsStream(req *proto.MetricsStreamRequest, stream proto.KVService_MetricsStreamServer) error {
+	if s.Metrics == nil {
+		return status.Error(codes.Unimplemented, "metrics are not enabled on this node")
+	}
+
+	interval := time.Duration(req.IntervalMs) * time.Millisecond
+	if interval == 0 {
+		interval = defaultMetricsInterval
+	}
+	if interval < minMetricsInterval {
+		interval = minMetricsInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m := s.Metrics.GetMetrics()
+		if err := stream.Send(&proto.MetricsSnapshot{
+			GetCount:           m.GetCount,
+			SetCount:           m.SetCount,
+			DeleteCount:        m.DeleteCount,
+			GetAvgLatencyNs:    int64(m.GetAvgLatency),
+			SetAvgLatencyNs:    int64(m.SetAvgLatency),
+			DeleteAvgLatencyNs: int64(m.DeleteAvgLatency),
+			GetP50Ns:           int64(m.GetP50),
+			GetP95Ns:           int64(m.GetP95),
+			GetP99Ns:           int64(m.GetP99),
+			SetP50Ns:           int64(m.SetP50),
+			SetP95Ns:           int64(m.SetP95),
+			SetP99Ns:           int64(m.SetP99),
+			DeleteP50Ns:        int64(m.DeleteP50),
+			DeleteP95Ns:        int64(m.DeleteP95),
+			DeleteP99Ns:        int64(m.DeleteP99),
+		}); err != nil {
+			return err
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Batch applies a list of sets and deletes atomically.
+func (s *GRPCServer) Batch(ctx context.Context, req *proto.BatchRequest) (*proto.BatchResponse, error) {
+	ops := make([]kv.BatchOp, len(req.Ops))
+	for i, op := range req.Ops {
+		ops[i] = kv.BatchOp{Op: op.Op, Key: op.Key, Value: op.Value}
+	}
+	if err := kv.ValidateBatch(ops); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	for i, op := range ops {
+		if err := checkSize(op.Key, op.Value, s.MaxKeyBytes, s.MaxValueBytes); err != nil {
+			return nil, status.Errorf(codes.ResourceExhausted, "op %d: %s", i, err)
+		}
+	}
+	if req.RequestId != "" {
+		if err := kv.ValidateRequestID(req.RequestId); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	if s.Raft != nil && s.Raft.State() != raft.Leader {
+		client, fwdCtx, err := s.forwardToLeader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return client.Batch(fwdCtx, req)
+	}
+	st, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.RequestId != "" {
+		is, ok := st.(kv.IdempotentStore)
+		if !ok {
+			return nil, status.Error(codes.Unimplemented, "request_id is not supported by this store")
+		}
+		err = is.BatchIdempotent(req.RequestId, ops)
+		if errors.Is(err, kv.ErrNotSupported) {
+			return nil, status.Error(codes.Unimplemented, "request_id is not supported by this store")
+		}
+	} else {
+		bs, ok := st.(kv.BatchStore)
+		if !ok {
+			return nil, status.Error(codes.Unimplemented, "batches are not supported by this store")
+		}
+		err = bs.Batch(ops)
+	}
+	if err != nil {
+		return nil, writeError(err, "apply batch", "")
+	}
+	return &proto.BatchResponse{
+		Success: true,
 	}, nil
 }
 
-// getLeaderGRPCAddr queries mandi to get the leader's gRPC address
+// CompareAndSwap replaces a value only if it still equals req.Old. The
+// comparison is applied through Raft, so it is linearizable on the leader.
+func (s *GRPCServer) CompareAndSwap(ctx context.Context, req *proto.CompareAndSwapRequest) (*proto.CompareAndSwapResponse, error) {
+	if err := kv.ValidateKey(req.Key); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := checkSize(req.Key, req.New, s.MaxKeyBytes, s.MaxValueBytes); err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if s.Raft != nil && s.Raft.State() != raft.Leader {
+		client, fwdCtx, err := s.forwardToLeader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return client.CompareAndSwap(fwdCtx, req)
+	}
+	st, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cs, ok := st.(kv.ConditionalStore)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "compare-and-swap is not supported by this store")
+	}
+	swapped, err := cs.CompareAndSwap(req.Key, req.Old, req.New)
+	if err != nil {
+		return nil, writeError(err, "compare-and-swap key", req.Key)
+	}
+	return &proto.CompareAndSwapResponse{
+		Success: swapped,
+	}, nil
+}
+
+// Increment adds req.Delta to an integer value and returns the new total.
+// The sum is computed when the Raft entry is applied, so concurrent
+// increments never lose an update.
+func (s *GRPCServer) Increment(ctx context.Context, req *proto.IncrementRequest) (*proto.IncrementResponse, error) {
+	if err := kv.ValidateKey(req.Key); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := checkSize(req.Key, "", s.MaxKeyBytes, s.MaxValueBytes); err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if req.RequestId != "" {
+		if err := kv.ValidateRequestID(req.RequestId); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	if s.Raft != nil && s.Raft.State() != raft.Leader {
+		client, fwdCtx, err := s.forwardToLeader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return client.Increment(fwdCtx, req)
+	}
+	st, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var n int64
+	if req.RequestId != "" {
+		is, ok := st.(kv.IdempotentStore)
+		if !ok {
+			return nil, status.Error(codes.Unimplemented, "request_id is not supported by this store")
+		}
+		n, err = is.IncrementIdempotent(req.RequestId, req.Key, req.Delta)
+		if errors.Is(err, kv.ErrNotSupported) {
+			return nil, status.Error(codes.Unimplemented, "request_id is not supported by this store")
+		}
+	} else {
+		cs, ok := st.(kv.CounterStore)
+		if !ok {
+			return nil, status.Error(codes.Unimplemented, "increment is not supported by this store")
+		}
+		n, err = cs.Increment(req.Key, req.Delta)
+	}
+	if err != nil {
+		return nil, writeError(err, "increment key", req.Key)
+	}
+	return &proto.IncrementResponse{
+		Value: n,
+	}, nil
+}
+
+// Watch streams changes to the requested key or prefix. It is served by
+// whichever node the client is connected to, since every node applies the
+// same log; a follower's events trail the leader by its replication lag.
+// A consumer that falls too far behind, or any watch open when the node
+// restores a snapshot, is disconnected with ResourceExhausted.
+func (s *GRPCServer) Watch(req *proto.WatchRequest, stream proto.KVService_WatchServer) error {
+	st, err := s.store(stream.Context())
+	if err != nil {
+		return err
+	}
+	ws, ok := st.(kv.WatchStore)
+	if !ok {
+		return status.Error(codes.Unimplemented, "watch is not supported by this store")
+	}
+	ctx := stream.Context()
+	events, err := ws.Watch(ctx, kv.WatchOptions{Key: req.Key, Prefix: req.Prefix, IncludeInitial: req.IncludeInitial})
+	if err != nil {
+		return writeError(err, "start watch", req.Key)
+	}
+	for e := range events {
+		if err := stream.Send(&proto.WatchEvent{Type: e.Type, Key: e.Key, Value: e.Value, Index: e.Index}); err != nil {
+			return err
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return status.Error(codes.ResourceExhausted, "watcher dropped; reconnect with include_initial to resynchronize")
+}
+
+// Role reports this node's Raft role. It is never forwarded.
+func (s *GRPCServer) Role(ctx context.Context, req *proto.RoleRequest) (*proto.RoleResponse, error) {
+	return &proto.RoleResponse{Role: nodeRole(s.Raft)}, nil
+}
+
+// Ping reports this node's ID, Raft state and the gRPC address of the
+// leader it knows of, for health checks and client-side load balancing.
+// It is never forwarded and never touches the store; the leader's address
+// is only looked up once Raft knows there is one.
+func (s *GRPCServer) Ping(ctx context.Context, req *proto.PingRequest) (*proto.PingResponse, error) {
+	if s.Raft == nil {
+		return &proto.PingResponse{NodeId: s.NodeID, RaftState: "single-node"}, nil
+	}
+	resp := &proto.PingResponse{NodeId: s.NodeID, RaftState: s.Raft.State().String()}
+	if leaderAddr, _ := s.Raft.LeaderWithID(); leaderAddr != "" {
+		resp.LeaderAddr = s.getLeaderGRPCAddr()
+	}
+	return resp, nil
+}
+
+// Exists reports whether a key is present without returning its value.
+// It reads this node's local state and is never forwarded, so a follower
+// may briefly lag the leader.
+func (s *GRPCServer) Exists(ctx context.Context, req *proto.ExistsRequest) (*proto.ExistsResponse, error) {
+	if err := kv.ValidateKey(req.Key); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	st, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := existsResolved(st, req.Key)
+	if err != nil {
+		return nil, writeError(err, "check key", req.Key)
+	}
+	return &proto.ExistsResponse{Exists: exists}, nil
+}
+
+// writeError maps a failed store call to the gRPC code errorStatus gives
+// it, so clients can tell a retryable failure from a real one: Unavailable
+// when there is no leader or it lost quorum, DeadlineExceeded when the
+// write was not committed in time, ResourceExhausted over a quota, and
+// Internal for anything unknown. The underlying error text is kept in the
+// message. Failures on the node's side are logged with key, which is
+// empty for writes spanning several keys.
+func writeError(err error, action, key string) error {
+	httpStatus, code := errorStatus(err)
+	if serverFault(httpStatus) {
+		slog.Error("Failed to "+action, "key", key, "code", code.String(), "error", err)
+	}
+	return status.Errorf(code, "failed to %s: %v", action, err)
+}
+
+// leaseExpired reports whether this node is the leader but can no longer
+// confirm contact with a quorum, so its local reads may be stale.
+func (s *GRPCServer) leaseExpired() bool {
+	return s.Lease != nil && s.Raft != nil && s.Raft.State() == raft.Leader && !s.Lease.Valid()
+}
+
+// forwardContext returns an outgoing context carrying an incremented forward
+// count, or an Aborted error once the call has been forwarded more than
+// MaxForwardHops times.
+func (s *GRPCServer) forwardContext(ctx context.Context) (context.Context, error) {
+	hops := 0
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(forwardCountKey); len(v) > 0 {
+			hops, _ = strconv.Atoi(v[0])
+		}
+	}
+	if hops >= hopLimit(s.MaxForwardHops) {
+		return nil, status.Error(codes.Aborted, "loop detected: call forwarded too many times")
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, forwardCountKey, strconv.Itoa(hops+1))
+	if ns := namespaceOf(ctx); ns != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, namespaceKey, ns)
+	}
+	return ctx, nil
+}
+
+// forwardToLeader prepares a call for forwarding to the leader: it returns
+// a client on the cached leader connection and the outgoing context to
+// call it with. Failures are already gRPC statuses: Aborted for a
+// forwarding loop, Unavailable when no leader is known or reachable or
+// mandi names this node itself.
+func (s *GRPCServer) forwardToLeader(ctx context.Context) (proto.KVServiceClient, context.Context, error) {
+	fwdCtx, err := s.forwardContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	leaderAddr := s.getLeaderGRPCAddr()
+	if leaderAddr == "" {
+		slog.Warn("Forwarding to leader failed: no leader known")
+		return nil, nil, status.Error(codes.Unavailable, "Not leader and no leader known")
+	}
+	// A leader whose lease expired keeps registering itself with mandi;
+	// forwarding to ourselves would only loop until the hop limit.
+	if leaderAddr == s.GRPCPort {
+		if s.Raft != nil && s.Raft.State() == raft.Leader {
+			return nil, nil, status.Error(codes.Unavailable, "leader lease expired; refusing possibly stale read")
+		}
+		return nil, nil, status.Error(codes.Unavailable, "Not leader and mandi names this node as leader")
+	}
+	client, err := s.leaderClient(leaderAddr)
+	if err != nil {
+		slog.Warn("Forwarding to leader failed", "leader_addr", leaderAddr, "error", err)
+		return nil, nil, status.Errorf(codes.Unavailable, "Cannot connect to leader: %v", err)
+	}
+	return client, fwdCtx, nil
+}
+
+// getLeaderGRPCAddr queries mandi to get the leader's gRPC address,
+// falling back to the statically configured leader if mandi has none.
 func (s *GRPCServer) getLeaderGRPCAddr() string {
+	if addr := s.queryLeaderGRPCAddr(); addr != "" {
+		return addr
+	}
+	return s.FallbackLeaderAddr
+}
+
+func (s *GRPCServer) queryLeaderGRPCAddr() string {
 	if s.MandiAddr == "" {
 		return ""
 	}
 
-	resp, err := http.Get(s.MandiAddr + "/leader")
+	resp, err := getMandiLeader(s.MandiAddr, s.MandiToken)
 	if err != nil {
 		return ""
 	}
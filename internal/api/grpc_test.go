@@ -0,0 +1,355 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/heysubinoy/pyazdb/api/proto"
+	"github.com/heysubinoy/pyazdb/internal/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// followerRaft returns a Raft node that was never bootstrapped, so it stays
+// a follower with no leader and the API forwards every write and read.
+func followerRaft(t *testing.T) *raft.Raft {
+	t.Helper()
+	cfg := raft.DefaultConfig()
+	cfg.LocalID = "follower"
+	cfg.LogOutput = io.Discard
+	_, trans := raft.NewInmemTransport("")
+	r, err := raft.NewRaft(cfg, store.NewRaftStore(store.NewMemStore(), nil), raft.NewInmemStore(), raft.NewInmemStore(), raft.NewInmemSnapshotStore(), trans)
+	if err != nil {
+		t.Fatalf("raft: %v", err)
+	}
+	t.Cleanup(func() { r.Shutdown().Error() })
+	return r
+}
+
+// leaderRaft returns a single-node Raft cluster once it has elected itself.
+func leaderRaft(t *testing.T) *raft.Raft {
+	t.Helper()
+	cfg := raft.DefaultConfig()
+	cfg.LocalID = "leader"
+	cfg.HeartbeatTimeout = 50 * time.Millisecond
+	cfg.ElectionTimeout = 50 * time.Millisecond
+	cfg.LeaderLeaseTimeout = 50 * time.Millisecond
+	cfg.LogOutput = io.Discard
+	addr, trans := raft.NewInmemTransport("")
+	r, err := raft.NewRaft(cfg, store.NewRaftStore(store.NewMemStore(), nil), raft.NewInmemStore(), raft.NewInmemStore(), raft.NewInmemSnapshotStore(), trans)
+	if err != nil {
+		t.Fatalf("raft: %v", err)
+	}
+	t.Cleanup(func() { r.Shutdown().Error() })
+	r.BootstrapCluster(raft.Configuration{Servers: []raft.Server{{ID: cfg.LocalID, Address: addr}}})
+	deadline := time.Now().Add(5 * time.Second)
+	for r.State() != raft.Leader {
+		if time.Now().After(deadline) {
+			t.Fatal("no leader elected")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return r
+}
+
+// expiredLease returns a lease on r that has lost contact with every voter.
+func expiredLease(r *raft.Raft) *LeaseTracker {
+	return &LeaseTracker{raft: r, failing: map[raft.ServerID]bool{"leader": true}}
+}
+
+// countingListener counts the connections it accepts.
+type countingListener struct {
+	net.Listener
+	accepted atomic.Int64
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.accepted.Add(1)
+	}
+	return conn, err
+}
+
+// serveGRPC serves srv on a loopback port until the test ends.
+func serveGRPC(t *testing.T, srv proto.KVServiceServer) *countingListener {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	counted := &countingListener{Listener: lis}
+	g := grpc.NewServer()
+	proto.RegisterKVServiceServer(g, srv)
+	go g.Serve(counted)
+	t.Cleanup(g.Stop)
+	return counted
+}
+
+func TestForwardContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxHops int
+		hops    string
+		want    string // outgoing count, empty when the call is rejected
+	}{
+		{"first hop", 0, "", "1"},
+		{"under default limit", 0, "2", "3"},
+		{"at default limit", 0, strconv.Itoa(defaultMaxForwardHops), ""},
+		{"under configured limit", 5, "4", "5"},
+		{"at configured limit", 1, "1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &GRPCServer{MaxForwardHops: tt.maxHops}
+			ctx := context.Background()
+			if tt.hops != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(forwardCountKey, tt.hops))
+			}
+			out, err := s.forwardContext(ctx)
+			if tt.want == "" {
+				if status.Code(err) != codes.Aborted {
+					t.Fatalf("err = %v, want Aborted", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("forwardContext: %v", err)
+			}
+			md, _ := metadata.FromOutgoingContext(out)
+			if got := md.Get(forwardCountKey); len(got) != 1 || got[0] != tt.want {
+				t.Errorf("outgoing %s = %v, want [%s]", forwardCountKey, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForwardedGetsShareOneConnection(t *testing.T) {
+	leaderStore := store.NewMemStore()
+	leaderStore.Set("k", "v")
+	lis := serveGRPC(t, &GRPCServer{Store: leaderStore})
+
+	follower := &GRPCServer{Store: store.NewMemStore(), Raft: followerRaft(t), FallbackLeaderAddr: lis.Addr().String()}
+	defer follower.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for i := 0; i < 1000; i++ {
+		resp, err := follower.Get(ctx, &proto.GetRequest{Key: "k"})
+		if err != nil {
+			t.Fatalf("Get %d: %v", i, err)
+		}
+		if resp.Value != "v" {
+			t.Fatalf("Get %d = %q, want v", i, resp.Value)
+		}
+	}
+	if n := lis.accepted.Load(); n != 1 {
+		t.Errorf("1000 forwarded Gets opened %d connections to the leader, want 1", n)
+	}
+}
+
+func TestControlCharacterKeysAreInvalidArgument(t *testing.T) {
+	s := &GRPCServer{Store: store.NewMemStore()}
+	ctx := context.Background()
+	for _, key := range []string{"a\nb", "a\x00b", "\x1b[0m"} {
+		if _, err := s.Get(ctx, &proto.GetRequest{Key: key}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Get(%q) = %v, want InvalidArgument", key, err)
+		}
+		if _, err := s.Set(ctx, &proto.SetRequest{Key: key, Value: "v"}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Set(%q) = %v, want InvalidArgument", key, err)
+		}
+	}
+	if _, err := s.Set(ctx, &proto.SetRequest{Key: "clé/🔑", Value: "v"}); err != nil {
+		t.Errorf("Set of a unicode key = %v, want nil", err)
+	}
+}
+
+// mockLeader records the calls forwarded to it and answers each with a
+// canned response, or with err when set.
+type mockLeader struct {
+	proto.UnimplementedKVServiceServer
+
+	mu    sync.Mutex
+	calls []string
+	hops  []string
+	err   error
+}
+
+func (m *mockLeader) record(ctx context.Context, method string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, method)
+	md, _ := metadata.FromIncomingContext(ctx)
+	m.hops = append(m.hops, md.Get(forwardCountKey)...)
+	return m.err
+}
+
+func (m *mockLeader) Get(ctx context.Context, req *proto.GetRequest) (*proto.GetResponse, error) {
+	if err := m.record(ctx, "Get"); err != nil {
+		return nil, err
+	}
+	return &proto.GetResponse{Value: "from-leader", Found: true}, nil
+}
+
+func (m *mockLeader) Set(ctx context.Context, req *proto.SetRequest) (*proto.SetResponse, error) {
+	if err := m.record(ctx, "Set"); err != nil {
+		return nil, err
+	}
+	return &proto.SetResponse{Success: true, Index: 7}, nil
+}
+
+func (m *mockLeader) Delete(ctx context.Context, req *proto.DeleteRequest) (*proto.DeleteResponse, error) {
+	if err := m.record(ctx, "Delete"); err != nil {
+		return nil, err
+	}
+	return &proto.DeleteResponse{Success: true, Existed: true}, nil
+}
+
+func (m *mockLeader) CompareAndSwap(ctx context.Context, req *proto.CompareAndSwapRequest) (*proto.CompareAndSwapResponse, error) {
+	if err := m.record(ctx, "CompareAndSwap"); err != nil {
+		return nil, err
+	}
+	return &proto.CompareAndSwapResponse{Success: true}, nil
+}
+
+func (m *mockLeader) Increment(ctx context.Context, req *proto.IncrementRequest) (*proto.IncrementResponse, error) {
+	if err := m.record(ctx, "Increment"); err != nil {
+		return nil, err
+	}
+	return &proto.IncrementResponse{Value: 42}, nil
+}
+
+func (m *mockLeader) Batch(ctx context.Context, req *proto.BatchRequest) (*proto.BatchResponse, error) {
+	if err := m.record(ctx, "Batch"); err != nil {
+		return nil, err
+	}
+	return &proto.BatchResponse{}, nil
+}
+
+func TestForwardToLeader(t *testing.T) {
+	leader := &mockLeader{}
+	lis := serveGRPC(t, leader)
+	follower := &GRPCServer{Store: store.NewMemStore(), Raft: followerRaft(t), FallbackLeaderAddr: lis.Addr().String()}
+	defer follower.Close()
+
+	tests := []struct {
+		method string
+		call   func(ctx context.Context) (string, error) // returns what the leader answered
+		want   string
+	}{
+		{"Get", func(ctx context.Context) (string, error) {
+			resp, err := follower.Get(ctx, &proto.GetRequest{Key: "k"})
+			return resp.GetValue(), err
+		}, "from-leader"},
+		{"Set", func(ctx context.Context) (string, error) {
+			resp, err := follower.Set(ctx, &proto.SetRequest{Key: "k", Value: "v"})
+			return strconv.FormatUint(resp.GetIndex(), 10), err
+		}, "7"},
+		{"Delete", func(ctx context.Context) (string, error) {
+			resp, err := follower.Delete(ctx, &proto.DeleteRequest{Key: "k"})
+			return strconv.FormatBool(resp.GetExisted()), err
+		}, "true"},
+		{"CompareAndSwap", func(ctx context.Context) (string, error) {
+			resp, err := follower.CompareAndSwap(ctx, &proto.CompareAndSwapRequest{Key: "k", Old: "a", New: "b"})
+			return strconv.FormatBool(resp.GetSuccess()), err
+		}, "true"},
+		{"Increment", func(ctx context.Context) (string, error) {
+			resp, err := follower.Increment(ctx, &proto.IncrementRequest{Key: "n", Delta: 1})
+			return strconv.FormatInt(resp.GetValue(), 10), err
+		}, "42"},
+		{"Batch", func(ctx context.Context) (string, error) {
+			_, err := follower.Batch(ctx, &proto.BatchRequest{Ops: []*proto.BatchOp{{Op: "set", Key: "k", Value: "v"}}})
+			return "", err
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			leader.mu.Lock()
+			leader.calls, leader.hops, leader.err = nil, nil, nil
+			leader.mu.Unlock()
+
+			got, err := tt.call(context.Background())
+			if err != nil {
+				t.Fatalf("%s: %v", tt.method, err)
+			}
+			if got != tt.want {
+				t.Errorf("%s answered %q, want the leader's %q", tt.method, got, tt.want)
+			}
+			leader.mu.Lock()
+			calls, hops := leader.calls, leader.hops
+			leader.mu.Unlock()
+			if len(calls) != 1 || calls[0] != tt.method {
+				t.Errorf("leader saw %v, want one %s", calls, tt.method)
+			}
+			if len(hops) != 1 || hops[0] != "1" {
+				t.Errorf("leader saw forward count %v, want [1]", hops)
+			}
+
+			// The leader's own failures reach the caller with their code.
+			leader.mu.Lock()
+			leader.err = status.Error(codes.FailedPrecondition, "not an integer")
+			leader.mu.Unlock()
+			if _, err := tt.call(context.Background()); status.Code(err) != codes.FailedPrecondition {
+				t.Errorf("%s with a failing leader = %v, want FailedPrecondition", tt.method, err)
+			}
+		})
+	}
+}
+
+func TestForwardToLeaderFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		leader string
+		hops   string
+		want   codes.Code
+	}{
+		{"no leader known", "", "", codes.Unavailable},
+		{"forwarding loop", "127.0.0.1:1", strconv.Itoa(defaultMaxForwardHops), codes.Aborted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			follower := &GRPCServer{Store: store.NewMemStore(), Raft: followerRaft(t), FallbackLeaderAddr: tt.leader}
+			defer follower.Close()
+			ctx := context.Background()
+			if tt.hops != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(forwardCountKey, tt.hops))
+			}
+			if _, err := follower.Set(ctx, &proto.SetRequest{Key: "k", Value: "v"}); status.Code(err) != tt.want {
+				t.Errorf("Set = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestForwardToSelfIsRefused(t *testing.T) {
+	const self = "127.0.0.1:9090"
+	leader := leaderRaft(t)
+	tests := []struct {
+		name string
+		srv  *GRPCServer
+		msg  string
+	}{
+		{"leader with expired lease", &GRPCServer{Raft: leader, Lease: expiredLease(leader), StaleReads: StaleReadsForward}, "lease expired"},
+		{"follower named by mandi", &GRPCServer{Raft: followerRaft(t)}, "names this node"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.srv.Store = store.NewMemStore()
+			tt.srv.GRPCPort = self
+			tt.srv.FallbackLeaderAddr = self
+			_, err := tt.srv.Get(context.Background(), &proto.GetRequest{Key: "k"})
+			if status.Code(err) != codes.Unavailable || !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("Get = %v, want Unavailable mentioning %q", err, tt.msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// gzipped wraps h so its response is gzip-compressed when the client
+// accepts it and the body reaches s.GzipMinBytes. Smaller bodies are sent
+// as-is, since compressing them costs more than it saves. With
+// GzipMinBytes at 0, h is returned unchanged.
+//
+// Responses relayed from the leader are never compressed twice: forward
+// lets the HTTP client negotiate gzip itself, and the client hands back
+// the body already decompressed.
+func (s *Server) gzipped(h http.HandlerFunc) http.HandlerFunc {
+	if s.GzipMinBytes <= 0 {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r) {
+			h(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w, min: s.GzipMinBytes}
+		defer gw.close()
+		h(gw, r)
+	}
+}
+
+// acceptsGzip reports whether the Accept-Encoding header lists gzip
+// without ruling it out with q=0.
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter holds back the status and the start of the body
+// until it knows whether the body reaches min bytes, then either starts a
+// gzip stream or writes the body through unchanged.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	min    int
+	status int
+	buf    []byte
+	gz     *gzip.Writer
+	plain  bool // decided against compressing
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	switch {
+	case w.gz != nil:
+		return w.gz.Write(b)
+	case w.plain:
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) < w.min {
+		return len(b), nil
+	}
+	if err := w.start(); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// start sends the headers and the buffered body, compressed unless the
+// handler already set its own Content-Encoding.
+func (w *gzipResponseWriter) start() error {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	buf := w.buf
+	w.buf = nil
+	if w.Header().Get("Content-Encoding") != "" {
+		w.plain = true
+		w.ResponseWriter.WriteHeader(w.status)
+		_, err := w.ResponseWriter.Write(buf)
+		return err
+	}
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+	_, err := w.gz.Write(buf)
+	return err
+}
+
+// close finishes the gzip stream, or sends a body that stayed below min
+// as-is.
+func (w *gzipResponseWriter) close() {
+	if w.gz != nil {
+		w.gz.Close()
+		return
+	}
+	if w.plain {
+		return
+	}
+	w.plain = true
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if len(w.buf) > 0 {
+		w.ResponseWriter.Write(w.buf)
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -1,9 +1,18 @@
 package api
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/raft"
 	"github.com/heysubinoy/pyazdb/pkg/kv"
@@ -16,8 +25,65 @@ type Server struct {
 	Raft      *raft.Raft
 	MandiAddr string
 	HTTPPort  string
+
+	// NodeID and RaftAddr identify this node in GET /stats.
+	NodeID   string
+	RaftAddr string
+
+	// MandiToken is sent as a bearer token on mandi lookups.
+	MandiToken string
+
+	// MaxKeyBytes and MaxValueBytes bound the keys and values writes
+	// accept (0 = DefaultMaxKeyBytes / DefaultMaxValueBytes), and
+	// MaxEntryBytes the body of a /batch (0 = store.DefaultMaxEntryBytes).
+	MaxKeyBytes   int
+	MaxValueBytes int
+	MaxEntryBytes int
+
+	// MaxForwardHops bounds how many times a request may be forwarded
+	// between nodes before it is rejected as a loop (0 = default).
+	MaxForwardHops int
+
+	// FallbackLeaderAddr is forwarded to when mandi has no leader to offer.
+	FallbackLeaderAddr string
+
+	// Lease, when set, lets reads detect a leader that lost quorum contact;
+	// StaleReads picks what happens then (one of the StaleReads* policies).
+	Lease      *LeaseTracker
+	StaleReads string
+
+	// AccessLog is the request log format: AccessLogText (default),
+	// AccessLogJSON or AccessLogOff. Set it before RegisterRoutes.
+	AccessLog string
+
+	// GzipMinBytes gzip-compresses bulk read responses (/get, /mget,
+	// /keys, /scan, /list) of at least this many bytes for clients that
+	// accept it (0 = off). Set it before RegisterRoutes.
+	GzipMinBytes int
+
+	// ForwardClient relays requests to the leader; its Timeout covers the
+	// whole exchange, body included, so a stuck leader cannot hold a
+	// follower's handlers forever (nil = DefaultForwardTimeout).
+	ForwardClient *http.Client
 }
 
+// ForwardCountHeader carries the number of times a request has already
+// been forwarded between nodes.
+const ForwardCountHeader = "X-Pyaz-Forward-Count"
+
+// defaultMaxForwardHops is used when no hop limit is configured.
+const defaultMaxForwardHops = 3
+
+// DefaultForwardTimeout bounds a request relayed to the leader when the
+// Server has no ForwardClient of its own.
+const DefaultForwardTimeout = 30 * time.Second
+
+var defaultForwardClient = &http.Client{Timeout: DefaultForwardTimeout}
+
+// mandiClient looks the leader up in mandi, which sits on the path of
+// every forwarded request and so gets a much shorter timeout.
+var mandiClient = &http.Client{Timeout: 5 * time.Second}
+
 // NewServer creates a new HTTP server with the given store.
 func NewServer(store kv.Store, raftNode *raft.Raft, mandiAddr, httpPort string) *Server {
 	return &Server{
@@ -28,45 +94,127 @@ func NewServer(store kv.Store, raftNode *raft.Raft, mandiAddr, httpPort string)
 	}
 }
 
-// RegisterRoutes registers all HTTP handlers on the given mux.
+// RegisterRoutes registers the data HTTP handlers on the given mux, each
+// wrapped in the access log; see RegisterOpsRoutes for the rest. Key routes honour NamespaceHeader, and the
+// bulk reads are gzip-compressed.
 func (s *Server) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("/get", s.handleGet)
-	mux.HandleFunc("/set", s.handleSet)
-	mux.HandleFunc("/delete", s.handleDelete)
+	mux.HandleFunc("/get", s.logged(s.namespaced(s.gzipped(s.handleGet))))
+	mux.HandleFunc("/exists", s.logged(s.namespaced(s.handleExists)))
+	mux.HandleFunc("/mget", s.logged(s.namespaced(s.gzipped(s.handleMultiGet))))
+	mux.HandleFunc("/set", s.logged(s.namespaced(s.handleSet)))
+	mux.HandleFunc("/delete", s.logged(s.namespaced(s.handleDelete)))
+	mux.HandleFunc("/undelete", s.logged(s.namespaced(s.handleUndelete)))
+	mux.HandleFunc("/touch", s.logged(s.namespaced(s.handleTouch)))
+	mux.HandleFunc("/lock", s.logged(s.namespaced(s.handleLock)))
+	mux.HandleFunc("/unlock", s.logged(s.namespaced(s.handleUnlock)))
+	mux.HandleFunc("/alias", s.logged(s.namespaced(s.handleAlias)))
+	mux.HandleFunc("/cas", s.logged(s.namespaced(s.handleCAS)))
+	mux.HandleFunc("/incr", s.logged(s.namespaced(s.handleIncr)))
+	mux.HandleFunc("/batch", s.logged(s.namespaced(s.handleBatch)))
+	mux.HandleFunc("/expire-prefix", s.logged(s.namespaced(s.handleExpirePrefix)))
+	mux.HandleFunc("/role", s.logged(s.handleRole))
+	mux.HandleFunc("/readyz", s.logged(s.handleReadyz))
+	mux.HandleFunc("/config", s.logged(s.handleConfig))
+	mux.HandleFunc("/label", s.logged(s.namespaced(s.handleLabel)))
+	mux.HandleFunc("/meta", s.logged(s.namespaced(s.handleMeta)))
+	mux.HandleFunc("/keys", s.logged(s.namespaced(s.gzipped(s.handleKeys))))
+	mux.HandleFunc("/scan", s.logged(s.namespaced(s.gzipped(s.handleScan))))
+	mux.HandleFunc("/watch", s.logged(s.namespaced(s.handleWatch)))
+	mux.HandleFunc("/list", s.logged(s.namespaced(s.gzipped(s.handleList))))
+	mux.HandleFunc("/oldest", s.logged(s.namespaced(s.handleOldest)))
+	mux.HandleFunc("/newest", s.logged(s.namespaced(s.handleNewest)))
+}
+
+// RegisterOpsRoutes registers the operational endpoints, /stats and
+// /healthz, on the given mux: the one RegisterRoutes uses, or a separate
+// admin mux that can be firewalled apart from the data endpoints.
+func (s *Server) RegisterOpsRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/stats", s.logged(s.handleStats))
+	mux.HandleFunc("/healthz", s.logged(s.handleHealthz))
 }
 
 // handleGet handles GET /get?key=foo requests.
-// Returns the value as plain text or appropriate error codes.
+// Returns the value as plain text or appropriate error codes. With
+// consistent=true the leader runs a Raft barrier first, so the read sees
+// every write committed before it, at the cost of a quorum round trip.
+// With format=json or Accept: application/json it returns
+// {"key": "foo", "value": "bar", "found": true} instead, and a missing key
+// is {"found": false} with 200 rather than a 404. With encoding=base64 the
+// value is base64-encoded in either form.
 func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	if s.Raft != nil && s.Raft.State() != raft.Leader {
-		leaderHTTP := s.getLeaderHTTPAddr()
+	asJSON, err := wantsJSON(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// encoding=base64 encodes the value, so binary values survive JSON
+	// and text clients that expect UTF-8.
+	b64 := false
+	switch r.URL.Query().Get("encoding") {
+	case "":
+	case "base64":
+		b64 = true
+	default:
+		http.Error(w, "encoding must be base64", http.StatusBadRequest)
+		return
+	}
+
+	consistent := false
+	if v := r.URL.Query().Get("consistent"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "consistent must be true or false", http.StatusBadRequest)
+			return
+		}
+		consistent = b
+	}
+
+	// A consistent read goes through the barrier instead of trusting the
+	// lease, so the stale-read policy does not apply to it.
+	stale := !consistent && s.leaseExpired()
+	if stale && s.StaleReads == StaleReadsError {
+		http.Error(w, "Leader lease expired; refusing possibly stale read", http.StatusServiceUnavailable)
+		return
+	}
+
+	if s.Raft != nil && (s.Raft.State() != raft.Leader || (stale && s.StaleReads == StaleReadsForward)) {
+		if s.forwardLoopDetected(w, r) {
+			return
+		}
+		leaderHTTP := s.leaderTarget(w)
 		if leaderHTTP == "" {
-			http.Error(w, "Not leader and no leader known", http.StatusServiceUnavailable)
 			return
 		}
 		// Automatically forward the request to the leader
-		targetURL := "http://" + leaderHTTP + "/get?key=" + r.URL.Query().Get("key")
-		resp, err := http.Get(targetURL)
+		targetURL := "http://" + leaderHTTP + "/get?key=" + url.QueryEscape(r.URL.Query().Get("key"))
+		if consistent {
+			targetURL += "&consistent=true"
+		}
+		if asJSON {
+			targetURL += "&format=json"
+		}
+		if b64 {
+			targetURL += "&encoding=base64"
+		}
+		resp, err := s.forward(r, http.MethodGet, targetURL, nil)
 		if err != nil {
-			http.Error(w, "Failed to forward to leader: "+err.Error(), http.StatusBadGateway)
+			forwardFailed(w, err)
 			return
 		}
 		defer resp.Body.Close()
-		w.WriteHeader(resp.StatusCode)
+		// Headers must be set before WriteHeader, and the body streamed in
+		// full: values can be far larger than any fixed buffer.
 		for k, v := range resp.Header {
 			w.Header()[k] = v
 		}
-		_, _ = w.Write([]byte{})
-		if resp.StatusCode == http.StatusOK {
-			var buf [4096]byte
-			n, _ := resp.Body.Read(buf[:])
-			w.Write(buf[:n])
-		}
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
 		return
 	}
 
@@ -75,8 +223,47 @@ func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Missing key parameter", http.StatusBadRequest)
 		return
 	}
+	if err := kv.ValidateKey(key); err != nil {
+		http.Error(w, "Invalid key: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if stale && s.StaleReads == StaleReadsMark {
+		w.Header().Set(StaleHeader, "true")
+	}
+
+	if consistent {
+		start := time.Now()
+		if err := consistentRead(s.Raft); err != nil {
+			http.Error(w, "Consistent read failed: "+err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		// Report what the guarantee cost, e.g. "barrier;dur=1.204" (ms).
+		w.Header().Set("Server-Timing", fmt.Sprintf("barrier;dur=%.3f", float64(time.Since(start).Microseconds())/1000))
+	}
 
-	value, ok := s.Store.Get(key)
+	value, ok, err := getResolved(s.store(r), key)
+	if errors.Is(err, kv.ErrDanglingAlias) {
+		// A read of a dangling alias finds nothing rather than conflicting.
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeFailed(w, "Failed to get key", key, err)
+		return
+	}
+	if b64 && ok {
+		value = base64.StdEncoding.EncodeToString([]byte(value))
+	}
+	if asJSON {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+			Found bool   `json:"found"`
+		}{Key: key, Value: value, Found: ok})
+		return
+	}
 	if !ok {
 		http.Error(w, "Key not found", http.StatusNotFound)
 		return
@@ -86,8 +273,117 @@ func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(value))
 }
 
+// wantsJSON reports whether a read asked for a JSON response, through
+// format=json or an Accept header listing application/json. An explicit
+// format=text wins over the header.
+func wantsJSON(r *http.Request) (bool, error) {
+	switch r.URL.Query().Get("format") {
+	case "json":
+		return true, nil
+	case "text":
+		return false, nil
+	case "":
+	default:
+		return false, errors.New("format must be json or text")
+	}
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, _, _ := strings.Cut(part, ";")
+			if strings.EqualFold(strings.TrimSpace(mediaType), "application/json") {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// handleExists handles GET /exists?key=foo requests.
+// It reads this node's local state and is never forwarded, so a follower
+// may briefly lag the leader.
+// Returns: {"exists": true}
+func (s *Server) handleExists(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		http.Error(w, "Missing key parameter", http.StatusBadRequest)
+		return
+	}
+	if err := kv.ValidateKey(key); err != nil {
+		http.Error(w, "Invalid key: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	exists, err := existsResolved(s.store(r), key)
+	if err != nil {
+		writeFailed(w, "Failed to check key", key, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"exists": exists})
+}
+
+// handleMultiGet handles POST /mget requests with JSON body.
+// Expects: {"keys": ["a", "b"]}
+// Returns one result per key, in request order:
+// {"results": [{"key": "a", "value": "1", "found": true}, {"key": "b", "value": "", "found": false}]}
+func (s *Server) handleMultiGet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.routeRead(w, r) {
+		return
+	}
+
+	var req struct {
+		Keys []string `json:"keys"`
+	}
+	if err := decodeStrict(r, &req); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, key := range req.Keys {
+		if key == "" {
+			http.Error(w, "keys must not be empty", http.StatusBadRequest)
+			return
+		}
+		if err := kv.ValidateKey(key); err != nil {
+			http.Error(w, "Invalid key: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	values, found, err := multiGetResolved(s.store(r), req.Keys)
+	if err != nil {
+		writeFailed(w, "Failed to get keys", "", err)
+		return
+	}
+
+	type result struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+		Found bool   `json:"found"`
+	}
+	results := make([]result, len(req.Keys))
+	for i, key := range req.Keys {
+		results[i] = result{Key: key, Value: values[i], Found: found[i]}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]result{"results": results})
+}
+
 // handleSet handles POST /set requests with JSON body.
 // Expects: {"key": "foo", "value": "bar"}
+// An optional "min_replicas" delays the response until the write is held
+// by at least that many nodes, and an optional "ttl_seconds" expires the key.
+// Binary values can be sent base64-encoded as "value_b64" instead of "value".
 func (s *Server) handleSet(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -95,30 +391,122 @@ func (s *Server) handleSet(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if s.Raft != nil && s.Raft.State() != raft.Leader {
-		leaderHTTP := s.getLeaderHTTPAddr()
-		if leaderHTTP == "" {
-			http.Error(w, "Not leader and no leader known", http.StatusServiceUnavailable)
+		s.forwardWrite(w, r, "/set")
+		return
+	}
+
+	var req struct {
+		Key         string `json:"key"`
+		Value       string `json:"value"`
+		ValueB64    string `json:"value_b64"`
+		MinReplicas int    `json:"min_replicas"`
+		TTLSeconds  int64  `json:"ttl_seconds"`
+	}
+
+	if !decodeLimited(w, r, &req, maxSetBody(s.MaxKeyBytes, s.MaxValueBytes)) {
+		return
+	}
+
+	if req.Key == "" {
+		http.Error(w, "Missing key field", http.StatusBadRequest)
+		return
+	}
+	if err := kv.ValidateKey(req.Key); err != nil {
+		http.Error(w, "Invalid key: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// value_b64 carries values JSON strings can't, such as invalid UTF-8.
+	if req.ValueB64 != "" {
+		if req.Value != "" {
+			http.Error(w, "value and value_b64 are mutually exclusive", http.StatusBadRequest)
 			return
 		}
-		// Automatically forward the request to the leader
-		targetURL := "http://" + leaderHTTP + "/set"
-		resp, err := http.Post(targetURL, "application/json", r.Body)
+		value, err := base64.StdEncoding.DecodeString(req.ValueB64)
 		if err != nil {
-			http.Error(w, "Failed to forward to leader: "+err.Error(), http.StatusBadGateway)
+			http.Error(w, "Invalid value_b64: "+err.Error(), http.StatusBadRequest)
 			return
 		}
-		defer resp.Body.Close()
-		w.WriteHeader(resp.StatusCode)
+		req.Value = string(value)
+	}
+
+	if err := checkSize(req.Key, req.Value, s.MaxKeyBytes, s.MaxValueBytes); err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if req.TTLSeconds > 0 {
+		if req.MinReplicas > 0 {
+			http.Error(w, "min_replicas cannot be combined with ttl_seconds", http.StatusBadRequest)
+			return
+		}
+		ts, ok := s.store(r).(kv.TTLStore)
+		if !ok {
+			http.Error(w, "ttl_seconds is not supported by this store", http.StatusNotImplemented)
+			return
+		}
+		if err := ts.SetWithTTL(req.Key, req.Value, time.Duration(req.TTLSeconds)*time.Second); err != nil {
+			writeFailed(w, "Failed to set key", req.Key, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if req.MinReplicas > 0 {
+		rs, ok := s.store(r).(kv.ReplicatedStore)
+		if !ok {
+			http.Error(w, "min_replicas is not supported by this store", http.StatusNotImplemented)
+			return
+		}
+		replicas, err := rs.SetReplicated(req.Key, req.Value, req.MinReplicas)
+		if err != nil {
+			writeFailed(w, "Failed to replicate key", req.Key, err)
+			return
+		}
+		w.Header().Set(ReplicasHeader, strconv.Itoa(replicas))
+		if replicas < req.MinReplicas {
+			// Committed, so not a failure; the client decides whether the
+			// shortfall matters.
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprintf(w, "Committed, but confirmed on only %d of %d replicas\n", replicas, req.MinReplicas)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	index, err := setIndexed(r.Context(), s.store(r), req.Key, req.Value)
+	if err != nil {
+		writeFailed(w, "Failed to set key", req.Key, err)
+		return
+	}
+
+	if index != 0 {
+		w.Header().Set(IndexHeader, strconv.FormatUint(index, 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDelete handles POST /delete requests with JSON body.
+// Expects: {"key": "foo"}
+// Answers 204 either way; ExistedHeader tells whether the key existed.
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.Raft != nil && s.Raft.State() != raft.Leader {
+		s.forwardWrite(w, r, "/delete")
 		return
 	}
 
 	var req struct {
-		Key   string `json:"key"`
-		Value string `json:"value"`
+		Key string `json:"key"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	if !decodeLimited(w, r, &req, maxSetBody(s.MaxKeyBytes, s.MaxValueBytes)) {
 		return
 	}
 
@@ -126,38 +514,35 @@ func (s *Server) handleSet(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Missing key field", http.StatusBadRequest)
 		return
 	}
+	if err := kv.ValidateKey(req.Key); err != nil {
+		http.Error(w, "Invalid key: "+err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	if err := s.Store.Set(req.Key, req.Value); err != nil {
-		http.Error(w, "Failed to set key", http.StatusInternalServerError)
+	existed, index, err := deleteReported(r.Context(), s.store(r), req.Key)
+	if err != nil {
+		writeFailed(w, "Failed to delete key", req.Key, err)
 		return
 	}
 
+	if index != 0 {
+		w.Header().Set(IndexHeader, strconv.FormatUint(index, 10))
+	}
+	w.Header().Set(ExistedHeader, strconv.FormatBool(existed))
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// handleDelete handles POST /delete requests with JSON body.
+// handleUndelete handles POST /undelete requests with JSON body.
 // Expects: {"key": "foo"}
-func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+// Restores a soft-deleted key within the configured grace period.
+func (s *Server) handleUndelete(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	if s.Raft != nil && s.Raft.State() != raft.Leader {
-		leaderHTTP := s.getLeaderHTTPAddr()
-		if leaderHTTP == "" {
-			http.Error(w, "Not leader and no leader known", http.StatusServiceUnavailable)
-			return
-		}
-		// Automatically forward the request to the leader
-		targetURL := "http://" + leaderHTTP + "/delete"
-		resp, err := http.Post(targetURL, "application/json", r.Body)
-		if err != nil {
-			http.Error(w, "Failed to forward to leader: "+err.Error(), http.StatusBadGateway)
-			return
-		}
-		defer resp.Body.Close()
-		w.WriteHeader(resp.StatusCode)
+		s.forwardWrite(w, r, "/undelete")
 		return
 	}
 
@@ -165,8 +550,57 @@ func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
 		Key string `json:"key"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	if !decodeLimited(w, r, &req, maxSetBody(s.MaxKeyBytes, s.MaxValueBytes)) {
+		return
+	}
+
+	if req.Key == "" {
+		http.Error(w, "Missing key field", http.StatusBadRequest)
+		return
+	}
+	if err := kv.ValidateKey(req.Key); err != nil {
+		http.Error(w, "Invalid key: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	us, ok := s.store(r).(kv.UndeleteStore)
+	if !ok {
+		http.Error(w, "Undelete is not supported by this store", http.StatusNotImplemented)
+		return
+	}
+	restored, err := us.Undelete(req.Key)
+	if err != nil {
+		writeFailed(w, "Failed to undelete key", req.Key, err)
+		return
+	}
+	if !restored {
+		http.Error(w, "No recoverable deleted key", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTouch handles POST /touch requests with JSON body.
+// Expects: {"key": "foo", "ttl_seconds": 60}
+// Resets the key's TTL without rewriting its value.
+func (s *Server) handleTouch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.Raft != nil && s.Raft.State() != raft.Leader {
+		s.forwardWrite(w, r, "/touch")
+		return
+	}
+
+	var req struct {
+		Key        string `json:"key"`
+		TTLSeconds int64  `json:"ttl_seconds"`
+	}
+
+	if !decodeLimited(w, r, &req, maxSetBody(s.MaxKeyBytes, s.MaxValueBytes)) {
 		return
 	}
 
@@ -174,22 +608,1207 @@ func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Missing key field", http.StatusBadRequest)
 		return
 	}
+	if err := kv.ValidateKey(req.Key); err != nil {
+		http.Error(w, "Invalid key: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.TTLSeconds <= 0 {
+		http.Error(w, "ttl_seconds must be positive", http.StatusBadRequest)
+		return
+	}
 
-	if err := s.Store.Delete(req.Key); err != nil {
-		http.Error(w, "Failed to delete key", http.StatusInternalServerError)
+	ts, ok := s.store(r).(kv.TTLStore)
+	if !ok {
+		http.Error(w, "Touch is not supported by this store", http.StatusNotImplemented)
+		return
+	}
+	touched, err := ts.Touch(req.Key, time.Duration(req.TTLSeconds)*time.Second)
+	if err != nil {
+		writeFailed(w, "Failed to touch key", req.Key, err)
+		return
+	}
+	if !touched {
+		http.Error(w, "Key not found", http.StatusNotFound)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// getLeaderHTTPAddr queries mandi to get the leader's HTTP address
-func (s *Server) getLeaderHTTPAddr() string {
+// handleLock handles POST /lock requests with JSON body.
+// Expects: {"key": "jobs/leader", "value": "worker-7", "ttl_seconds": 30}
+// Sets the key only if it is absent, in one replicated command. Returns
+// 204 if the lock was acquired and 409 if another holder has it.
+func (s *Server) handleLock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.Raft != nil && s.Raft.State() != raft.Leader {
+		s.forwardWrite(w, r, "/lock")
+		return
+	}
+
+	var req struct {
+		Key        string `json:"key"`
+		Value      string `json:"value"`
+		TTLSeconds int64  `json:"ttl_seconds"`
+	}
+
+	if !decodeLimited(w, r, &req, maxSetBody(s.MaxKeyBytes, s.MaxValueBytes)) {
+		return
+	}
+
+	if req.Key == "" || req.Value == "" {
+		http.Error(w, "Missing key or value field", http.StatusBadRequest)
+		return
+	}
+	if err := kv.ValidateKey(req.Key); err != nil {
+		http.Error(w, "Invalid key: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := checkSize(req.Key, req.Value, s.MaxKeyBytes, s.MaxValueBytes); err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if req.TTLSeconds <= 0 {
+		http.Error(w, "ttl_seconds must be positive", http.StatusBadRequest)
+		return
+	}
+
+	cs, ok := s.store(r).(kv.ConditionalStore)
+	if !ok {
+		http.Error(w, "Locks are not supported by this store", http.StatusNotImplemented)
+		return
+	}
+	acquired, err := cs.SetNXWithTTL(req.Key, req.Value, time.Duration(req.TTLSeconds)*time.Second)
+	if err != nil {
+		writeFailed(w, "Failed to acquire lock", req.Key, err)
+		return
+	}
+	if !acquired {
+		http.Error(w, "Lock is held", http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleUnlock handles POST /unlock requests with JSON body.
+// Expects: {"key": "jobs/leader", "value": "worker-7"}
+// Deletes the key only if it still holds value, so a holder whose lease
+// expired cannot release a lock someone else has since acquired.
+func (s *Server) handleUnlock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.Raft != nil && s.Raft.State() != raft.Leader {
+		s.forwardWrite(w, r, "/unlock")
+		return
+	}
+
+	var req struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+
+	if !decodeLimited(w, r, &req, maxSetBody(s.MaxKeyBytes, s.MaxValueBytes)) {
+		return
+	}
+
+	if req.Key == "" || req.Value == "" {
+		http.Error(w, "Missing key or value field", http.StatusBadRequest)
+		return
+	}
+	if err := kv.ValidateKey(req.Key); err != nil {
+		http.Error(w, "Invalid key: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := checkSize(req.Key, req.Value, s.MaxKeyBytes, s.MaxValueBytes); err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	cs, ok := s.store(r).(kv.ConditionalStore)
+	if !ok {
+		http.Error(w, "Locks are not supported by this store", http.StatusNotImplemented)
+		return
+	}
+	released, err := cs.DeleteIf(req.Key, req.Value)
+	if err != nil {
+		writeFailed(w, "Failed to release lock", req.Key, err)
+		return
+	}
+	if !released {
+		http.Error(w, "Lock is not held with this value", http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleBatch handles POST /batch requests with JSON body.
+// Expects: {"ops": [{"op": "set", "key": "k", "value": "v"}, {"op": "delete", "key": "x"}]}
+// Applies the ops in order as a single Raft entry, so either all of them
+// take effect on every node or none do.
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.Raft != nil && s.Raft.State() != raft.Leader {
+		s.forwardWrite(w, r, "/batch")
+		return
+	}
+
+	var req struct {
+		Ops []struct {
+			Op    string `json:"op"`
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"ops"`
+	}
+
+	if !decodeLimited(w, r, &req, maxBatchBody(s.MaxEntryBytes)) {
+		return
+	}
+
+	ops := make([]kv.BatchOp, len(req.Ops))
+	for i, op := range req.Ops {
+		ops[i] = kv.BatchOp{Op: op.Op, Key: op.Key, Value: op.Value}
+	}
+	if err := kv.ValidateBatch(ops); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for i, op := range ops {
+		if err := checkSize(op.Key, op.Value, s.MaxKeyBytes, s.MaxValueBytes); err != nil {
+			http.Error(w, fmt.Sprintf("op %d: %s", i, err), http.StatusRequestEntityTooLarge)
+			return
+		}
+	}
+
+	bs, ok := s.store(r).(kv.BatchStore)
+	if !ok {
+		http.Error(w, "Batches are not supported by this store", http.StatusNotImplemented)
+		return
+	}
+	if err := bs.Batch(ops); err != nil {
+		writeFailed(w, "Failed to apply batch", "", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleCAS handles POST /cas requests with JSON body.
+// Expects: {"key": "foo", "old": "bar", "new": "baz"}
+// Replaces the value only if it still equals old, and returns
+// {"success": true|false}; a mismatch is not an error.
+func (s *Server) handleCAS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.Raft != nil && s.Raft.State() != raft.Leader {
+		s.forwardWrite(w, r, "/cas")
+		return
+	}
+
+	var req struct {
+		Key string `json:"key"`
+		Old string `json:"old"`
+		New string `json:"new"`
+	}
+
+	if !decodeLimited(w, r, &req, maxSetBody(s.MaxKeyBytes, s.MaxValueBytes)) {
+		return
+	}
+
+	if req.Key == "" {
+		http.Error(w, "Missing key field", http.StatusBadRequest)
+		return
+	}
+	if err := kv.ValidateKey(req.Key); err != nil {
+		http.Error(w, "Invalid key: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := checkSize(req.Key, req.New, s.MaxKeyBytes, s.MaxValueBytes); err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	cs, ok := s.store(r).(kv.ConditionalStore)
+	if !ok {
+		http.Error(w, "Compare-and-swap is not supported by this store", http.StatusNotImplemented)
+		return
+	}
+	swapped, err := cs.CompareAndSwap(req.Key, req.Old, req.New)
+	if err != nil {
+		writeFailed(w, "Failed to compare-and-swap key", req.Key, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": swapped})
+}
+
+// handleIncr handles POST /incr requests with JSON body.
+// Expects: {"key": "visits", "delta": 1}
+// Adds delta to the key's integer value (a missing key counts as 0) and
+// returns {"value": <new total>}.
+func (s *Server) handleIncr(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.Raft != nil && s.Raft.State() != raft.Leader {
+		s.forwardWrite(w, r, "/incr")
+		return
+	}
+
+	var req struct {
+		Key   string `json:"key"`
+		Delta int64  `json:"delta"`
+	}
+
+	if !decodeLimited(w, r, &req, maxSetBody(s.MaxKeyBytes, s.MaxValueBytes)) {
+		return
+	}
+
+	if req.Key == "" {
+		http.Error(w, "Missing key field", http.StatusBadRequest)
+		return
+	}
+	if err := kv.ValidateKey(req.Key); err != nil {
+		http.Error(w, "Invalid key: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := checkSize(req.Key, "", s.MaxKeyBytes, s.MaxValueBytes); err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	cs, ok := s.store(r).(kv.CounterStore)
+	if !ok {
+		http.Error(w, "Increment is not supported by this store", http.StatusNotImplemented)
+		return
+	}
+	n, err := cs.Increment(req.Key, req.Delta)
+	if err != nil {
+		writeFailed(w, "Failed to increment key", req.Key, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"value": n})
+}
+
+// handleAlias handles POST /alias requests with JSON body.
+// Expects: {"alias": "current", "target": "v42"}
+// Points alias at target so /get on the alias reads the target's value.
+// An empty target removes the alias. Returns 409 if the alias would form
+// a cycle or a chain deeper than kv.MaxAliasDepth, or if target doesn't
+// resolve to an existing key.
+func (s *Server) handleAlias(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.Raft != nil && s.Raft.State() != raft.Leader {
+		s.forwardWrite(w, r, "/alias")
+		return
+	}
+
+	var req struct {
+		Alias  string `json:"alias"`
+		Target string `json:"target"`
+	}
+
+	if !decodeLimited(w, r, &req, maxSetBody(s.MaxKeyBytes, s.MaxValueBytes)) {
+		return
+	}
+
+	if req.Alias == "" {
+		http.Error(w, "Missing alias field", http.StatusBadRequest)
+		return
+	}
+	if err := kv.ValidateKey(req.Alias); err != nil {
+		http.Error(w, "Invalid alias: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := checkSize(req.Alias, req.Target, s.MaxKeyBytes, s.MaxValueBytes); err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	as, ok := s.store(r).(kv.AliasStore)
+	if !ok {
+		http.Error(w, "Aliases are not supported by this store", http.StatusNotImplemented)
+		return
+	}
+	if err := as.SetAlias(req.Alias, req.Target); err != nil {
+		writeFailed(w, "Failed to set alias", req.Alias, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// forwardWrite relays a write request to the leader's path and copies the
+// leader's response back to the client.
+func (s *Server) forwardWrite(w http.ResponseWriter, r *http.Request, path string) {
+	if s.forwardLoopDetected(w, r) {
+		return
+	}
+	leaderHTTP := s.leaderTarget(w)
+	if leaderHTTP == "" {
+		return
+	}
+	resp, err := s.forward(r, http.MethodPost, "http://"+leaderHTTP+path, r.Body)
+	if err != nil {
+		forwardFailed(w, err)
+		return
+	}
+	defer resp.Body.Close()
+	relayResponse(w, resp)
+}
+
+// handleExpirePrefix handles POST /expire-prefix requests with JSON body.
+// Expects: {"prefix": "cache/", "ttl_seconds": 3600}
+// Applies one TTL to every matching key in a single replicated command
+// and returns {"count": N} with the number of keys affected.
+func (s *Server) handleExpirePrefix(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.Raft != nil && s.Raft.State() != raft.Leader {
+		s.forwardWrite(w, r, "/expire-prefix")
+		return
+	}
+
+	var req struct {
+		Prefix     string `json:"prefix"`
+		TTLSeconds int64  `json:"ttl_seconds"`
+	}
+
+	if !decodeLimited(w, r, &req, maxSetBody(s.MaxKeyBytes, s.MaxValueBytes)) {
+		return
+	}
+
+	if req.Prefix == "" {
+		http.Error(w, "Missing prefix field", http.StatusBadRequest)
+		return
+	}
+	if req.TTLSeconds <= 0 {
+		http.Error(w, "ttl_seconds must be positive", http.StatusBadRequest)
+		return
+	}
+
+	ts, ok := s.store(r).(kv.TTLStore)
+	if !ok {
+		http.Error(w, "TTLs are not supported by this store", http.StatusNotImplemented)
+		return
+	}
+	count, err := ts.ExpirePrefix(req.Prefix, time.Duration(req.TTLSeconds)*time.Second)
+	if err != nil {
+		writeFailed(w, "Failed to expire keys", req.Prefix, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"count": count})
+}
+
+// handleLabel handles POST /label requests with JSON body.
+// Expects: {"key": "foo", "labels": {"owner": "team-x", "env": "prod"}}
+// Replaces the key's labels; an empty object clears them.
+func (s *Server) handleLabel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.Raft != nil && s.Raft.State() != raft.Leader {
+		s.forwardWrite(w, r, "/label")
+		return
+	}
+
+	var req struct {
+		Key    string            `json:"key"`
+		Labels map[string]string `json:"labels"`
+	}
+
+	if !decodeLimited(w, r, &req, maxSetBody(s.MaxKeyBytes, s.MaxValueBytes)) {
+		return
+	}
+
+	if req.Key == "" {
+		http.Error(w, "Missing key field", http.StatusBadRequest)
+		return
+	}
+	if err := kv.ValidateKey(req.Key); err != nil {
+		http.Error(w, "Invalid key: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := checkSize(req.Key, "", s.MaxKeyBytes, s.MaxValueBytes); err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err := kv.ValidateLabels(req.Labels); err != nil {
+		http.Error(w, "Invalid labels: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ls, ok := s.store(r).(kv.LabelStore)
+	if !ok {
+		http.Error(w, "Labels are not supported by this store", http.StatusNotImplemented)
+		return
+	}
+	labeled, err := ls.SetLabels(req.Key, req.Labels)
+	if err != nil {
+		writeFailed(w, "Failed to label key", req.Key, err)
+		return
+	}
+	if !labeled {
+		http.Error(w, "Key not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMeta handles GET /meta?key=foo requests.
+// Returns the value with its labels, expiry and timestamps as JSON.
+func (s *Server) handleMeta(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.routeRead(w, r) {
+		return
+	}
+
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		http.Error(w, "Missing key parameter", http.StatusBadRequest)
+		return
+	}
+	if err := kv.ValidateKey(key); err != nil {
+		http.Error(w, "Invalid key: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ls, ok := s.store(r).(kv.LabelStore)
+	if !ok {
+		http.Error(w, "Metadata is not supported by this store", http.StatusNotImplemented)
+		return
+	}
+	entry, found := ls.GetWithMeta(key)
+	if !found {
+		http.Error(w, "Key not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newEntryResponse(key, entry))
+}
+
+// entryResponse is the JSON form of a key with its metadata.
+type entryResponse struct {
+	Key       string            `json:"key"`
+	Value     string            `json:"value"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"`
+	CreatedAt *time.Time        `json:"created_at,omitempty"`
+	UpdatedAt *time.Time        `json:"updated_at,omitempty"`
+}
+
+func newEntryResponse(key string, entry kv.Entry) entryResponse {
+	resp := entryResponse{Key: key, Value: entry.Value, Labels: entry.Labels}
+	if !entry.ExpiresAt.IsZero() {
+		resp.ExpiresAt = &entry.ExpiresAt
+	}
+	if !entry.CreatedAt.IsZero() {
+		resp.CreatedAt = &entry.CreatedAt
+	}
+	if !entry.UpdatedAt.IsZero() {
+		resp.UpdatedAt = &entry.UpdatedAt
+	}
+	return resp
+}
+
+// handleOldest handles GET /oldest?prefix=p&by=created|updated requests.
+func (s *Server) handleOldest(w http.ResponseWriter, r *http.Request) {
+	s.handleExtreme(w, r, false)
+}
+
+// handleNewest handles GET /newest?prefix=p&by=created|updated requests.
+func (s *Server) handleNewest(w http.ResponseWriter, r *http.Request) {
+	s.handleExtreme(w, r, true)
+}
+
+// handleExtreme returns the key under prefix with the earliest or latest
+// creation (default) or update time, with its metadata, as JSON.
+// Every matching key is scanned, so the cost grows with the keyspace.
+func (s *Server) handleExtreme(w http.ResponseWriter, r *http.Request, newest bool) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.routeRead(w, r) {
+		return
+	}
+
+	q := r.URL.Query()
+	var byUpdate bool
+	switch q.Get("by") {
+	case "", "created":
+	case "updated":
+		byUpdate = true
+	default:
+		http.Error(w, "by must be created or updated", http.StatusBadRequest)
+		return
+	}
+
+	as, ok := s.store(r).(kv.AgeStore)
+	if !ok {
+		http.Error(w, "Key timestamps are not supported by this store", http.StatusNotImplemented)
+		return
+	}
+	find := as.Oldest
+	if newest {
+		find = as.Newest
+	}
+	key, entry, found := find(q.Get("prefix"), byUpdate)
+	if !found {
+		http.Error(w, "No keys match prefix", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newEntryResponse(key, entry))
+}
+
+// handleKeys handles GET /keys?prefix=p&label=env:prod&limit=N requests.
+// Returns the matching keys as a JSON array, sorted. Without a label it
+// lists every key under the prefix. With with_values=true it returns
+// [{"key": ..., "value": ...}] instead, saving a Get per key.
+func (s *Server) handleKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.routeRead(w, r) {
+		return
+	}
+
+	q := r.URL.Query()
+	prefix := q.Get("prefix")
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	withValues := false
+	if v := q.Get("with_values"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "with_values must be true or false", http.StatusBadRequest)
+			return
+		}
+		withValues = b
+	}
+
+	// Both paths read all matching pairs under one consistent view of the
+	// store, so values are never mixed from before and after a write.
+	var pairs []kv.KeyValue
+	if label := q.Get("label"); label != "" {
+		name, value, ok := strings.Cut(label, ":")
+		if !ok || name == "" {
+			http.Error(w, "label must be name:value", http.StatusBadRequest)
+			return
+		}
+		ls, ok := s.store(r).(kv.LabelStore)
+		if !ok {
+			http.Error(w, "Labels are not supported by this store", http.StatusNotImplemented)
+			return
+		}
+		matched, err := ls.ScanLabel(prefix, name, value, limit)
+		if err != nil {
+			writeFailed(w, "Failed to list keys", prefix, err)
+			return
+		}
+		pairs = matched
+	} else {
+		scanned, err := s.store(r).Scan(prefix, limit)
+		if err != nil {
+			writeFailed(w, "Failed to list keys", prefix, err)
+			return
+		}
+		pairs = scanned
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if withValues {
+		if pairs == nil {
+			pairs = []kv.KeyValue{}
+		}
+		json.NewEncoder(w).Encode(pairs)
+		return
+	}
+	keys := make([]string, len(pairs))
+	for i, p := range pairs {
+		keys[i] = p.Key
+	}
+	json.NewEncoder(w).Encode(keys)
+}
+
+// handleScan handles GET /scan?prefix=p&limit=N requests.
+// Returns the pairs under the prefix as [{"key": ..., "value": ...}],
+// sorted by key; the HTTP counterpart of the gRPC Scan stream.
+func (s *Server) handleScan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.routeRead(w, r) {
+		return
+	}
+
+	q := r.URL.Query()
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	pairs, err := s.store(r).Scan(q.Get("prefix"), limit)
+	if err != nil {
+		writeFailed(w, "Failed to scan keys", q.Get("prefix"), err)
+		return
+	}
+	if pairs == nil {
+		pairs = []kv.KeyValue{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pairs)
+}
+
+// handleWatch handles GET /watch?prefix=p (or ?key=k) requests, streaming
+// changes as Server-Sent Events: one `data: {"op": "set", "key": ...,
+// "value": ..., "index": N}` line per event, flushed immediately. With
+// include_initial=true the matching keys are sent first with op "initial".
+// Like the gRPC Watch it is served locally, never forwarded; the stream
+// ends when the client disconnects or falls too far behind.
+func (s *Server) handleWatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	opts := kv.WatchOptions{Key: q.Get("key"), Prefix: q.Get("prefix")}
+	if v := q.Get("include_initial"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "include_initial must be true or false", http.StatusBadRequest)
+			return
+		}
+		opts.IncludeInitial = b
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	// A watch outlives the server's read and write timeouts by design.
+	clearDeadlines(w)
+	ws, ok := s.store(r).(kv.WatchStore)
+	if !ok {
+		http.Error(w, "Watch is not supported by this store", http.StatusNotImplemented)
+		return
+	}
+	// The request context ends the watch, and with it the subscription,
+	// as soon as the client goes away.
+	events, err := ws.Watch(r.Context(), opts)
+	if err != nil {
+		writeFailed(w, "Failed to start watch", opts.Key, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for e := range events {
+		data, _ := json.Marshal(struct {
+			Op    string `json:"op"`
+			Key   string `json:"key"`
+			Value string `json:"value,omitempty"`
+			Index uint64 `json:"index"`
+		}{strings.ToLower(e.Type), e.Key, e.Value, e.Index})
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// handleList handles GET /list?prefix=config/&delimiter=/ requests.
+// Returns only the immediate children of prefix, like an S3 delimiter
+// listing: {"keys": [...], "folders": [...]}, where each folder is a
+// prefix ending in the delimiter that groups deeper keys. The delimiter
+// defaults to "/".
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.routeRead(w, r) {
+		return
+	}
+
+	q := r.URL.Query()
+	prefix := q.Get("prefix")
+	delimiter := q.Get("delimiter")
+	if delimiter == "" {
+		delimiter = "/"
+	}
+
+	pairs, err := s.store(r).Scan(prefix, 0)
+	if err != nil {
+		writeFailed(w, "Failed to list keys", prefix, err)
+		return
+	}
+
+	// Scan results are sorted, so keys in the same folder are adjacent.
+	resp := struct {
+		Keys    []string `json:"keys"`
+		Folders []string `json:"folders"`
+	}{Keys: []string{}, Folders: []string{}}
+	for _, p := range pairs {
+		rest := p.Key[len(prefix):]
+		i := strings.Index(rest, delimiter)
+		if i < 0 {
+			resp.Keys = append(resp.Keys, p.Key)
+			continue
+		}
+		folder := p.Key[:len(prefix)+i+len(delimiter)]
+		if n := len(resp.Folders); n == 0 || resp.Folders[n-1] != folder {
+			resp.Folders = append(resp.Folders, folder)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// routeRead applies the stale-read policy and forwards the read to the
+// leader when this node is a follower (or a stale leader with the forward
+// policy). Reports whether the request was handled.
+func (s *Server) routeRead(w http.ResponseWriter, r *http.Request) bool {
+	stale := s.leaseExpired()
+	if stale && s.StaleReads == StaleReadsError {
+		http.Error(w, "Leader lease expired; refusing possibly stale read", http.StatusServiceUnavailable)
+		return true
+	}
+	if s.forwardReadToLeader(w, r, stale && s.StaleReads == StaleReadsForward) {
+		return true
+	}
+	if stale && s.StaleReads == StaleReadsMark {
+		w.Header().Set(StaleHeader, "true")
+	}
+	return false
+}
+
+// forwardReadToLeader relays a read to the leader, query string and any
+// POST body included, when this node is a follower or force is set.
+// Reports whether the request was handled.
+func (s *Server) forwardReadToLeader(w http.ResponseWriter, r *http.Request, force bool) bool {
+	if s.Raft == nil || (s.Raft.State() == raft.Leader && !force) {
+		return false
+	}
+	if s.forwardLoopDetected(w, r) {
+		return true
+	}
+	leaderHTTP := s.leaderTarget(w)
+	if leaderHTTP == "" {
+		return true
+	}
+	var body io.Reader
+	if r.Method != http.MethodGet {
+		body = r.Body
+	}
+	resp, err := s.forward(r, r.Method, "http://"+leaderHTTP+r.URL.RequestURI(), body)
+	if err != nil {
+		forwardFailed(w, err)
+		return true
+	}
+	defer resp.Body.Close()
+	relayResponse(w, resp)
+	return true
+}
+
+// raftIndexes is how far this node's log has progressed.
+type raftIndexes struct {
+	Commit, Applied, Durable, Snapshot uint64
+}
+
+// raftIndexesOf reads r's progress from stats, a raft.Stats() map. Durable
+// is the last committed entry in stable storage: the log's last index
+// includes entries not yet committed, which an election may still discard.
+func raftIndexesOf(r *raft.Raft, stats map[string]string) raftIndexes {
+	commit, _ := strconv.ParseUint(stats["commit_index"], 10, 64)
+	snapshot, _ := strconv.ParseUint(stats["last_snapshot_index"], 10, 64)
+	return raftIndexes{
+		Commit:   commit,
+		Applied:  r.AppliedIndex(),
+		Durable:  min(r.LastIndex(), commit),
+		Snapshot: snapshot,
+	}
+}
+
+// handleStats handles GET /stats requests.
+// Reports this node's Raft progress as JSON: commit_index and applied_index
+// say what has been acknowledged, while durable_index is the last committed
+// entry held in stable storage (the fsynced log or a snapshot) and so
+// survives a restart of the whole cluster. "namespaces" holds per-namespace usage.
+// "raft" is the raw raft.Stats() map, alongside the node's ID, Raft
+// address and the current leader. Without Raft, "mode" is "single-node".
+// Never forwarded.
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats := map[string]interface{}{
+		"node_id":   s.NodeID,
+		"raft_addr": s.RaftAddr,
+	}
+	if s.Raft == nil {
+		stats["mode"] = "single-node"
+	} else {
+		raftStats := s.Raft.Stats()
+		leaderAddr, leaderID := s.Raft.LeaderWithID()
+		stats["mode"] = "raft"
+		stats["raft"] = raftStats
+		stats["leader"] = string(leaderAddr)
+		stats["leader_id"] = string(leaderID)
+		idx := raftIndexesOf(s.Raft, raftStats)
+		stats["commit_index"] = idx.Commit
+		stats["last_snapshot_index"] = idx.Snapshot
+		stats["applied_index"] = idx.Applied
+		stats["durable_index"] = idx.Durable
+	}
+	if us, ok := s.Store.(kv.UsageStore); ok {
+		stats["namespaces"] = us.NamespaceUsage()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// handleRole handles GET /role requests.
+// Returns this node's role as plain text, without forwarding, for cheap
+// high-frequency polling by load balancers and smart clients.
+func (s *Server) handleRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(nodeRole(s.Raft)))
+}
+
+// handleHealthz handles GET /healthz, the liveness probe. It answers 200
+// whenever the process can serve HTTP.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte("ok"))
+}
+
+// handleReadyz handles GET /readyz, the readiness probe. A node is ready
+// when it runs without Raft or knows the current leader; otherwise it
+// answers 503. Always served locally, never forwarded.
+// Returns: {"ready": true, "state": "follower", "leader": "10.0.0.1:12000"}
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := struct {
+		Ready  bool   `json:"ready"`
+		State  string `json:"state"`
+		Leader string `json:"leader,omitempty"`
+	}{State: nodeRole(s.Raft)}
+	if s.Raft == nil {
+		resp.Ready = true
+	} else {
+		leaderAddr, _ := s.Raft.LeaderWithID()
+		resp.Leader = string(leaderAddr)
+		resp.Ready = resp.Leader != ""
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !resp.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleConfig handles GET /config, listing the Raft cluster members as
+// this node sees them. Served locally, never forwarded.
+// Returns: {"servers": [{"id": "node1", "address": "10.0.0.1:12000", "suffrage": "Voter"}]}
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.Raft == nil {
+		http.Error(w, "Raft is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	f := s.Raft.GetConfiguration()
+	if err := f.Error(); err != nil {
+		http.Error(w, "Failed to read Raft configuration: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	type server struct {
+		ID       string `json:"id"`
+		Address  string `json:"address"`
+		Suffrage string `json:"suffrage"`
+	}
+	servers := []server{}
+	for _, srv := range f.Configuration().Servers {
+		servers = append(servers, server{
+			ID:       string(srv.ID),
+			Address:  string(srv.Address),
+			Suffrage: srv.Suffrage.String(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"servers": servers})
+}
+
+// nodeRole maps the Raft state to "leader", "follower", "candidate" or
+// "shutdown". A node without Raft is always its own leader.
+func nodeRole(r *raft.Raft) string {
+	if r == nil {
+		return "leader"
+	}
+	return strings.ToLower(r.State().String())
+}
+
+// relayedHeaders are the leader's response headers passed back to a client
+// whose request was forwarded.
+var relayedHeaders = []string{"Content-Type", IndexHeader, ExistedHeader, ReplicasHeader, StaleHeader}
+
+// relayResponse copies a forwarded response's relayedHeaders, status and
+// body back to the original client.
+func relayResponse(w http.ResponseWriter, resp *http.Response) {
+	for _, h := range relayedHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			w.Header().Set(h, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// decodeStrict decodes a JSON request body, rejecting unknown fields so a
+// typo like {"keys": "foo"} fails with the offending field named instead
+// of silently decoding to an empty key.
+func decodeStrict(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			return err
+		}
+		return errors.New(strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return nil
+}
+
+// leaseExpired reports whether this node is the leader but can no longer
+// confirm contact with a quorum, so its local reads may be stale.
+func (s *Server) leaseExpired() bool {
+	return s.Lease != nil && s.Raft != nil && s.Raft.State() == raft.Leader && !s.Lease.Valid()
+}
+
+// forwardLoopDetected rejects a request with 508 once it has been forwarded
+// more than MaxForwardHops times, which only happens when leader discovery
+// routes nodes back at each other.
+func (s *Server) forwardLoopDetected(w http.ResponseWriter, r *http.Request) bool {
+	hops, _ := strconv.Atoi(r.Header.Get(ForwardCountHeader))
+	if hops < hopLimit(s.MaxForwardHops) {
+		return false
+	}
+	http.Error(w, "Loop detected: request forwarded too many times", http.StatusLoopDetected)
+	return true
+}
+
+// forward sends a request to the leader, carrying over the content type,
+// any Authorization header and an incremented forward count from the
+// incoming request.
+func (s *Server) forward(r *http.Request, method, targetURL string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, targetURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		req.Header.Set("Content-Type", ct)
+	} else if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	if ns := r.Header.Get(NamespaceHeader); ns != "" {
+		req.Header.Set(NamespaceHeader, ns)
+	}
+	hops, _ := strconv.Atoi(r.Header.Get(ForwardCountHeader))
+	req.Header.Set(ForwardCountHeader, strconv.Itoa(hops+1))
+	client := s.ForwardClient
+	if client == nil {
+		client = defaultForwardClient
+	}
+	return client.Do(req)
+}
+
+// forwardFailed reports a request the leader did not answer: 504 if it
+// ran out of time, 502 otherwise.
+func forwardFailed(w http.ResponseWriter, err error) {
+	slog.Warn("Forwarding to leader failed", "error", err)
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		http.Error(w, "Leader did not respond in time: "+err.Error(), http.StatusGatewayTimeout)
+		return
+	}
+	http.Error(w, "Failed to forward to leader: "+err.Error(), http.StatusBadGateway)
+}
+
+// writeFailed answers a failed store call with the status errorStatus
+// maps err to, as writeError does for gRPC. Known errors are answered
+// with msg and their text; anything else gets 500 with msg alone,
+// keeping the underlying error out of the response. Failures on the
+// node's side are logged.
+func writeFailed(w http.ResponseWriter, msg, key string, err error) {
+	status, _ := errorStatus(err)
+	if serverFault(status) {
+		slog.Error(msg, "key", key, "status", status, "error", err)
+	}
+	if status == http.StatusInternalServerError {
+		http.Error(w, msg, status)
+		return
+	}
+	http.Error(w, msg+": "+err.Error(), status)
+}
+
+// clearDeadlines lifts the server's read and write timeouts for a
+// long-running request, such as a watch or a dump. Writers without
+// deadline support are left as they are.
+func clearDeadlines(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+}
+
+// hopLimit returns the configured hop limit or the default.
+func hopLimit(max int) int {
+	if max <= 0 {
+		return defaultMaxForwardHops
+	}
+	return max
+}
+
+// leaderTarget returns the leader's HTTP address to forward a request to.
+// When none is known, or mandi names this node itself, it answers 503 and
+// returns "": a leader whose lease expired keeps registering itself with
+// mandi, so forwarding its reads would only bounce them back here until
+// the hop limit.
+func (s *Server) leaderTarget(w http.ResponseWriter) string {
+	addr := s.getLeaderHTTPAddr()
+	switch {
+	case addr == "":
+		http.Error(w, "Not leader and no leader known", http.StatusServiceUnavailable)
+	case addr == s.HTTPPort && s.Raft != nil && s.Raft.State() == raft.Leader:
+		http.Error(w, "Leader lease expired; refusing possibly stale read", http.StatusServiceUnavailable)
+	case addr == s.HTTPPort:
+		http.Error(w, "Not leader and mandi names this node as leader", http.StatusServiceUnavailable)
+	default:
+		return addr
+	}
+	return ""
+}
+
+// getLeaderHTTPAddr queries mandi to get the leader's HTTP address,
+// falling back to the statically configured leader if mandi has none.
+func (s *Server) getLeaderHTTPAddr() string {
+	if addr := s.queryLeaderHTTPAddr(); addr != "" {
+		return addr
+	}
+	return s.FallbackLeaderAddr
+}
+
+// getMandiLeader fetches mandi's leader record, authenticating with token
+// if one is configured.
+func getMandiLeader(mandiAddr, token string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, mandiAddr+"/leader", nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return mandiClient.Do(req)
+}
+
+func (s *Server) queryLeaderHTTPAddr() string {
 	if s.MandiAddr == "" {
 		return ""
 	}
 
-	resp, err := http.Get(s.MandiAddr + "/leader")
+	resp, err := getMandiLeader(s.MandiAddr, s.MandiToken)
 	if err != nil {
 		return ""
 	}
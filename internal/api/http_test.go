@@ -0,0 +1,236 @@
+package api
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/heysubinoy/pyazdb/internal/store"
+)
+
+func TestForwardLoopDetected(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxHops  int
+		header   string
+		rejected bool
+	}{
+		{"first hop", 0, "", false},
+		{"under default limit", 0, "2", false},
+		{"at default limit", 0, strconv.Itoa(defaultMaxForwardHops), true},
+		{"past default limit", 0, "10", true},
+		{"under configured limit", 5, "4", false},
+		{"at configured limit", 1, "1", true},
+		{"garbage counts as zero", 1, "lots", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{MaxForwardHops: tt.maxHops}
+			req := httptest.NewRequest(http.MethodPut, "/set", nil)
+			if tt.header != "" {
+				req.Header.Set(ForwardCountHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			if got := s.forwardLoopDetected(rec, req); got != tt.rejected {
+				t.Fatalf("forwardLoopDetected = %v, want %v", got, tt.rejected)
+			}
+			if tt.rejected && rec.Code != http.StatusLoopDetected {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusLoopDetected)
+			}
+		})
+	}
+}
+
+func TestForwardIncrementsHopCount(t *testing.T) {
+	var got string
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(ForwardCountHeader)
+	}))
+	defer leader.Close()
+
+	s := &Server{}
+	for _, tt := range []struct{ in, want string }{{"", "1"}, {"2", "3"}} {
+		req := httptest.NewRequest(http.MethodPut, "/set", nil)
+		if tt.in != "" {
+			req.Header.Set(ForwardCountHeader, tt.in)
+		}
+		resp, err := s.forward(req, http.MethodPut, leader.URL+"/set", nil)
+		if err != nil {
+			t.Fatalf("forward: %v", err)
+		}
+		resp.Body.Close()
+		if got != tt.want {
+			t.Errorf("forwarded count with %q = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteHandlersRejectUnknownFields(t *testing.T) {
+	s := NewServer(store.NewMemStore(), nil, "", "")
+	s.AccessLog = AccessLogOff
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+
+	tests := []struct {
+		name  string
+		path  string
+		body  string
+		want  int
+		field string // named in the error, if rejected
+	}{
+		{"set", "/set", `{"key":"k","value":"v"}`, http.StatusNoContent, ""},
+		{"set typo'd key", "/set", `{"keys":"k","value":"v"}`, http.StatusBadRequest, `"keys"`},
+		{"set extra field", "/set", `{"key":"k","value":"v","ttl":5}`, http.StatusBadRequest, `"ttl"`},
+		{"delete", "/delete", `{"key":"k"}`, http.StatusNoContent, ""},
+		{"delete typo'd key", "/delete", `{"Kye":"k"}`, http.StatusBadRequest, `"Kye"`},
+		{"delete extra field", "/delete", `{"key":"k","value":"v"}`, http.StatusBadRequest, `"value"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.want {
+				t.Fatalf("POST %s = %d %q, want %d", tt.path, rec.Code, rec.Body.String(), tt.want)
+			}
+			if tt.field != "" && !strings.Contains(rec.Body.String(), tt.field) {
+				t.Errorf("error %q does not name %s", rec.Body.String(), tt.field)
+			}
+		})
+	}
+}
+
+func TestForwardedGetReturnsWholeValue(t *testing.T) {
+	leaderStore := store.NewMemStore()
+	leader := NewServer(leaderStore, nil, "", "")
+	leader.AccessLog = AccessLogOff
+	mux := http.NewServeMux()
+	leader.RegisterRoutes(mux)
+	leaderHTTP := httptest.NewServer(mux)
+	defer leaderHTTP.Close()
+
+	follower := NewServer(store.NewMemStore(), followerRaft(t), "", "")
+	follower.FallbackLeaderAddr = strings.TrimPrefix(leaderHTTP.URL, "http://")
+
+	tests := []struct {
+		name  string
+		size  int
+		query string
+	}{
+		{"just under 4KB", 4095, ""},
+		{"just over 4KB", 4097, ""},
+		{"1MB", 1 << 20, ""},
+		{"1MB base64", 1 << 20, "&encoding=base64"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := "blob/" + strconv.Itoa(i)
+			value := strings.Repeat("0123456789abcdef", tt.size/16+1)[:tt.size]
+			leaderStore.Set(key, value)
+
+			rec := httptest.NewRecorder()
+			follower.handleGet(rec, httptest.NewRequest(http.MethodGet, "/get?key="+url.QueryEscape(key)+tt.query, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("forwarded GET = %d %q", rec.Code, rec.Body.String())
+			}
+			got, _ := io.ReadAll(rec.Body)
+			if tt.query != "" {
+				if got, _ = base64.StdEncoding.DecodeString(string(got)); got == nil {
+					t.Fatal("body is not base64")
+				}
+			}
+			if string(got) != value {
+				t.Errorf("forwarded GET returned %d bytes, want all %d", len(got), len(value))
+			}
+		})
+	}
+}
+
+func TestForwardReadToSelfIsRefused(t *testing.T) {
+	const self = "127.0.0.1:8080"
+	leader := leaderRaft(t)
+	tests := []struct {
+		name string
+		srv  *Server
+		path string
+		msg  string
+	}{
+		{"get on leader with expired lease", &Server{Raft: leader, Lease: expiredLease(leader), StaleReads: StaleReadsForward}, "/get?key=k", "lease expired"},
+		{"scan on leader with expired lease", &Server{Raft: leader, Lease: expiredLease(leader), StaleReads: StaleReadsForward}, "/scan?prefix=k", "lease expired"},
+		{"get on follower named by mandi", &Server{Raft: followerRaft(t)}, "/get?key=k", "names this node"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.srv.Store = store.NewMemStore()
+			tt.srv.HTTPPort = self
+			tt.srv.FallbackLeaderAddr = self
+			tt.srv.AccessLog = AccessLogOff
+			mux := http.NewServeMux()
+			tt.srv.RegisterRoutes(mux)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), tt.msg) {
+				t.Errorf("GET %s = %d %q, want 503 mentioning %q", tt.path, rec.Code, rec.Body.String(), tt.msg)
+			}
+		})
+	}
+}
+
+func TestForwardedWritesRelayLeaderResponse(t *testing.T) {
+	type answer struct {
+		status  int
+		headers map[string]string
+		body    string
+	}
+	answers := map[string]answer{
+		"/set":    {http.StatusNoContent, map[string]string{IndexHeader: "7", ReplicasHeader: "2"}, ""},
+		"/delete": {http.StatusGatewayTimeout, map[string]string{IndexHeader: "8", ExistedHeader: "true"}, "Failed to delete key: write not committed in time\n"},
+		"/cas":    {http.StatusConflict, nil, "Failed to swap: value is not an integer\n"},
+	}
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a := answers[r.URL.Path]
+		for k, v := range a.headers {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(a.status)
+		io.WriteString(w, a.body)
+	}))
+	defer leader.Close()
+
+	follower := NewServer(store.NewMemStore(), followerRaft(t), "", "")
+	follower.FallbackLeaderAddr = strings.TrimPrefix(leader.URL, "http://")
+	follower.AccessLog = AccessLogOff
+	mux := http.NewServeMux()
+	follower.RegisterRoutes(mux)
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/set", `{"key":"k","value":"v"}`},
+		{"/delete", `{"key":"k"}`},
+		{"/cas", `{"key":"k","old":"a","new":"b"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			want := answers[tt.path]
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != want.status {
+				t.Errorf("status = %d, want the leader's %d", rec.Code, want.status)
+			}
+			if rec.Body.String() != want.body {
+				t.Errorf("body = %q, want the leader's %q", rec.Body.String(), want.body)
+			}
+			for k, v := range want.headers {
+				if got := rec.Header().Get(k); got != v {
+					t.Errorf("%s = %q, want the leader's %q", k, got, v)
+				}
+			}
+		})
+	}
+}
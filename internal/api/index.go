@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/heysubinoy/pyazdb/pkg/kv"
+)
+
+// IndexHeader carries the Raft log index a write was committed at.
+const IndexHeader = "X-Pyaz-Index"
+
+// ExistedHeader reports on /delete whether the key existed beforehand.
+const ExistedHeader = "X-Pyaz-Existed"
+
+// ReplicasHeader reports on a /set with min_replicas how many nodes,
+// leader included, were confirmed to hold the write.
+const ReplicasHeader = "X-Pyaz-Replicas"
+
+// setIndexed writes through kv.ContextStore or kv.IndexedStore when
+// available so the response can carry the commit index, and so a store
+// that blocks on consensus stops waiting once ctx is done. Other stores
+// report index 0.
+func setIndexed(ctx context.Context, st kv.Store, key, value string) (uint64, error) {
+	if cs, ok := st.(kv.ContextStore); ok {
+		index, err := cs.SetCtx(ctx, key, value)
+		if !errors.Is(err, kv.ErrNotSupported) {
+			return index, err
+		}
+	}
+	if is, ok := st.(kv.IndexedStore); ok {
+		index, err := is.SetIndexed(key, value)
+		if !errors.Is(err, kv.ErrNotSupported) {
+			return index, err
+		}
+	}
+	return 0, st.Set(key, value)
+}
+
+// deleteIndexed is the delete counterpart of setIndexed.
+func deleteIndexed(st kv.Store, key string) (uint64, error) {
+	if is, ok := st.(kv.IndexedStore); ok {
+		index, err := is.DeleteIndexed(key)
+		if !errors.Is(err, kv.ErrNotSupported) {
+			return index, err
+		}
+	}
+	return 0, st.Delete(key)
+}
+
+// deleteReported deletes through kv.ContextStore or kv.DeleteReportStore
+// when available so the response can say whether the key existed. Other
+// stores report false.
+func deleteReported(ctx context.Context, st kv.Store, key string) (bool, uint64, error) {
+	if cs, ok := st.(kv.ContextStore); ok {
+		existed, index, err := cs.DeleteCtx(ctx, key)
+		if !errors.Is(err, kv.ErrNotSupported) {
+			return existed, index, err
+		}
+	}
+	if ds, ok := st.(kv.DeleteReportStore); ok {
+		existed, index, err := ds.DeleteReport(key)
+		if !errors.Is(err, kv.ErrNotSupported) {
+			return existed, index, err
+		}
+	}
+	index, err := deleteIndexed(st, key)
+	return false, index, err
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// Stale read policies applied when the leader can no longer confirm it
+// reaches a quorum of voters.
+const (
+	StaleReadsAllow   = "allow"   // serve the local value as before
+	StaleReadsMark    = "mark"    // serve it, flagged with StaleHeader / stale metadata
+	StaleReadsForward = "forward" // forward to whichever leader mandi advertises
+	StaleReadsError   = "error"   // refuse with 503 / Unavailable
+)
+
+// StaleHeader is set on reads served while the leader lease is in doubt.
+const StaleHeader = "X-Pyaz-Stale"
+
+// consistentReadTimeout bounds the barrier behind a consistent read.
+const consistentReadTimeout = 5 * time.Second
+
+// consistentRead prepares the leader for a linearizable read. The barrier
+// commits through a quorum, so it fails if this node is no longer leader,
+// and returns only once every earlier entry has been applied locally.
+// Without Raft there is nothing to wait for.
+func consistentRead(r *raft.Raft) error {
+	if r == nil {
+		return nil
+	}
+	return r.Barrier(consistentReadTimeout).Error()
+}
+
+// LeaseTracker watches heartbeat observations on the leader to tell whether
+// it can still reach a quorum. Reads are served from the leader's local
+// store, so a leader partitioned into a minority would otherwise keep
+// answering with data a new majority leader may already have overwritten.
+type LeaseTracker struct {
+	raft    *raft.Raft
+	mu      sync.Mutex
+	failing map[raft.ServerID]bool
+}
+
+// NewLeaseTracker registers a Raft observer and starts tracking peers whose
+// heartbeats are failing.
+func NewLeaseTracker(r *raft.Raft) *LeaseTracker {
+	t := &LeaseTracker{
+		raft:    r,
+		failing: make(map[raft.ServerID]bool),
+	}
+
+	ch := make(chan raft.Observation, 16)
+	r.RegisterObserver(raft.NewObserver(ch, false, func(o *raft.Observation) bool {
+		switch o.Data.(type) {
+		case raft.FailedHeartbeatObservation, raft.ResumedHeartbeatObservation, raft.RaftState:
+			return true
+		}
+		return false
+	}))
+	go t.watch(ch)
+
+	return t
+}
+
+func (t *LeaseTracker) watch(ch chan raft.Observation) {
+	for o := range ch {
+		t.mu.Lock()
+		switch d := o.Data.(type) {
+		case raft.FailedHeartbeatObservation:
+			t.failing[d.PeerID] = true
+		case raft.ResumedHeartbeatObservation:
+			delete(t.failing, d.PeerID)
+		case raft.RaftState:
+			// Heartbeat state only means something for the current term's leader
+			t.failing = make(map[raft.ServerID]bool)
+		}
+		t.mu.Unlock()
+	}
+}
+
+// Valid reports whether the leader is still in contact with a quorum of voters.
+func (t *LeaseTracker) Valid() bool {
+	t.mu.Lock()
+	failing := make(map[raft.ServerID]bool, len(t.failing))
+	for id := range t.failing {
+		failing[id] = true
+	}
+	t.mu.Unlock()
+
+	if len(failing) == 0 {
+		return true
+	}
+
+	f := t.raft.GetConfiguration()
+	if err := f.Error(); err != nil {
+		return false
+	}
+	voters, reachable := 0, 0
+	for _, srv := range f.Configuration().Servers {
+		if srv.Suffrage != raft.Voter {
+			continue
+		}
+		voters++
+		if !failing[srv.ID] {
+			reachable++
+		}
+	}
+	return reachable >= voters/2+1
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/heysubinoy/pyazdb/internal/store"
+	"github.com/heysubinoy/pyazdb/pkg/kv"
+)
+
+// Size limits applied to keys and values when none are configured.
+const (
+	DefaultMaxValueBytes = 1 << 20 // 1 MiB
+	DefaultMaxKeyBytes   = 4096
+)
+
+// limitOr returns limit, or def when limit is unset.
+func limitOr(limit, def int) int {
+	if limit <= 0 {
+		return def
+	}
+	return limit
+}
+
+// checkSize rejects a key or value over its limit with kv.ErrTooLarge,
+// before anything is stored or submitted to Raft.
+func checkSize(key, value string, maxKey, maxValue int) error {
+	maxKey = limitOr(maxKey, DefaultMaxKeyBytes)
+	maxValue = limitOr(maxValue, DefaultMaxValueBytes)
+	if len(key) > maxKey {
+		return fmt.Errorf("key is %d bytes, limit is %d: %w", len(key), maxKey, kv.ErrTooLarge)
+	}
+	if len(value) > maxValue {
+		return fmt.Errorf("value is %d bytes, limit is %d: %w", len(value), maxValue, kv.ErrTooLarge)
+	}
+	return nil
+}
+
+// maxSetBody bounds a /set request body: the key and value at their
+// limits under worst-case JSON escaping (6 bytes per byte, as in \u0000),
+// plus room for the other fields.
+func maxSetBody(maxKey, maxValue int) int64 {
+	return 6*int64(limitOr(maxKey, DefaultMaxKeyBytes)+limitOr(maxValue, DefaultMaxValueBytes)) + 4096
+}
+
+// maxBatchBody bounds a /batch request body. A batch is proposed as one
+// Raft entry, so it can't usefully exceed maxEntry bytes of keys and
+// values; the factor allows for the same worst-case JSON escaping as
+// maxSetBody.
+func maxBatchBody(maxEntry int) int64 {
+	return 6*int64(limitOr(maxEntry, store.DefaultMaxEntryBytes)) + 4096
+}
+
+// decodeLimited decodes r's JSON body into v, reading at most limit bytes
+// so an oversized body is refused while reading it, not after it has been
+// buffered in full. On failure it answers 413 or 400 and returns false.
+func decodeLimited(w http.ResponseWriter, r *http.Request, v interface{}, limit int64) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	if err := decodeStrict(r, v); err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return false
+		}
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
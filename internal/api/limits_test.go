@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/heysubinoy/pyazdb/internal/store"
+)
+
+func TestWriteHandlersEnforceSizeLimits(t *testing.T) {
+	s := NewServer(store.NewMemStore(), nil, "", "")
+	s.MaxKeyBytes = 8
+	s.MaxValueBytes = 16
+	s.AccessLog = AccessLogOff
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+
+	long := strings.Repeat("x", 32)
+	huge := strings.Repeat("x", 1<<20)
+	tests := []struct {
+		name string
+		path string
+		body string
+		want int
+	}{
+		{"set within limits", "/set", `{"key":"k","value":"v"}`, http.StatusNoContent},
+		{"set long value", "/set", `{"key":"k","value":"` + long + `"}`, http.StatusRequestEntityTooLarge},
+		{"lock long value", "/lock", `{"key":"l","value":"` + long + `","ttl_seconds":5}`, http.StatusRequestEntityTooLarge},
+		{"cas long new", "/cas", `{"key":"k","old":"v","new":"` + long + `"}`, http.StatusRequestEntityTooLarge},
+		{"incr long key", "/incr", `{"key":"` + long + `","delta":1}`, http.StatusRequestEntityTooLarge},
+		{"batch long value", "/batch", `{"ops":[{"op":"set","key":"k","value":"` + long + `"}]}`, http.StatusRequestEntityTooLarge},
+		{"body over limit", "/cas", `{"key":"k","old":"` + huge + `"}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.want {
+				t.Errorf("POST %s = %d %q, want %d", tt.path, rec.Code, rec.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRestoreRejectsOversizedRecord(t *testing.T) {
+	h := RestoreHandler(store.NewMemStore(), nil, 8, 16)
+	body := `{"key":"a","value":"ok"}` + "\n" + `{"key":"b","value":"` + strings.Repeat("x", 1<<20) + `"}` + "\n"
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/admin/restore", strings.NewReader(body)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("restore = %d %q, want 413", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "record 2") {
+		t.Errorf("restore error %q does not name record 2", rec.Body.String())
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// membershipTimeout bounds how long a membership change may wait to be
+// enqueued on the leader.
+const membershipTimeout = 10 * time.Second
+
+// RegisterMembershipRoutes registers POST /join and POST /remove, guarded
+// by the admin token like the /admin endpoints. Followers forward both to
+// the leader, Authorization header included.
+func (s *Server) RegisterMembershipRoutes(mux *http.ServeMux, token string) {
+	mux.HandleFunc("/join", s.logged(RequireToken(token, s.handleJoin)))
+	mux.HandleFunc("/remove", s.logged(RequireToken(token, s.handleRemove)))
+}
+
+// handleJoin handles POST /join requests with JSON body.
+// Expects: {"id": "node3", "addr": "10.0.0.3:12000"}
+// Adds the node to the cluster as a voter.
+func (s *Server) handleJoin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.Raft == nil {
+		http.Error(w, "Membership changes need Raft", http.StatusNotImplemented)
+		return
+	}
+	if s.Raft.State() != raft.Leader {
+		s.forwardWrite(w, r, "/join")
+		return
+	}
+
+	var req struct {
+		ID   string `json:"id"`
+		Addr string `json:"addr"`
+	}
+	if err := decodeStrict(r, &req); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.ID == "" || req.Addr == "" {
+		http.Error(w, "id and addr are required", http.StatusBadRequest)
+		return
+	}
+
+	f := s.Raft.AddVoter(raft.ServerID(req.ID), raft.ServerAddress(req.Addr), 0, membershipTimeout)
+	if err := f.Error(); err != nil {
+		http.Error(w, "Failed to add voter: "+err.Error(), membershipStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRemove handles POST /remove requests with JSON body.
+// Expects: {"id": "node3"}
+// Removes the node from the cluster.
+func (s *Server) handleRemove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.Raft == nil {
+		http.Error(w, "Membership changes need Raft", http.StatusNotImplemented)
+		return
+	}
+	if s.Raft.State() != raft.Leader {
+		s.forwardWrite(w, r, "/remove")
+		return
+	}
+
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := decodeStrict(r, &req); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.ID == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	f := s.Raft.RemoveServer(raft.ServerID(req.ID), 0, membershipTimeout)
+	if err := f.Error(); err != nil {
+		http.Error(w, "Failed to remove server: "+err.Error(), membershipStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// membershipStatus maps a failed membership change to an HTTP status.
+func membershipStatus(err error) int {
+	switch err {
+	case raft.ErrNotLeader, raft.ErrLeadershipLost, raft.ErrEnqueueTimeout:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
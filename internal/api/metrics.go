@@ -2,8 +2,11 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 
+	"github.com/hashicorp/raft"
 	"github.com/heysubinoy/pyazdb/internal/store"
 )
 
@@ -16,22 +19,174 @@ func MetricsHandler(instrumentedStore *store.InstrumentedStore) http.HandlerFunc
 			return
 		}
 
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(metricsJSON(instrumentedStore, instrumentedStore.GetMetrics()))
+	}
+}
+
+// MetricsResetHandler clears the store metrics and returns the snapshot
+// taken just before, in the shape MetricsHandler uses, so the interval
+// that ended is not lost. Swapping the counters out means operations that
+// race with the reset land in exactly one of the two intervals. The key
+// and byte gauges are not counters and are reported as they stand.
+func MetricsResetHandler(instrumentedStore *store.InstrumentedStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(metricsJSON(instrumentedStore, instrumentedStore.GetMetricsAndReset()))
+	}
+}
+
+// metricsJSON lays out a metrics snapshot for the JSON metrics endpoints,
+// with the store's key and byte gauges if it reports them and the read
+// cache counts if one is enabled.
+func metricsJSON(instrumentedStore *store.InstrumentedStore, metrics store.MetricsSnapshot) map[string]interface{} {
+	out := map[string]interface{}{
+		"operations": map[string]uint64{
+			"get":    metrics.GetCount,
+			"set":    metrics.SetCount,
+			"delete": metrics.DeleteCount,
+		},
+		"avg_latency": map[string]string{
+			"get":    metrics.GetAvgLatency.String(),
+			"set":    metrics.SetAvgLatency.String(),
+			"delete": metrics.DeleteAvgLatency.String(),
+		},
+		"latency_percentiles": map[string]map[string]string{
+			"get":    {"p50": metrics.GetP50.String(), "p95": metrics.GetP95.String(), "p99": metrics.GetP99.String()},
+			"set":    {"p50": metrics.SetP50.String(), "p95": metrics.SetP95.String(), "p99": metrics.SetP99.String()},
+			"delete": {"p50": metrics.DeleteP50.String(), "p95": metrics.DeleteP95.String(), "p99": metrics.DeleteP99.String()},
+		},
+	}
+	if keys, bytes, ok := instrumentedStore.Size(); ok {
+		out["keys"] = keys
+		out["approx_bytes"] = bytes
+	}
+	if rc, ok := instrumentedStore.ReadCacheStats(); ok {
+		out["read_cache"] = map[string]interface{}{
+			"hits":      rc.Hits,
+			"misses":    rc.Misses,
+			"hit_ratio": rc.HitRatio(),
+			"entries":   rc.Entries,
+		}
+	}
+	return out
+}
+
+// MetricsHandlerPrometheus returns current store metrics in the Prometheus
+// text exposition format, from the same snapshot MetricsHandler uses.
+// Averages are cumulative over the counters' lifetime, like the JSON shape.
+// The key and byte gauges are left out for stores that don't report them,
+// the read cache series when no read cache is enabled, and the Raft index
+// gauges, as in GET /stats, when r is nil.
+func MetricsHandlerPrometheus(instrumentedStore *store.InstrumentedStore, r *raft.Raft) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
 		metrics := instrumentedStore.GetMetrics()
+		ops := []struct {
+			name          string
+			count         uint64
+			avg           float64
+			p50, p95, p99 float64
+		}{
+			{"get", metrics.GetCount, metrics.GetAvgLatency.Seconds(),
+				metrics.GetP50.Seconds(), metrics.GetP95.Seconds(), metrics.GetP99.Seconds()},
+			{"set", metrics.SetCount, metrics.SetAvgLatency.Seconds(),
+				metrics.SetP50.Seconds(), metrics.SetP95.Seconds(), metrics.SetP99.Seconds()},
+			{"delete", metrics.DeleteCount, metrics.DeleteAvgLatency.Seconds(),
+				metrics.DeleteP50.Seconds(), metrics.DeleteP95.Seconds(), metrics.DeleteP99.Seconds()},
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		fmt.Fprintln(w, "# HELP pyazdb_operations_total Store operations handled by this node.")
+		fmt.Fprintln(w, "# TYPE pyazdb_operations_total counter")
+		for _, op := range ops {
+			fmt.Fprintf(w, "pyazdb_operations_total{op=%q} %d\n", op.name, op.count)
+		}
+		fmt.Fprintln(w, "# HELP pyazdb_operation_avg_latency_seconds Average store operation latency.")
+		fmt.Fprintln(w, "# TYPE pyazdb_operation_avg_latency_seconds gauge")
+		for _, op := range ops {
+			fmt.Fprintf(w, "pyazdb_operation_avg_latency_seconds{op=%q} %g\n", op.name, op.avg)
+		}
+		fmt.Fprintln(w, "# HELP pyazdb_operation_latency_seconds Store operation latency percentiles.")
+		fmt.Fprintln(w, "# TYPE pyazdb_operation_latency_seconds gauge")
+		for _, op := range ops {
+			fmt.Fprintf(w, "pyazdb_operation_latency_seconds{op=%q,quantile=\"0.5\"} %g\n", op.name, op.p50)
+			fmt.Fprintf(w, "pyazdb_operation_latency_seconds{op=%q,quantile=\"0.95\"} %g\n", op.name, op.p95)
+			fmt.Fprintf(w, "pyazdb_operation_latency_seconds{op=%q,quantile=\"0.99\"} %g\n", op.name, op.p99)
+		}
+		if keys, bytes, ok := instrumentedStore.Size(); ok {
+			fmt.Fprintln(w, "# HELP pyazdb_keys Keys held by this node, including expired keys not yet swept.")
+			fmt.Fprintln(w, "# TYPE pyazdb_keys gauge")
+			fmt.Fprintf(w, "pyazdb_keys %d\n", keys)
+			fmt.Fprintln(w, "# HELP pyazdb_approx_bytes Summed length of the keys and values held by this node.")
+			fmt.Fprintln(w, "# TYPE pyazdb_approx_bytes gauge")
+			fmt.Fprintf(w, "pyazdb_approx_bytes %d\n", bytes)
+		}
+		if rc, ok := instrumentedStore.ReadCacheStats(); ok {
+			fmt.Fprintln(w, "# HELP pyazdb_read_cache_hits_total Gets answered from the read cache.")
+			fmt.Fprintln(w, "# TYPE pyazdb_read_cache_hits_total counter")
+			fmt.Fprintf(w, "pyazdb_read_cache_hits_total %d\n", rc.Hits)
+			fmt.Fprintln(w, "# HELP pyazdb_read_cache_misses_total Gets the read cache passed to the store.")
+			fmt.Fprintln(w, "# TYPE pyazdb_read_cache_misses_total counter")
+			fmt.Fprintf(w, "pyazdb_read_cache_misses_total %d\n", rc.Misses)
+			fmt.Fprintln(w, "# HELP pyazdb_read_cache_entries Keys held by the read cache.")
+			fmt.Fprintln(w, "# TYPE pyazdb_read_cache_entries gauge")
+			fmt.Fprintf(w, "pyazdb_read_cache_entries %d\n", rc.Entries)
+		}
+		if r != nil {
+			idx := raftIndexesOf(r, r.Stats())
+			fmt.Fprintln(w, "# HELP pyazdb_commit_index Last Raft log index known to be committed.")
+			fmt.Fprintln(w, "# TYPE pyazdb_commit_index gauge")
+			fmt.Fprintf(w, "pyazdb_commit_index %d\n", idx.Commit)
+			fmt.Fprintln(w, "# HELP pyazdb_applied_index Last Raft log index applied to this node's store.")
+			fmt.Fprintln(w, "# TYPE pyazdb_applied_index gauge")
+			fmt.Fprintf(w, "pyazdb_applied_index %d\n", idx.Applied)
+			fmt.Fprintln(w, "# HELP pyazdb_durable_index Last committed Raft log index in this node's stable storage.")
+			fmt.Fprintln(w, "# TYPE pyazdb_durable_index gauge")
+			fmt.Fprintf(w, "pyazdb_durable_index %d\n", idx.Durable)
+		}
+	}
+}
 
-		response := map[string]interface{}{
-			"operations": map[string]uint64{
-				"get":    metrics.GetCount,
-				"set":    metrics.SetCount,
-				"delete": metrics.DeleteCount,
-			},
-			"avg_latency": map[string]string{
-				"get":    metrics.GetAvgLatency.String(),
-				"set":    metrics.SetAvgLatency.String(),
-				"delete": metrics.DeleteAvgLatency.String(),
-			},
+// HotKeysHandler returns the n most read keys on this node (?n=, default
+// 10) with their estimated read counts. It answers 501 unless hot key
+// tracking was enabled on the InstrumentedStore.
+func HotKeysHandler(instrumentedStore *store.InstrumentedStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		n := 10
+		if v := r.URL.Query().Get("n"); v != "" {
+			parsed, err := strconv.Atoi(v)
+			if err != nil || parsed <= 0 {
+				http.Error(w, "n must be a positive integer", http.StatusBadRequest)
+				return
+			}
+			n = parsed
+		}
+
+		keys, ok := instrumentedStore.HotKeys(n)
+		if !ok {
+			http.Error(w, "Hot key tracking is not enabled (set HOT_KEYS_CAPACITY)", http.StatusNotImplemented)
+			return
+		}
+		if keys == nil {
+			keys = []store.HotKey{}
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
 	}
 }
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/heysubinoy/pyazdb/internal/store"
+	"github.com/heysubinoy/pyazdb/pkg/kv"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// NamespaceHeader selects the namespace (logical database) an HTTP request
+// works in. Its keys are stored as "<namespace>:<key>"; without the header
+// the request works in the global namespace on keys as given.
+const NamespaceHeader = "X-Pyaz-Namespace"
+
+// namespaceKey is the gRPC metadata counterpart of NamespaceHeader.
+const namespaceKey = "x-pyaz-namespace"
+
+// validateNamespace rejects namespaces that could overlap another one.
+func validateNamespace(ns string) error {
+	if strings.Contains(ns, kv.NamespaceSeparator) {
+		return fmt.Errorf("namespace must not contain %q", kv.NamespaceSeparator)
+	}
+	return nil
+}
+
+// inNamespace returns st confined to ns, or st itself for the global
+// namespace.
+func inNamespace(st kv.Store, ns string) kv.Store {
+	if ns == "" {
+		return st
+	}
+	return store.NewNamespacedStore(st, ns)
+}
+
+// namespaced wraps h so a request with an invalid NamespaceHeader is
+// rejected with 400 before h runs.
+func (s *Server) namespaced(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := validateNamespace(r.Header.Get(NamespaceHeader)); err != nil {
+			http.Error(w, "Invalid "+NamespaceHeader+": "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// store returns the store r works on: s.Store confined to the request's
+// namespace, if it names one.
+func (s *Server) store(r *http.Request) kv.Store {
+	return inNamespace(s.Store, r.Header.Get(NamespaceHeader))
+}
+
+// namespaceOf returns the namespace named in ctx's incoming metadata, or ""
+// for the global namespace.
+func namespaceOf(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if v := md.Get(namespaceKey); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// store returns the store a call works on: s.Store confined to the
+// namespace in its metadata, if it names one.
+func (s *GRPCServer) store(ctx context.Context) (kv.Store, error) {
+	ns := namespaceOf(ctx)
+	if err := validateNamespace(ns); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid %s: %v", namespaceKey, err)
+	}
+	return inNamespace(s.Store, ns), nil
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRateLimitClients bounds how many client IPs a RateLimiter tracks
+	// at once, so a flood of unique addresses cannot grow it unbounded.
+	maxRateLimitClients = 100_000
+	// rateLimitSweepEvery is how often idle buckets are dropped.
+	rateLimitSweepEvery = time.Minute
+)
+
+// RateLimiter limits requests per client IP with a token bucket: each
+// client may burst up to Burst requests and then Rate per second. A
+// client whose bucket has refilled holds no state worth keeping, so such
+// buckets are dropped periodically and the number tracked is capped.
+type RateLimiter struct {
+	rate  float64
+	burst float64
+
+	// TrustForwardedFor keys clients by the last address in
+	// X-Forwarded-For, as added by a proxy in front of the node, instead
+	// of the connection's remote address. Only enable it behind a proxy
+	// that sets the header, since clients can send it themselves.
+	TrustForwardedFor bool
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a limiter allowing rate requests per second per
+// client IP with bursts of up to burst (0 = rate, at least 1).
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	b := float64(burst)
+	if burst <= 0 {
+		b = math.Max(1, math.Ceil(rate))
+	}
+	return &RateLimiter{
+		rate:      rate,
+		burst:     b,
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Wrap returns h behind the limiter. Requests over a client's limit get
+// 429 with a Retry-After header. Every request is counted, including
+// those relayed by another node: ForwardCountHeader is set by clients as
+// easily as by peers, so it can't be trusted to skip the limit. Relayed
+// requests count against the relaying node's address.
+func (l *RateLimiter) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := l.allow(l.clientIP(r), time.Now()); !ok {
+			secs := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(max(secs, 1)))
+			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// clientIP returns the address r is limited by.
+func (l *RateLimiter) clientIP(r *http.Request) string {
+	if l.TrustForwardedFor {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			hops := strings.Split(xff, ",")
+			if ip := strings.TrimSpace(hops[len(hops)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// allow takes a token from ip's bucket, or reports how long until one is
+// available.
+func (l *RateLimiter) allow(ip string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= rateLimitSweepEvery {
+		l.sweepLocked(now)
+	}
+
+	b, ok := l.buckets[ip]
+	if !ok {
+		if len(l.buckets) >= maxRateLimitClients {
+			l.sweepLocked(now)
+			// Still full of active clients: forget one at random, which at
+			// worst hands it a fresh burst.
+			for k := range l.buckets {
+				if len(l.buckets) < maxRateLimitClients {
+					break
+				}
+				delete(l.buckets, k)
+			}
+		}
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[ip] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweepLocked drops buckets that have refilled completely, which behave
+// exactly like a new bucket. Callers must hold l.mu.
+func (l *RateLimiter) sweepLocked(now time.Time) {
+	for ip, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, ip)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,38 @@
+package export
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/heysubinoy/pyazdb/internal/store"
+)
+
+// Exporter pushes store metrics to an external backend.
+// Implementations are called from a single goroutine.
+type Exporter interface {
+	Export(m store.MetricsSnapshot) error
+}
+
+// New returns the exporter for the named backend sending to addr.
+func New(backend, addr string) (Exporter, error) {
+	switch backend {
+	case "statsd":
+		return NewStatsD(addr, "pyazdb")
+	default:
+		return nil, fmt.Errorf("unknown metrics exporter %q", backend)
+	}
+}
+
+// Run exports a fresh snapshot from s every interval. It never returns,
+// so callers should start it in its own goroutine.
+func Run(s *store.InstrumentedStore, exp Exporter, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := exp.Export(s.GetMetrics()); err != nil {
+			slog.Warn("Metrics export failed", "error", err)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package export
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/heysubinoy/pyazdb/internal/store"
+)
+
+// RunLogger logs a metrics snapshot from s every interval, for deployments
+// without a metrics backend. With reset set, counters are cleared after each
+// line so it reports per-interval rates; this also resets what GET /metrics
+// and MetricsStream see. It never returns, so callers should start it in its
+// own goroutine.
+func RunLogger(s *store.InstrumentedStore, interval time.Duration, reset bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		var m store.MetricsSnapshot
+		if reset {
+			m = s.GetMetricsAndReset()
+		} else {
+			m = s.GetMetrics()
+		}
+		slog.Info("metrics", "interval", interval, "cumulative", !reset,
+			"get", m.GetCount, "set", m.SetCount, "delete", m.DeleteCount,
+			"get_avg", m.GetAvgLatency, "set_avg", m.SetAvgLatency, "delete_avg", m.DeleteAvgLatency,
+			"get_p99", m.GetP99, "set_p99", m.SetP99, "delete_p99", m.DeleteP99)
+	}
+}
@@ -0,0 +1,56 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/heysubinoy/pyazdb/internal/store"
+)
+
+// StatsD sends metrics over UDP in the StatsD line protocol.
+// Operation counts are sent as counters (deltas since the last export)
+// and average latencies as gauges in milliseconds.
+type StatsD struct {
+	conn   net.Conn
+	prefix string
+	last   store.MetricsSnapshot
+}
+
+// NewStatsD creates an exporter sending to the StatsD server at addr.
+func NewStatsD(addr, prefix string) (*StatsD, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &StatsD{conn: conn, prefix: prefix}, nil
+}
+
+// Export sends one packet with all counters and gauges.
+func (s *StatsD) Export(m store.MetricsSnapshot) error {
+	var buf bytes.Buffer
+	s.counter(&buf, "operations.get", m.GetCount, s.last.GetCount)
+	s.counter(&buf, "operations.set", m.SetCount, s.last.SetCount)
+	s.counter(&buf, "operations.delete", m.DeleteCount, s.last.DeleteCount)
+	s.gauge(&buf, "latency.avg.get", m.GetAvgLatency)
+	s.gauge(&buf, "latency.avg.set", m.SetAvgLatency)
+	s.gauge(&buf, "latency.avg.delete", m.DeleteAvgLatency)
+	s.last = m
+
+	_, err := s.conn.Write(buf.Bytes())
+	return err
+}
+
+func (s *StatsD) counter(buf *bytes.Buffer, name string, now, last uint64) {
+	delta := now - last
+	if now < last {
+		// Counters were reset since the last export
+		delta = now
+	}
+	fmt.Fprintf(buf, "%s.%s:%d|c\n", s.prefix, name, delta)
+}
+
+func (s *StatsD) gauge(buf *bytes.Buffer, name string, d time.Duration) {
+	fmt.Fprintf(buf, "%s.%s:%g|g\n", s.prefix, name, float64(d)/float64(time.Millisecond))
+}
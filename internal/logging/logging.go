@@ -0,0 +1,54 @@
+// Package logging builds the structured slog loggers used by the node and
+// mandi.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Log formats accepted by New.
+const (
+	FormatText = "text" // key=value pairs (default)
+	FormatJSON = "json" // one JSON object per line
+)
+
+// New returns a logger writing to w that drops records below level
+// ("debug", "info", "warn" or "error"; empty = info) and encodes them in
+// format (FormatText or FormatJSON; empty = text).
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("log level %q must be one of debug, info, warn, error", level)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case "", FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("log format %q must be one of text, json", format)
+	}
+}
+
+// Setup installs a logger on stderr built by New as slog's default, which
+// also routes the standard log package through it.
+func Setup(level, format string, attrs ...any) error {
+	logger, err := New(os.Stderr, level, format)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger.With(attrs...))
+	return nil
+}
+
+// Fatal logs msg at error level with args and exits with status 1.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
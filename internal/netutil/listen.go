@@ -0,0 +1,39 @@
+package netutil
+
+import (
+	"context"
+	"net"
+)
+
+// ListenOptions tunes the TCP listeners used by the servers.
+// The zero value behaves exactly like net.Listen.
+type ListenOptions struct {
+	// Backlog sets the accept queue length. The kernel silently caps it
+	// (net.core.somaxconn on Linux). Zero keeps the OS default.
+	Backlog int
+
+	// ReusePort sets SO_REUSEPORT so several server processes can bind the
+	// same port and let the kernel spread connections between them.
+	ReusePort bool
+}
+
+// Listen opens a TCP listener on addr with opts applied.
+func Listen(addr string, opts ListenOptions) (net.Listener, error) {
+	lc := net.ListenConfig{}
+	if opts.ReusePort {
+		lc.Control = reusePortControl
+	}
+
+	ln, err := lc.Listen(context.Background(), "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Backlog > 0 {
+		if err := setBacklog(ln, opts.Backlog); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
@@ -0,0 +1,19 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package netutil
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+var errUnsupported = errors.New("listener tuning is not supported on this platform")
+
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	return errUnsupported
+}
+
+func setBacklog(ln net.Listener, backlog int) error {
+	return errUnsupported
+}
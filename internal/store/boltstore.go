@@ -0,0 +1,166 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/heysubinoy/pyazdb/pkg/kv"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltBucket holds every key of a BoltStore.
+var boltBucket = []byte("kv")
+
+// BoltStore is a kv.Store persisted in a single bolt file, for nodes that
+// run without Raft. Each write is its own transaction, committed and
+// synced to disk before it returns, so acknowledged writes survive a
+// crash or restart.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// Compile-time checks to ensure BoltStore implements kv.Store and the
+// optional store interfaces it supports natively.
+var (
+	_ kv.Store             = (*BoltStore)(nil)
+	_ kv.BatchStore        = (*BoltStore)(nil)
+	_ kv.DeleteReportStore = (*BoltStore)(nil)
+)
+
+// NewBoltStore opens (creating if needed) the bolt file at path. Bolt
+// holds an exclusive lock on the file, so a second process opening the
+// same path fails after a short wait instead of corrupting it.
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening bolt store %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating bucket in %s: %w", path, err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Close releases the file. The store must not be used afterwards.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// Get retrieves the value for key.
+func (s *BoltStore) Get(key string) (string, bool) {
+	var value string
+	var ok bool
+	s.db.View(func(tx *bolt.Tx) error {
+		value, ok = getCopy(tx, key)
+		return nil
+	})
+	return value, ok
+}
+
+// MultiGet retrieves several keys in one read transaction, so the result
+// is a consistent view.
+func (s *BoltStore) MultiGet(keys []string) (map[string]string, error) {
+	out := make(map[string]string, len(keys))
+	err := s.db.View(func(tx *bolt.Tx) error {
+		for _, key := range keys {
+			if v, ok := getCopy(tx, key); ok {
+				out[key] = v
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+// Exists reports whether key is present.
+func (s *BoltStore) Exists(key string) (bool, error) {
+	var ok bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		ok = tx.Bucket(boltBucket).Get([]byte(key)) != nil
+		return nil
+	})
+	return ok, err
+}
+
+// Set stores a key-value pair.
+func (s *BoltStore) Set(key, value string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(key), []byte(value))
+	})
+}
+
+// Delete removes key. Deleting a missing key is not an error.
+func (s *BoltStore) Delete(key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Delete([]byte(key))
+	})
+}
+
+// DeleteReport removes key and reports whether it existed, checked in the
+// same transaction. BoltStore has no commit index, so the index is always 0.
+func (s *BoltStore) DeleteReport(key string) (bool, uint64, error) {
+	var existed bool
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltBucket)
+		existed = b.Get([]byte(key)) != nil
+		return b.Delete([]byte(key))
+	})
+	return existed, 0, err
+}
+
+// Batch applies ops in order in one transaction: all of them are
+// persisted or none. Callers are expected to have checked the ops with
+// kv.ValidateBatch; unknown ops are skipped.
+func (s *BoltStore) Batch(ops []kv.BatchOp) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltBucket)
+		for _, op := range ops {
+			var err error
+			switch op.Op {
+			case "set":
+				err = b.Put([]byte(op.Key), []byte(op.Value))
+			case "delete":
+				err = b.Delete([]byte(op.Key))
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Scan returns the pairs whose key starts with prefix, sorted by key.
+// Bolt keeps keys sorted, so this seeks to prefix and stops at the first
+// key past it; the read transaction makes the result a consistent view.
+func (s *BoltStore) Scan(prefix string, limit int) ([]kv.KeyValue, error) {
+	var pairs []kv.KeyValue
+	err := s.db.View(func(tx *bolt.Tx) error {
+		p := []byte(prefix)
+		c := tx.Bucket(boltBucket).Cursor()
+		for k, v := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
+			pairs = append(pairs, kv.KeyValue{Key: string(k), Value: string(v)})
+			if limit > 0 && len(pairs) >= limit {
+				break
+			}
+		}
+		return nil
+	})
+	return pairs, err
+}
+
+// getCopy reads key inside tx. Bolt's slices are only valid for the
+// transaction, so the value is copied out as a string.
+func getCopy(tx *bolt.Tx, key string) (string, bool) {
+	v := tx.Bucket(boltBucket).Get([]byte(key))
+	if v == nil {
+		return "", false
+	}
+	return string(v), true
+}
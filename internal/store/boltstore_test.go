@@ -0,0 +1,96 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/heysubinoy/pyazdb/pkg/kv"
+)
+
+func TestBoltStorePersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.db")
+	tests := []struct {
+		name  string
+		write func(s *BoltStore) error
+		key   string
+		value string
+		found bool
+	}{
+		{"set", func(s *BoltStore) error { return s.Set("a", "1") }, "a", "1", true},
+		{"overwrite", func(s *BoltStore) error { return s.Set("a", "2") }, "a", "2", true},
+		{"delete", func(s *BoltStore) error { return s.Delete("a") }, "a", "", false},
+		{"batch set", func(s *BoltStore) error {
+			return s.Batch([]kv.BatchOp{{Op: "set", Key: "b", Value: "x"}, {Op: "set", Key: "c", Value: "y"}})
+		}, "c", "y", true},
+		{"batch delete", func(s *BoltStore) error {
+			return s.Batch([]kv.BatchOp{{Op: "delete", Key: "b"}})
+		}, "b", "", false},
+		{"empty value", func(s *BoltStore) error { return s.Set("e", "") }, "e", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewBoltStore(path)
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			if err := tt.write(s); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			if err := s.Close(); err != nil {
+				t.Fatalf("close: %v", err)
+			}
+
+			s, err = NewBoltStore(path)
+			if err != nil {
+				t.Fatalf("reopen: %v", err)
+			}
+			defer s.Close()
+			v, ok := s.Get(tt.key)
+			if ok != tt.found || v != tt.value {
+				t.Errorf("Get(%s) after reopen = %q, %v; want %q, %v", tt.key, v, ok, tt.value, tt.found)
+			}
+		})
+	}
+}
+
+func TestBoltStoreScan(t *testing.T) {
+	s, err := NewBoltStore(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer s.Close()
+	for _, k := range []string{"user/2", "user/1", "user/3", "other"} {
+		s.Set(k, k)
+	}
+
+	tests := []struct {
+		prefix string
+		limit  int
+		want   []string
+	}{
+		{"user/", 0, []string{"user/1", "user/2", "user/3"}},
+		{"user/", 2, []string{"user/1", "user/2"}},
+		{"", 0, []string{"other", "user/1", "user/2", "user/3"}},
+		{"none/", 0, nil},
+	}
+	for _, tt := range tests {
+		pairs, err := s.Scan(tt.prefix, tt.limit)
+		if err != nil {
+			t.Fatalf("Scan(%q): %v", tt.prefix, err)
+		}
+		var got []string
+		for _, p := range pairs {
+			got = append(got, p.Key)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("Scan(%q, %d) = %v, want %v", tt.prefix, tt.limit, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Scan(%q, %d) = %v, want %v", tt.prefix, tt.limit, got, tt.want)
+				break
+			}
+		}
+	}
+}
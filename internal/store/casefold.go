@@ -0,0 +1,373 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/heysubinoy/pyazdb/pkg/kv"
+)
+
+// CaseFoldStore wraps a kv.Store and lowercases every key before delegating,
+// so "Foo" and "foo" name the same entry. This changes key identity: keys
+// differing only in case collide, and Scan returns the lowercased keys.
+//
+// Wrap the RaftStore (not the MemStore) so keys are folded before they are
+// proposed; every replica then applies the same key. All nodes in a cluster
+// must agree on whether folding is enabled.
+type CaseFoldStore struct {
+	store kv.Store
+}
+
+// Compile-time checks to ensure CaseFoldStore implements kv.Store and
+// forwards the optional store interfaces.
+var (
+	_ kv.Store             = (*CaseFoldStore)(nil)
+	_ kv.TTLStore          = (*CaseFoldStore)(nil)
+	_ kv.ReplicatedStore   = (*CaseFoldStore)(nil)
+	_ kv.UndeleteStore     = (*CaseFoldStore)(nil)
+	_ kv.IndexedStore      = (*CaseFoldStore)(nil)
+	_ kv.LabelStore        = (*CaseFoldStore)(nil)
+	_ kv.BatchStore        = (*CaseFoldStore)(nil)
+	_ kv.UsageStore        = (*CaseFoldStore)(nil)
+	_ kv.SizeStore         = (*CaseFoldStore)(nil)
+	_ kv.AgeStore          = (*CaseFoldStore)(nil)
+	_ kv.ConditionalStore  = (*CaseFoldStore)(nil)
+	_ kv.CounterStore      = (*CaseFoldStore)(nil)
+	_ kv.DeleteReportStore = (*CaseFoldStore)(nil)
+	_ kv.ContextStore      = (*CaseFoldStore)(nil)
+	_ kv.WatchStore        = (*CaseFoldStore)(nil)
+	_ kv.AliasStore        = (*CaseFoldStore)(nil)
+	_ kv.IdempotentStore   = (*CaseFoldStore)(nil)
+)
+
+// NewCaseFoldStore wraps a store with case-insensitive keys.
+func NewCaseFoldStore(store kv.Store) *CaseFoldStore {
+	return &CaseFoldStore{store: store}
+}
+
+// Get retrieves the value for the lowercased key.
+func (s *CaseFoldStore) Get(key string) (string, bool) {
+	return s.store.Get(strings.ToLower(key))
+}
+
+// MultiGet retrieves the lowercased keys; the result is keyed by the keys
+// as given.
+func (s *CaseFoldStore) MultiGet(keys []string) (map[string]string, error) {
+	folded := make([]string, len(keys))
+	for i, key := range keys {
+		folded[i] = strings.ToLower(key)
+	}
+	values, err := s.store.MultiGet(folded)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(values))
+	for i, key := range keys {
+		if v, ok := values[folded[i]]; ok {
+			out[key] = v
+		}
+	}
+	return out, nil
+}
+
+// Exists checks the lowercased key.
+func (s *CaseFoldStore) Exists(key string) (bool, error) {
+	return s.store.Exists(strings.ToLower(key))
+}
+
+// Set stores the value under the lowercased key.
+func (s *CaseFoldStore) Set(key, value string) error {
+	return s.store.Set(strings.ToLower(key), value)
+}
+
+// Delete removes the lowercased key.
+func (s *CaseFoldStore) Delete(key string) error {
+	return s.store.Delete(strings.ToLower(key))
+}
+
+// Scan returns the pairs under the lowercased prefix.
+func (s *CaseFoldStore) Scan(prefix string, limit int) ([]kv.KeyValue, error) {
+	return s.store.Scan(strings.ToLower(prefix), limit)
+}
+
+// SetWithTTL delegates to the wrapped store if it supports TTLs.
+func (s *CaseFoldStore) SetWithTTL(key, value string, ttl time.Duration) error {
+	ts, ok := s.store.(kv.TTLStore)
+	if !ok {
+		return kv.ErrNotSupported
+	}
+	return ts.SetWithTTL(strings.ToLower(key), value, ttl)
+}
+
+// Touch delegates to the wrapped store if it supports TTLs.
+func (s *CaseFoldStore) Touch(key string, ttl time.Duration) (bool, error) {
+	ts, ok := s.store.(kv.TTLStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	return ts.Touch(strings.ToLower(key), ttl)
+}
+
+// ExpirePrefix delegates to the wrapped store if it supports TTLs.
+func (s *CaseFoldStore) ExpirePrefix(prefix string, ttl time.Duration) (int, error) {
+	ts, ok := s.store.(kv.TTLStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	return ts.ExpirePrefix(strings.ToLower(prefix), ttl)
+}
+
+// SetReplicated delegates to the wrapped store if it supports replica acks.
+func (s *CaseFoldStore) SetReplicated(key, value string, replicas int) (int, error) {
+	rs, ok := s.store.(kv.ReplicatedStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	return rs.SetReplicated(strings.ToLower(key), value, replicas)
+}
+
+// Undelete delegates to the wrapped store if it supports soft deletes.
+func (s *CaseFoldStore) Undelete(key string) (bool, error) {
+	us, ok := s.store.(kv.UndeleteStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	return us.Undelete(strings.ToLower(key))
+}
+
+// SetIndexed delegates to the wrapped store if it reports commit indexes.
+func (s *CaseFoldStore) SetIndexed(key, value string) (uint64, error) {
+	is, ok := s.store.(kv.IndexedStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	return is.SetIndexed(strings.ToLower(key), value)
+}
+
+// DeleteIndexed delegates to the wrapped store if it reports commit indexes.
+func (s *CaseFoldStore) DeleteIndexed(key string) (uint64, error) {
+	is, ok := s.store.(kv.IndexedStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	return is.DeleteIndexed(strings.ToLower(key))
+}
+
+// DeleteReport delegates to the wrapped store if it reports whether deleted
+// keys existed.
+func (s *CaseFoldStore) DeleteReport(key string) (bool, uint64, error) {
+	ds, ok := s.store.(kv.DeleteReportStore)
+	if !ok {
+		return false, 0, kv.ErrNotSupported
+	}
+	return ds.DeleteReport(strings.ToLower(key))
+}
+
+// SetCtx delegates to the wrapped store if it supports cancellable writes.
+func (s *CaseFoldStore) SetCtx(ctx context.Context, key, value string) (uint64, error) {
+	cs, ok := s.store.(kv.ContextStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	return cs.SetCtx(ctx, strings.ToLower(key), value)
+}
+
+// DeleteCtx delegates to the wrapped store if it supports cancellable writes.
+func (s *CaseFoldStore) DeleteCtx(ctx context.Context, key string) (bool, uint64, error) {
+	cs, ok := s.store.(kv.ContextStore)
+	if !ok {
+		return false, 0, kv.ErrNotSupported
+	}
+	return cs.DeleteCtx(ctx, strings.ToLower(key))
+}
+
+// SetNXWithTTL delegates to the wrapped store if it supports conditional writes.
+func (s *CaseFoldStore) SetNXWithTTL(key, value string, ttl time.Duration) (bool, error) {
+	cs, ok := s.store.(kv.ConditionalStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	return cs.SetNXWithTTL(strings.ToLower(key), value, ttl)
+}
+
+// DeleteIf delegates to the wrapped store if it supports conditional writes.
+func (s *CaseFoldStore) DeleteIf(key, value string) (bool, error) {
+	cs, ok := s.store.(kv.ConditionalStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	return cs.DeleteIf(strings.ToLower(key), value)
+}
+
+// Watch delegates to the wrapped store if it supports watches, with the
+// watched key or prefix lowercased.
+func (s *CaseFoldStore) Watch(ctx context.Context, opts kv.WatchOptions) (<-chan kv.Event, error) {
+	ws, ok := s.store.(kv.WatchStore)
+	if !ok {
+		return nil, kv.ErrNotSupported
+	}
+	opts.Key = strings.ToLower(opts.Key)
+	opts.Prefix = strings.ToLower(opts.Prefix)
+	return ws.Watch(ctx, opts)
+}
+
+// SetAlias delegates to the wrapped store if it supports aliases.
+func (s *CaseFoldStore) SetAlias(alias, target string) error {
+	as, ok := s.store.(kv.AliasStore)
+	if !ok {
+		return kv.ErrNotSupported
+	}
+	return as.SetAlias(strings.ToLower(alias), strings.ToLower(target))
+}
+
+// ResolveAlias delegates to the wrapped store if it supports aliases.
+// Without alias support every key names itself.
+func (s *CaseFoldStore) ResolveAlias(key string) (string, error) {
+	as, ok := s.store.(kv.AliasStore)
+	if !ok {
+		return strings.ToLower(key), nil
+	}
+	return as.ResolveAlias(strings.ToLower(key))
+}
+
+// CompareAndSwap delegates to the wrapped store if it supports conditional writes.
+func (s *CaseFoldStore) CompareAndSwap(key, old, new string) (bool, error) {
+	cs, ok := s.store.(kv.ConditionalStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	return cs.CompareAndSwap(strings.ToLower(key), old, new)
+}
+
+// Increment delegates to the wrapped store if it supports counters.
+func (s *CaseFoldStore) Increment(key string, delta int64) (int64, error) {
+	cs, ok := s.store.(kv.CounterStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	return cs.Increment(strings.ToLower(key), delta)
+}
+
+// SetIdempotent delegates to the wrapped store if it deduplicates writes.
+func (s *CaseFoldStore) SetIdempotent(ctx context.Context, requestID, key, value string) (uint64, error) {
+	is, ok := s.store.(kv.IdempotentStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	return is.SetIdempotent(ctx, requestID, strings.ToLower(key), value)
+}
+
+// IncrementIdempotent delegates to the wrapped store if it deduplicates
+// writes.
+func (s *CaseFoldStore) IncrementIdempotent(requestID, key string, delta int64) (int64, error) {
+	is, ok := s.store.(kv.IdempotentStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	return is.IncrementIdempotent(requestID, strings.ToLower(key), delta)
+}
+
+// BatchIdempotent delegates to the wrapped store if it deduplicates
+// writes, with every op's key lowercased.
+func (s *CaseFoldStore) BatchIdempotent(requestID string, ops []kv.BatchOp) error {
+	is, ok := s.store.(kv.IdempotentStore)
+	if !ok {
+		return kv.ErrNotSupported
+	}
+	folded := make([]kv.BatchOp, len(ops))
+	for i, op := range ops {
+		folded[i] = kv.BatchOp{Op: op.Op, Key: strings.ToLower(op.Key), Value: op.Value}
+	}
+	return is.BatchIdempotent(requestID, folded)
+}
+
+// Oldest delegates to the wrapped store if it records key timestamps.
+func (s *CaseFoldStore) Oldest(prefix string, byUpdate bool) (string, kv.Entry, bool) {
+	as, ok := s.store.(kv.AgeStore)
+	if !ok {
+		return "", kv.Entry{}, false
+	}
+	return as.Oldest(strings.ToLower(prefix), byUpdate)
+}
+
+// Newest delegates to the wrapped store if it records key timestamps.
+func (s *CaseFoldStore) Newest(prefix string, byUpdate bool) (string, kv.Entry, bool) {
+	as, ok := s.store.(kv.AgeStore)
+	if !ok {
+		return "", kv.Entry{}, false
+	}
+	return as.Newest(strings.ToLower(prefix), byUpdate)
+}
+
+// NamespaceUsage delegates to the wrapped store if it tracks usage.
+// Stores without usage tracking report no namespaces.
+func (s *CaseFoldStore) NamespaceUsage() map[string]kv.Usage {
+	us, ok := s.store.(kv.UsageStore)
+	if !ok {
+		return nil
+	}
+	return us.NamespaceUsage()
+}
+
+// Len delegates to the wrapped store if it reports its size, and returns 0
+// otherwise.
+func (s *CaseFoldStore) Len() int {
+	ss, ok := s.store.(kv.SizeStore)
+	if !ok {
+		return 0
+	}
+	return ss.Len()
+}
+
+// ApproxBytes delegates to the wrapped store if it reports its size, and
+// returns 0 otherwise.
+func (s *CaseFoldStore) ApproxBytes() int64 {
+	ss, ok := s.store.(kv.SizeStore)
+	if !ok {
+		return 0
+	}
+	return ss.ApproxBytes()
+}
+
+// Batch delegates to the wrapped store if it supports batches, with every
+// op's key lowercased.
+func (s *CaseFoldStore) Batch(ops []kv.BatchOp) error {
+	bs, ok := s.store.(kv.BatchStore)
+	if !ok {
+		return kv.ErrNotSupported
+	}
+	folded := make([]kv.BatchOp, len(ops))
+	for i, op := range ops {
+		folded[i] = kv.BatchOp{Op: op.Op, Key: strings.ToLower(op.Key), Value: op.Value}
+	}
+	return bs.Batch(folded)
+}
+
+// SetLabels delegates to the wrapped store if it supports labels.
+func (s *CaseFoldStore) SetLabels(key string, labels map[string]string) (bool, error) {
+	ls, ok := s.store.(kv.LabelStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	return ls.SetLabels(strings.ToLower(key), labels)
+}
+
+// GetWithMeta delegates to the wrapped store if it supports labels.
+func (s *CaseFoldStore) GetWithMeta(key string) (kv.Entry, bool) {
+	ls, ok := s.store.(kv.LabelStore)
+	if !ok {
+		return kv.Entry{}, false
+	}
+	return ls.GetWithMeta(strings.ToLower(key))
+}
+
+// ScanLabel delegates to the wrapped store if it supports labels.
+// Only the prefix is folded; label names and values keep their case.
+func (s *CaseFoldStore) ScanLabel(prefix, name, value string, limit int) ([]kv.KeyValue, error) {
+	ls, ok := s.store.(kv.LabelStore)
+	if !ok {
+		return nil, kv.ErrNotSupported
+	}
+	return ls.ScanLabel(strings.ToLower(prefix), name, value, limit)
+}
@@ -0,0 +1,71 @@
+package store
+
+import "testing"
+
+func TestCaseFoldStoreFoldsKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		write string
+		read  string
+	}{
+		{"lower to upper", "foo", "FOO"},
+		{"upper to lower", "Foo", "foo"},
+		{"mixed", "User/Alice", "user/ALICE"},
+		{"unicode", "ÄPFEL", "äpfel"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mem := NewMemStore()
+			s := NewCaseFoldStore(mem)
+			if err := s.Set(tt.write, "v"); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+			if v, ok := s.Get(tt.read); !ok || v != "v" {
+				t.Errorf("Get(%q) = %q, %v; want v", tt.read, v, ok)
+			}
+			if ok, _ := s.Exists(tt.read); !ok {
+				t.Errorf("Exists(%q) = false", tt.read)
+			}
+			got, err := s.MultiGet([]string{tt.read})
+			if err != nil || got[tt.read] != "v" {
+				t.Errorf("MultiGet(%q) = %v, %v; want it keyed as given", tt.read, got, err)
+			}
+			if mem.Len() != 1 {
+				t.Errorf("underlying store holds %d keys, want 1", mem.Len())
+			}
+			if err := s.Delete(tt.read); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if _, ok := s.Get(tt.write); ok {
+				t.Errorf("Get(%q) found the key after Delete(%q)", tt.write, tt.read)
+			}
+		})
+	}
+}
+
+func TestCaseFoldStoreCollidesKeys(t *testing.T) {
+	s := NewCaseFoldStore(NewMemStore())
+	s.Set("Foo", "first")
+	s.Set("foo", "second")
+	if v, _ := s.Get("FOO"); v != "second" {
+		t.Errorf("Get(FOO) = %q, want the later write", v)
+	}
+
+	if _, err := s.Increment("Hits", 2); err != nil {
+		t.Fatalf("Increment: %v", err)
+	}
+	if n, err := s.Increment("HITS", 3); err != nil || n != 5 {
+		t.Errorf("Increment(HITS) = %d, %v; want 5", n, err)
+	}
+	if ok, err := s.CompareAndSwap("FOO", "second", "third"); err != nil || !ok {
+		t.Errorf("CompareAndSwap(FOO) = %v, %v; want swapped", ok, err)
+	}
+
+	pairs, err := s.Scan("F", 0)
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if len(pairs) != 1 || pairs[0].Key != "foo" || pairs[0].Value != "third" {
+		t.Errorf("Scan(F) = %v, want the one lowercased key", pairs)
+	}
+}
@@ -0,0 +1,153 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// testNode is one member of a testCluster.
+type testNode struct {
+	id        raft.ServerID
+	rs        *RaftStore
+	raft      *raft.Raft
+	trans     *raft.NetworkTransport
+	logs      *testLogStore
+	stable    *raft.InmemStore
+	snapshots raft.SnapshotStore
+}
+
+// testLogStore is an in-memory log that can be made to refuse new entries,
+// as a full or failing disk would. A node whose log refuses entries still
+// answers heartbeats, so a leader keeps its lease while nothing commits.
+type testLogStore struct {
+	*raft.InmemStore
+	failing atomic.Bool
+}
+
+func (s *testLogStore) StoreLog(log *raft.Log) error {
+	return s.StoreLogs([]*raft.Log{log})
+}
+
+func (s *testLogStore) StoreLogs(logs []*raft.Log) error {
+	if s.failing.Load() {
+		return errors.New("log store failing")
+	}
+	return s.InmemStore.StoreLogs(logs)
+}
+
+// testCluster is a Raft cluster of real TCP transports on loopback, with
+// in-memory logs and fast timeouts.
+type testCluster struct {
+	t     *testing.T
+	nodes []*testNode
+}
+
+func testRaftConfig(id raft.ServerID) *raft.Config {
+	cfg := raft.DefaultConfig()
+	cfg.LocalID = id
+	cfg.HeartbeatTimeout = 50 * time.Millisecond
+	cfg.ElectionTimeout = 50 * time.Millisecond
+	cfg.LeaderLeaseTimeout = 50 * time.Millisecond
+	cfg.CommitTimeout = 5 * time.Millisecond
+	cfg.LogOutput = io.Discard
+	return cfg
+}
+
+// newTestCluster starts n nodes bootstrapped as one cluster and waits for
+// a leader.
+func newTestCluster(t *testing.T, n int) *testCluster {
+	t.Helper()
+	c := &testCluster{t: t}
+	var servers []raft.Server
+	for i := 0; i < n; i++ {
+		trans, err := raft.NewTCPTransport("127.0.0.1:0", nil, 3, time.Second, io.Discard)
+		if err != nil {
+			t.Fatalf("transport: %v", err)
+		}
+		node := &testNode{
+			id:        raft.ServerID(fmt.Sprintf("node%d", i)),
+			trans:     trans,
+			logs:      &testLogStore{InmemStore: raft.NewInmemStore()},
+			stable:    raft.NewInmemStore(),
+			snapshots: raft.NewInmemSnapshotStore(),
+		}
+		c.nodes = append(c.nodes, node)
+		servers = append(servers, raft.Server{ID: node.id, Address: trans.LocalAddr()})
+	}
+	for _, node := range c.nodes {
+		c.start(node)
+		if err := node.raft.BootstrapCluster(raft.Configuration{Servers: servers}).Error(); err != nil {
+			t.Fatalf("bootstrap: %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		for _, node := range c.nodes {
+			if node.raft != nil {
+				node.raft.Shutdown().Error()
+			}
+		}
+	})
+	c.leader()
+	return c
+}
+
+// start runs a fresh RaftStore and raft instance on node's transport and
+// storage, as a restarted process would.
+func (c *testCluster) start(node *testNode) {
+	c.t.Helper()
+	node.rs = NewRaftStore(NewMemStore(), nil)
+	tracker := NewReplicationTracker(node.trans)
+	node.rs.SetReplicationTracker(tracker)
+	r, err := raft.NewRaft(testRaftConfig(node.id), node.rs, node.logs, node.stable, node.snapshots, tracker)
+	if err != nil {
+		c.t.Fatalf("raft: %v", err)
+	}
+	node.rs.SetRaft(r)
+	node.raft = r
+}
+
+// leader waits for a node to lead and returns it.
+func (c *testCluster) leader() *testNode {
+	c.t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, node := range c.nodes {
+			if node.raft != nil && node.raft.State() == raft.Leader {
+				return node
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.t.Fatal("no leader elected")
+	return nil
+}
+
+// followers returns every running node but the leader.
+func (c *testCluster) followers() []*testNode {
+	leader := c.leader()
+	var out []*testNode
+	for _, node := range c.nodes {
+		if node != leader && node.raft != nil {
+			out = append(out, node)
+		}
+	}
+	return out
+}
+
+// waitApplied waits until node has applied index.
+func (c *testCluster) waitApplied(node *testNode, index uint64) {
+	c.t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for node.raft.AppliedIndex() < index {
+		if time.Now().After(deadline) {
+			c.t.Fatalf("%s stuck at index %d, want %d", node.id, node.raft.AppliedIndex(), index)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// Histogram layout: values below histLinear get a bucket each; above that,
+// every power of two is split into histSubBuckets equal buckets, so a
+// bucket's width is at most 1/8 of its lower bound.
+const (
+	histSubBits    = 3
+	histSubBuckets = 1 << histSubBits
+	histLinear     = 2 * histSubBuckets
+	histBuckets    = histLinear + (64-histSubBits-1)*histSubBuckets
+)
+
+// LatencyHistogram is an HDR-style histogram of nanosecond latencies.
+// Record is a single atomic add, so it is safe and cheap on the hot path;
+// readers may see a recording in flight counted or not.
+type LatencyHistogram struct {
+	buckets [histBuckets]atomic.Uint64
+}
+
+// Record adds one observation of ns nanoseconds.
+func (h *LatencyHistogram) Record(ns uint64) {
+	h.buckets[bucketOf(ns)].Add(1)
+}
+
+// Quantile returns the latency at quantile q (0 < q <= 1), or 0 if nothing
+// has been recorded.
+func (h *LatencyHistogram) Quantile(q float64) time.Duration {
+	var counts histCounts
+	for i := range h.buckets {
+		counts[i] = h.buckets[i].Load()
+	}
+	return counts.quantile(q)
+}
+
+// swap returns the current counts and clears them.
+func (h *LatencyHistogram) swap() histCounts {
+	var counts histCounts
+	for i := range h.buckets {
+		counts[i] = h.buckets[i].Swap(0)
+	}
+	return counts
+}
+
+// histCounts is a copy of a histogram's buckets.
+type histCounts [histBuckets]uint64
+
+func (c *histCounts) quantile(q float64) time.Duration {
+	var total uint64
+	for _, n := range c {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(q*float64(total) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	var seen uint64
+	for i, n := range c {
+		seen += n
+		if seen >= rank {
+			return time.Duration(bucketValue(i))
+		}
+	}
+	return time.Duration(bucketValue(histBuckets - 1))
+}
+
+// bucketOf returns the bucket holding ns.
+func bucketOf(ns uint64) int {
+	if ns < histLinear {
+		return int(ns)
+	}
+	exp := bits.Len64(ns) - 1 // >= histSubBits+1
+	sub := (ns >> (exp - histSubBits)) & (histSubBuckets - 1)
+	return histLinear + (exp-histSubBits-1)*histSubBuckets + int(sub)
+}
+
+// bucketValue returns the midpoint of bucket i.
+func bucketValue(i int) uint64 {
+	if i < histLinear {
+		return uint64(i)
+	}
+	i -= histLinear
+	exp := i/histSubBuckets + histSubBits + 1
+	sub := uint64(i % histSubBuckets)
+	width := uint64(1) << (exp - histSubBits)
+	return (histSubBuckets+sub)*width + width/2
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+)
+
+// HotKey is a key's estimated access count. The true count lies between
+// Count-Error and Count.
+type HotKey struct {
+	Key   string `json:"key"`
+	Count uint64 `json:"count"`
+	Error uint64 `json:"error"`
+}
+
+// HotKeys finds the most accessed keys with the Space-Saving algorithm: it
+// counts at most capacity keys, and a new key replaces the least counted
+// one, inheriting its count as the error bound. Memory stays bounded by
+// capacity however many distinct keys are seen, and any key accessed more
+// than total/capacity times is guaranteed to be tracked.
+type HotKeys struct {
+	mu       sync.Mutex
+	capacity int
+	entries  hotKeyHeap
+	index    map[string]*hotKeyEntry
+}
+
+// NewHotKeys returns a tracker that counts at most capacity keys.
+func NewHotKeys(capacity int) *HotKeys {
+	return &HotKeys{
+		capacity: capacity,
+		index:    make(map[string]*hotKeyEntry, capacity),
+	}
+}
+
+// Record counts one access to key.
+func (h *HotKeys) Record(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if e, ok := h.index[key]; ok {
+		e.count++
+		heap.Fix(&h.entries, e.pos)
+		return
+	}
+	if len(h.entries) < h.capacity {
+		e := &hotKeyEntry{key: key, count: 1}
+		heap.Push(&h.entries, e)
+		h.index[key] = e
+		return
+	}
+	// Evict the least counted key; the newcomer may have been seen up to
+	// that many times before, uncounted.
+	e := h.entries[0]
+	delete(h.index, e.key)
+	e.key, e.err = key, e.count
+	e.count++
+	h.index[key] = e
+	heap.Fix(&h.entries, 0)
+}
+
+// Top returns up to n keys with the highest counts, most accessed first.
+func (h *HotKeys) Top(n int) []HotKey {
+	h.mu.Lock()
+	out := make([]HotKey, len(h.entries))
+	for i, e := range h.entries {
+		out[i] = HotKey{Key: e.key, Count: e.count, Error: e.err}
+	}
+	h.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Key < out[j].Key
+	})
+	if n < len(out) {
+		out = out[:n]
+	}
+	return out
+}
+
+type hotKeyEntry struct {
+	key   string
+	count uint64
+	err   uint64
+	pos   int // index in the heap
+}
+
+// hotKeyHeap is a min-heap by count, so the eviction candidate is at the root.
+type hotKeyHeap []*hotKeyEntry
+
+func (h hotKeyHeap) Len() int           { return len(h) }
+func (h hotKeyHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+
+func (h hotKeyHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].pos = i
+	h[j].pos = j
+}
+
+func (h *hotKeyHeap) Push(x interface{}) {
+	e := x.(*hotKeyEntry)
+	e.pos = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *hotKeyHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
@@ -1,6 +1,7 @@
 	package store
 
 import (
+	"context"
 	"sync/atomic"
 	"time"
 
@@ -18,6 +19,11 @@ type Metrics struct {
 	GetLatencyNs    atomic.Uint64
 	SetLatencyNs    atomic.Uint64
 	DeleteLatencyNs atomic.Uint64
+
+	// Latency distributions, for percentiles
+	GetLatency    LatencyHistogram
+	SetLatency    LatencyHistogram
+	DeleteLatency LatencyHistogram
 }
 
 // InstrumentedStore wraps any kv.Store implementation with timing metrics.
@@ -25,10 +31,35 @@ type Metrics struct {
 type InstrumentedStore struct {
 	store   kv.Store
 	metrics *Metrics
+
+	// hotKeys, when set, counts reads per key; see EnableHotKeys.
+	hotKeys *HotKeys
+
+	// readCache, when set, is the read cache below this store, reported
+	// alongside the store metrics; see SetReadCache.
+	readCache *ReadCacheStore
 }
 
-// Compile-time check to ensure InstrumentedStore implements kv.Store.
-var _ kv.Store = (*InstrumentedStore)(nil)
+// Compile-time checks to ensure InstrumentedStore implements kv.Store and
+// forwards the optional store interfaces.
+var (
+	_ kv.Store           = (*InstrumentedStore)(nil)
+	_ kv.TTLStore        = (*InstrumentedStore)(nil)
+	_ kv.ReplicatedStore = (*InstrumentedStore)(nil)
+	_ kv.UndeleteStore   = (*InstrumentedStore)(nil)
+	_ kv.IndexedStore    = (*InstrumentedStore)(nil)
+	_ kv.LabelStore      = (*InstrumentedStore)(nil)
+	_ kv.BatchStore      = (*InstrumentedStore)(nil)
+	_ kv.UsageStore      = (*InstrumentedStore)(nil)
+	_ kv.AgeStore        = (*InstrumentedStore)(nil)
+	_ kv.ConditionalStore = (*InstrumentedStore)(nil)
+	_ kv.CounterStore     = (*InstrumentedStore)(nil)
+	_ kv.DeleteReportStore = (*InstrumentedStore)(nil)
+	_ kv.ContextStore      = (*InstrumentedStore)(nil)
+	_ kv.WatchStore       = (*InstrumentedStore)(nil)
+	_ kv.AliasStore       = (*InstrumentedStore)(nil)
+	_ kv.IdempotentStore  = (*InstrumentedStore)(nil)
+)
 
 // NewInstrumentedStore wraps a store with instrumentation.
 func NewInstrumentedStore(store kv.Store) *InstrumentedStore {
@@ -38,14 +69,55 @@ func NewInstrumentedStore(store kv.Store) *InstrumentedStore {
 	}
 }
 
+// EnableHotKeys starts counting reads per key, tracking at most capacity
+// keys. It adds a mutex-guarded update to every read, so it is off unless
+// enabled. Call it before the store is shared.
+func (s *InstrumentedStore) EnableHotKeys(capacity int) {
+	s.hotKeys = NewHotKeys(capacity)
+}
+
+// HotKeys returns up to n of the most read keys, or false if hot key
+// tracking is not enabled.
+func (s *InstrumentedStore) HotKeys(n int) ([]HotKey, bool) {
+	if s.hotKeys == nil {
+		return nil, false
+	}
+	return s.hotKeys.Top(n), true
+}
+
+// SetReadCache reports c's hit and miss counts with this store's
+// metrics. c should be the read cache this store wraps, directly or
+// further down. Call it before the store is shared.
+func (s *InstrumentedStore) SetReadCache(c *ReadCacheStore) {
+	s.readCache = c
+}
+
+// ReadCacheStats returns the read cache's counts, or false if no read
+// cache is set.
+func (s *InstrumentedStore) ReadCacheStats() (ReadCacheStats, bool) {
+	if s.readCache == nil {
+		return ReadCacheStats{}, false
+	}
+	return s.readCache.Stats(), true
+}
+
+// recordRead counts a read of key when hot key tracking is enabled.
+func (s *InstrumentedStore) recordRead(key string) {
+	if s.hotKeys != nil {
+		s.hotKeys.Record(key)
+	}
+}
+
 // Get delegates to the wrapped store and records timing.
 func (s *InstrumentedStore) Get(key string) (string, bool) {
+	s.recordRead(key)
 	start := time.Now()
 	value, found := s.store.Get(key)
 	elapsed := time.Since(start).Nanoseconds()
 	
 	s.metrics.GetCount.Add(1)
 	s.metrics.GetLatencyNs.Add(uint64(elapsed))
+	s.metrics.GetLatency.Record(uint64(elapsed))
 	
 	return value, found
 }
@@ -58,10 +130,345 @@ func (s *InstrumentedStore) Set(key, value string) error {
 	
 	s.metrics.SetCount.Add(1)
 	s.metrics.SetLatencyNs.Add(uint64(elapsed))
+	s.metrics.SetLatency.Record(uint64(elapsed))
 	
 	return err
 }
 
+// SetWithTTL delegates to the wrapped store if it supports TTLs and records
+// timing as a set.
+func (s *InstrumentedStore) SetWithTTL(key, value string, ttl time.Duration) error {
+	ts, ok := s.store.(kv.TTLStore)
+	if !ok {
+		return kv.ErrNotSupported
+	}
+	start := time.Now()
+	err := ts.SetWithTTL(key, value, ttl)
+	s.recordSet(start)
+	return err
+}
+
+// SetIndexed delegates to the wrapped store if it reports commit indexes
+// and records timing as a set.
+func (s *InstrumentedStore) SetIndexed(key, value string) (uint64, error) {
+	is, ok := s.store.(kv.IndexedStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	start := time.Now()
+	index, err := is.SetIndexed(key, value)
+	s.recordSet(start)
+	return index, err
+}
+
+// DeleteIndexed delegates to the wrapped store if it reports commit indexes
+// and records timing as a delete.
+func (s *InstrumentedStore) DeleteIndexed(key string) (uint64, error) {
+	is, ok := s.store.(kv.IndexedStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	start := time.Now()
+	index, err := is.DeleteIndexed(key)
+	s.metrics.DeleteCount.Add(1)
+	elapsed := uint64(time.Since(start).Nanoseconds())
+	s.metrics.DeleteLatencyNs.Add(elapsed)
+	s.metrics.DeleteLatency.Record(elapsed)
+	return index, err
+}
+
+// DeleteReport delegates to the wrapped store if it reports whether deleted
+// keys existed, and records timing as a delete.
+func (s *InstrumentedStore) DeleteReport(key string) (bool, uint64, error) {
+	ds, ok := s.store.(kv.DeleteReportStore)
+	if !ok {
+		return false, 0, kv.ErrNotSupported
+	}
+	start := time.Now()
+	existed, index, err := ds.DeleteReport(key)
+	s.metrics.DeleteCount.Add(1)
+	elapsed := uint64(time.Since(start).Nanoseconds())
+	s.metrics.DeleteLatencyNs.Add(elapsed)
+	s.metrics.DeleteLatency.Record(elapsed)
+	return existed, index, err
+}
+
+// SetCtx delegates to the wrapped store if it supports cancellable writes
+// and records timing as a set.
+func (s *InstrumentedStore) SetCtx(ctx context.Context, key, value string) (uint64, error) {
+	cs, ok := s.store.(kv.ContextStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	start := time.Now()
+	index, err := cs.SetCtx(ctx, key, value)
+	s.recordSet(start)
+	return index, err
+}
+
+// DeleteCtx delegates to the wrapped store if it supports cancellable
+// writes and records timing as a delete.
+func (s *InstrumentedStore) DeleteCtx(ctx context.Context, key string) (bool, uint64, error) {
+	cs, ok := s.store.(kv.ContextStore)
+	if !ok {
+		return false, 0, kv.ErrNotSupported
+	}
+	start := time.Now()
+	existed, index, err := cs.DeleteCtx(ctx, key)
+	s.metrics.DeleteCount.Add(1)
+	elapsed := uint64(time.Since(start).Nanoseconds())
+	s.metrics.DeleteLatencyNs.Add(elapsed)
+	s.metrics.DeleteLatency.Record(elapsed)
+	return existed, index, err
+}
+
+// Touch delegates to the wrapped store if it supports TTLs.
+func (s *InstrumentedStore) Touch(key string, ttl time.Duration) (bool, error) {
+	ts, ok := s.store.(kv.TTLStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	return ts.Touch(key, ttl)
+}
+
+// ExpirePrefix delegates to the wrapped store if it supports TTLs.
+func (s *InstrumentedStore) ExpirePrefix(prefix string, ttl time.Duration) (int, error) {
+	ts, ok := s.store.(kv.TTLStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	return ts.ExpirePrefix(prefix, ttl)
+}
+
+// SetReplicated delegates to the wrapped store if it supports replication
+// targets and records timing as a set.
+func (s *InstrumentedStore) SetReplicated(key, value string, replicas int) (int, error) {
+	rs, ok := s.store.(kv.ReplicatedStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	start := time.Now()
+	n, err := rs.SetReplicated(key, value, replicas)
+	s.recordSet(start)
+	return n, err
+}
+
+// Undelete delegates to the wrapped store if it supports soft-delete.
+func (s *InstrumentedStore) Undelete(key string) (bool, error) {
+	us, ok := s.store.(kv.UndeleteStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	return us.Undelete(key)
+}
+
+// SetNXWithTTL delegates to the wrapped store if it supports conditional
+// writes and records timing as a set.
+func (s *InstrumentedStore) SetNXWithTTL(key, value string, ttl time.Duration) (bool, error) {
+	cs, ok := s.store.(kv.ConditionalStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	start := time.Now()
+	set, err := cs.SetNXWithTTL(key, value, ttl)
+	s.recordSet(start)
+	return set, err
+}
+
+// DeleteIf delegates to the wrapped store if it supports conditional
+// writes and records timing as a delete.
+func (s *InstrumentedStore) DeleteIf(key, value string) (bool, error) {
+	cs, ok := s.store.(kv.ConditionalStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	start := time.Now()
+	deleted, err := cs.DeleteIf(key, value)
+	s.metrics.DeleteCount.Add(1)
+	elapsed := uint64(time.Since(start).Nanoseconds())
+	s.metrics.DeleteLatencyNs.Add(elapsed)
+	s.metrics.DeleteLatency.Record(elapsed)
+	return deleted, err
+}
+
+// Watch delegates to the wrapped store if it supports watches.
+func (s *InstrumentedStore) Watch(ctx context.Context, opts kv.WatchOptions) (<-chan kv.Event, error) {
+	ws, ok := s.store.(kv.WatchStore)
+	if !ok {
+		return nil, kv.ErrNotSupported
+	}
+	return ws.Watch(ctx, opts)
+}
+
+// SetAlias delegates to the wrapped store if it supports aliases.
+func (s *InstrumentedStore) SetAlias(alias, target string) error {
+	as, ok := s.store.(kv.AliasStore)
+	if !ok {
+		return kv.ErrNotSupported
+	}
+	return as.SetAlias(alias, target)
+}
+
+// ResolveAlias delegates to the wrapped store if it supports aliases.
+// Without alias support every key names itself.
+func (s *InstrumentedStore) ResolveAlias(key string) (string, error) {
+	as, ok := s.store.(kv.AliasStore)
+	if !ok {
+		return key, nil
+	}
+	return as.ResolveAlias(key)
+}
+
+// CompareAndSwap delegates to the wrapped store if it supports conditional
+// writes and records timing as a set.
+func (s *InstrumentedStore) CompareAndSwap(key, old, new string) (bool, error) {
+	cs, ok := s.store.(kv.ConditionalStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	start := time.Now()
+	swapped, err := cs.CompareAndSwap(key, old, new)
+	s.recordSet(start)
+	return swapped, err
+}
+
+// Increment delegates to the wrapped store if it supports counters and
+// records timing as a set.
+func (s *InstrumentedStore) Increment(key string, delta int64) (int64, error) {
+	cs, ok := s.store.(kv.CounterStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	start := time.Now()
+	n, err := cs.Increment(key, delta)
+	s.recordSet(start)
+	return n, err
+}
+
+// SetIdempotent delegates to the wrapped store if it deduplicates writes
+// and records timing as a set.
+func (s *InstrumentedStore) SetIdempotent(ctx context.Context, requestID, key, value string) (uint64, error) {
+	is, ok := s.store.(kv.IdempotentStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	start := time.Now()
+	index, err := is.SetIdempotent(ctx, requestID, key, value)
+	s.recordSet(start)
+	return index, err
+}
+
+// IncrementIdempotent delegates to the wrapped store if it deduplicates
+// writes and records timing as a set.
+func (s *InstrumentedStore) IncrementIdempotent(requestID, key string, delta int64) (int64, error) {
+	is, ok := s.store.(kv.IdempotentStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	start := time.Now()
+	n, err := is.IncrementIdempotent(requestID, key, delta)
+	s.recordSet(start)
+	return n, err
+}
+
+// BatchIdempotent delegates to the wrapped store if it deduplicates writes.
+func (s *InstrumentedStore) BatchIdempotent(requestID string, ops []kv.BatchOp) error {
+	is, ok := s.store.(kv.IdempotentStore)
+	if !ok {
+		return kv.ErrNotSupported
+	}
+	return is.BatchIdempotent(requestID, ops)
+}
+
+// Oldest delegates to the wrapped store if it records key timestamps.
+func (s *InstrumentedStore) Oldest(prefix string, byUpdate bool) (string, kv.Entry, bool) {
+	as, ok := s.store.(kv.AgeStore)
+	if !ok {
+		return "", kv.Entry{}, false
+	}
+	return as.Oldest(prefix, byUpdate)
+}
+
+// Newest delegates to the wrapped store if it records key timestamps.
+func (s *InstrumentedStore) Newest(prefix string, byUpdate bool) (string, kv.Entry, bool) {
+	as, ok := s.store.(kv.AgeStore)
+	if !ok {
+		return "", kv.Entry{}, false
+	}
+	return as.Newest(prefix, byUpdate)
+}
+
+// NamespaceUsage delegates to the wrapped store if it tracks usage.
+// Stores without usage tracking report no namespaces.
+func (s *InstrumentedStore) NamespaceUsage() map[string]kv.Usage {
+	us, ok := s.store.(kv.UsageStore)
+	if !ok {
+		return nil
+	}
+	return us.NamespaceUsage()
+}
+
+// Size returns the wrapped store's key count and approximate bytes, and
+// false if it cannot report them cheaply.
+func (s *InstrumentedStore) Size() (keys int, bytes int64, ok bool) {
+	ss, ok := s.store.(kv.SizeStore)
+	if !ok {
+		return 0, 0, false
+	}
+	return ss.Len(), ss.ApproxBytes(), true
+}
+
+// Batch delegates to the wrapped store if it supports batches.
+func (s *InstrumentedStore) Batch(ops []kv.BatchOp) error {
+	bs, ok := s.store.(kv.BatchStore)
+	if !ok {
+		return kv.ErrNotSupported
+	}
+	return bs.Batch(ops)
+}
+
+// SetLabels delegates to the wrapped store if it supports labels.
+func (s *InstrumentedStore) SetLabels(key string, labels map[string]string) (bool, error) {
+	ls, ok := s.store.(kv.LabelStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	return ls.SetLabels(key, labels)
+}
+
+// GetWithMeta delegates to the wrapped store if it supports labels and
+// records timing as a get. Stores without labels report the key as missing.
+func (s *InstrumentedStore) GetWithMeta(key string) (kv.Entry, bool) {
+	ls, ok := s.store.(kv.LabelStore)
+	if !ok {
+		return kv.Entry{}, false
+	}
+	s.recordRead(key)
+	start := time.Now()
+	entry, found := ls.GetWithMeta(key)
+	s.metrics.GetCount.Add(1)
+	elapsed := uint64(time.Since(start).Nanoseconds())
+	s.metrics.GetLatencyNs.Add(elapsed)
+	s.metrics.GetLatency.Record(elapsed)
+	return entry, found
+}
+
+// ScanLabel delegates to the wrapped store if it supports labels.
+func (s *InstrumentedStore) ScanLabel(prefix, name, value string, limit int) ([]kv.KeyValue, error) {
+	ls, ok := s.store.(kv.LabelStore)
+	if !ok {
+		return nil, kv.ErrNotSupported
+	}
+	return ls.ScanLabel(prefix, name, value, limit)
+}
+
+func (s *InstrumentedStore) recordSet(start time.Time) {
+	s.metrics.SetCount.Add(1)
+	elapsed := uint64(time.Since(start).Nanoseconds())
+	s.metrics.SetLatencyNs.Add(elapsed)
+	s.metrics.SetLatency.Record(elapsed)
+}
+
 // Delete delegates to the wrapped store and records timing.
 func (s *InstrumentedStore) Delete(key string) error {
 	start := time.Now()
@@ -70,10 +477,29 @@ func (s *InstrumentedStore) Delete(key string) error {
 	
 	s.metrics.DeleteCount.Add(1)
 	s.metrics.DeleteLatencyNs.Add(uint64(elapsed))
+	s.metrics.DeleteLatency.Record(uint64(elapsed))
 	
 	return err
 }
 
+// Scan delegates to the wrapped store.
+func (s *InstrumentedStore) Scan(prefix string, limit int) ([]kv.KeyValue, error) {
+	return s.store.Scan(prefix, limit)
+}
+
+// MultiGet delegates to the wrapped store.
+func (s *InstrumentedStore) MultiGet(keys []string) (map[string]string, error) {
+	for _, key := range keys {
+		s.recordRead(key)
+	}
+	return s.store.MultiGet(keys)
+}
+
+// Exists delegates to the wrapped store.
+func (s *InstrumentedStore) Exists(key string) (bool, error) {
+	return s.store.Exists(key)
+}
+
 // GetMetrics returns a snapshot of current metrics.
 func (s *InstrumentedStore) GetMetrics() MetricsSnapshot {
 	getCount := s.metrics.GetCount.Load()
@@ -87,6 +513,45 @@ func (s *InstrumentedStore) GetMetrics() MetricsSnapshot {
 		GetAvgLatency:  s.avgLatency(s.metrics.GetLatencyNs.Load(), getCount),
 		SetAvgLatency:  s.avgLatency(s.metrics.SetLatencyNs.Load(), setCount),
 		DeleteAvgLatency: s.avgLatency(s.metrics.DeleteLatencyNs.Load(), deleteCount),
+		GetP50:           s.metrics.GetLatency.Quantile(0.50),
+		GetP95:           s.metrics.GetLatency.Quantile(0.95),
+		GetP99:           s.metrics.GetLatency.Quantile(0.99),
+		SetP50:           s.metrics.SetLatency.Quantile(0.50),
+		SetP95:           s.metrics.SetLatency.Quantile(0.95),
+		SetP99:           s.metrics.SetLatency.Quantile(0.99),
+		DeleteP50:        s.metrics.DeleteLatency.Quantile(0.50),
+		DeleteP95:        s.metrics.DeleteLatency.Quantile(0.95),
+		DeleteP99:        s.metrics.DeleteLatency.Quantile(0.99),
+	}
+}
+
+// GetMetricsAndReset returns a snapshot of current metrics and clears the
+// counters, so consecutive snapshots cover disjoint intervals. Operations
+// finishing mid-call may land in either interval.
+func (s *InstrumentedStore) GetMetricsAndReset() MetricsSnapshot {
+	getCount := s.metrics.GetCount.Swap(0)
+	setCount := s.metrics.SetCount.Swap(0)
+	deleteCount := s.metrics.DeleteCount.Swap(0)
+	getHist := s.metrics.GetLatency.swap()
+	setHist := s.metrics.SetLatency.swap()
+	deleteHist := s.metrics.DeleteLatency.swap()
+
+	return MetricsSnapshot{
+		GetCount:         getCount,
+		SetCount:         setCount,
+		DeleteCount:      deleteCount,
+		GetAvgLatency:    s.avgLatency(s.metrics.GetLatencyNs.Swap(0), getCount),
+		SetAvgLatency:    s.avgLatency(s.metrics.SetLatencyNs.Swap(0), setCount),
+		DeleteAvgLatency: s.avgLatency(s.metrics.DeleteLatencyNs.Swap(0), deleteCount),
+		GetP50:           getHist.quantile(0.50),
+		GetP95:           getHist.quantile(0.95),
+		GetP99:           getHist.quantile(0.99),
+		SetP50:           setHist.quantile(0.50),
+		SetP95:           setHist.quantile(0.95),
+		SetP99:           setHist.quantile(0.99),
+		DeleteP50:        deleteHist.quantile(0.50),
+		DeleteP95:        deleteHist.quantile(0.95),
+		DeleteP99:        deleteHist.quantile(0.99),
 	}
 }
 
@@ -98,6 +563,9 @@ func (s *InstrumentedStore) ResetMetrics() {
 	s.metrics.GetLatencyNs.Store(0)
 	s.metrics.SetLatencyNs.Store(0)
 	s.metrics.DeleteLatencyNs.Store(0)
+	s.metrics.GetLatency.swap()
+	s.metrics.SetLatency.swap()
+	s.metrics.DeleteLatency.swap()
 }
 
 func (s *InstrumentedStore) avgLatency(totalNs, count uint64) time.Duration {
@@ -115,4 +583,15 @@ type MetricsSnapshot struct {
 	GetAvgLatency    time.Duration
 	SetAvgLatency    time.Duration
 	DeleteAvgLatency time.Duration
+
+	// Latency percentiles, accurate to within about 6%
+	GetP50    time.Duration
+	GetP95    time.Duration
+	GetP99    time.Duration
+	SetP50    time.Duration
+	SetP95    time.Duration
+	SetP99    time.Duration
+	DeleteP50 time.Duration
+	DeleteP95 time.Duration
+	DeleteP99 time.Duration
 }
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/heysubinoy/pyazdb/pkg/kv"
+)
+
+func BenchmarkInstrumentedStore(b *testing.B) {
+	keys := make([]string, 4096)
+	for i := range keys {
+		keys[i] = "key/" + strconv.Itoa(i)
+	}
+	stores := []struct {
+		name  string
+		store func() kv.Store
+	}{
+		{"raw", func() kv.Store { return NewMemStore() }},
+		{"instrumented", func() kv.Store { return NewInstrumentedStore(NewMemStore()) }},
+	}
+	for _, st := range stores {
+		b.Run(st.name, func(b *testing.B) {
+			s := st.store()
+			for _, k := range keys {
+				s.Set(k, "v")
+			}
+			var worker atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				// Nine reads to every write, spread over all keys.
+				i := int(worker.Add(1)) * 997
+				for pb.Next() {
+					k := keys[i%len(keys)]
+					if i%10 == 0 {
+						s.Set(k, "v")
+					} else {
+						s.Get(k)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruList orders the keys of a bounded MemStore by recency of use. It is
+// shared by all shards and has its own mutex, which may be taken while a
+// shard lock is held but never the other way round.
+type lruList struct {
+	mu    sync.Mutex
+	order *list.List // of keys, most recently used at the front
+	elems map[string]*list.Element
+}
+
+func newLRUList() *lruList {
+	return &lruList{order: list.New(), elems: make(map[string]*list.Element)}
+}
+
+// touch marks key as just used, adding it if it is new.
+func (l *lruList) touch(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.elems[key]; ok {
+		l.order.MoveToFront(e)
+		return
+	}
+	l.elems[key] = l.order.PushFront(key)
+}
+
+// remove forgets key.
+func (l *lruList) remove(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.elems[key]; ok {
+		l.order.Remove(e)
+		delete(l.elems, key)
+	}
+}
+
+// overflow returns the least recently used key if more than max keys are
+// tracked.
+func (l *lruList) overflow(max int) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.order.Len() <= max {
+		return "", false
+	}
+	return l.order.Back().Value.(string), true
+}
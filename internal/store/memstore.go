@@ -1,54 +1,860 @@
 package store
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/heysubinoy/pyazdb/pkg/kv"
 )
 
+// shardCount is the number of independently locked partitions in a MemStore.
+const shardCount = 32
+
 // MemStore is an in-memory implementation of the kv.Store interface.
-// It uses a map protected by a RWMutex for thread-safe operations.
+// Keys are spread over shards selected by key hash, each protected by its
+// own RWMutex, so writes to different keys rarely contend on the same lock.
 type MemStore struct {
-	mu   sync.RWMutex
-	data map[string]string
+	shards [shardCount]*shard
+
+	// aliases maps alias names to their target, a key or another alias.
+	// aliasMu is taken before any shard lock.
+	aliasMu sync.RWMutex
+	aliases map[string]string
+
+	// lru, when set, caps the store at maxEntries keys; see NewMemStoreLRU.
+	lru        *lruList
+	maxEntries int
 }
 
-// Compile-time check to ensure MemStore implements kv.Store.
-var _ kv.Store = (*MemStore)(nil)
+// shard is one lock-protected partition of a MemStore.
+type shard struct {
+	mu         sync.RWMutex
+	data       map[string]string
+	expires    map[string]time.Time // absolute deadlines for keys with a TTL
+	tombstones map[string]time.Time // purge deadlines for soft-deleted keys
+	labels     map[string]map[string]string
+	usage      map[string]kv.Usage // per namespace, for the keys in this shard
+	times      map[string]keyTimes
+	lru        *lruList // the store's, or nil if it is unbounded
+}
+
+// keyTimes records when a key was first written and last overwritten.
+type keyTimes struct {
+	created time.Time
+	updated time.Time
+}
+
+// Compile-time checks to ensure MemStore implements kv.Store and the
+// optional store interfaces it supports natively.
+var (
+	_ kv.Store             = (*MemStore)(nil)
+	_ kv.TTLStore          = (*MemStore)(nil)
+	_ kv.LabelStore        = (*MemStore)(nil)
+	_ kv.BatchStore        = (*MemStore)(nil)
+	_ kv.UsageStore        = (*MemStore)(nil)
+	_ kv.SizeStore         = (*MemStore)(nil)
+	_ kv.AgeStore          = (*MemStore)(nil)
+	_ kv.ConditionalStore  = (*MemStore)(nil)
+	_ kv.CounterStore      = (*MemStore)(nil)
+	_ kv.DeleteReportStore = (*MemStore)(nil)
+	_ kv.AliasStore        = (*MemStore)(nil)
+)
 
 // NewMemStore creates and returns a new MemStore instance.
 func NewMemStore() *MemStore {
-	return &MemStore{
-		data: make(map[string]string),
+	s := &MemStore{aliases: make(map[string]string)}
+	for i := range s.shards {
+		s.shards[i] = &shard{
+			data:       make(map[string]string),
+			expires:    make(map[string]time.Time),
+			tombstones: make(map[string]time.Time),
+			labels:     make(map[string]map[string]string),
+			usage:      make(map[string]kv.Usage),
+			times:      make(map[string]keyTimes),
+		}
+	}
+	return s
+}
+
+// NewMemStoreLRU creates a MemStore holding at most maxEntries keys, for
+// cache use. Reads and writes mark a key as used, and a write that takes
+// the store past maxEntries evicts the least recently used keys.
+//
+// Eviction depends on each node's own reads and is not replicated, so a
+// bounded MemStore must not back a RaftStore: replicas would diverge.
+func NewMemStoreLRU(maxEntries int) *MemStore {
+	s := NewMemStore()
+	s.lru = newLRUList()
+	s.maxEntries = maxEntries
+	for _, sh := range s.shards {
+		sh.lru = s.lru
 	}
+	return s
 }
 
 // Get retrieves a value by key from the store.
 // Returns the value and true if found, empty string and false otherwise.
 func (s *MemStore) Get(key string) (string, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	sh := s.shardFor(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
 
-	val, ok := s.data[key]
+	if !sh.visible(key, time.Now()) {
+		return "", false
+	}
+	val, ok := sh.data[key]
+	if ok {
+		sh.used(key)
+	}
 	return val, ok
 }
 
+// MultiGet retrieves keys under one read lock of the store, so the values
+// form a consistent view and cost one lock acquisition per shard rather
+// than per key.
+func (s *MemStore) MultiGet(keys []string) (map[string]string, error) {
+	s.rlockAll()
+	defer s.runlockAll()
+
+	now := time.Now()
+	out := make(map[string]string, len(keys))
+	for _, key := range keys {
+		sh := s.shardFor(key)
+		if val, ok := sh.data[key]; ok && sh.visible(key, now) {
+			out[key] = val
+			sh.used(key)
+		}
+	}
+	return out, nil
+}
+
+// Exists reports whether key is present, under the same visibility rules as Get.
+func (s *MemStore) Exists(key string) (bool, error) {
+	return s.exists(key, time.Now()), nil
+}
+
 // Set stores a key-value pair in the store.
 // Always returns nil for in-memory operations.
 func (s *MemStore) Set(key, value string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.SetAt(key, value, time.Time{}, time.Now())
+	s.evict()
+	return nil
+}
+
+// SetWithTTL stores a key-value pair that expires after ttl.
+func (s *MemStore) SetWithTTL(key, value string, ttl time.Duration) error {
+	return s.SetWithExpiry(key, value, time.Now().Add(ttl))
+}
 
-	s.data[key] = value
+// SetWithExpiry stores a key-value pair that expires at the given absolute time.
+// Replicas apply the same deadline so they agree on when the key disappears.
+func (s *MemStore) SetWithExpiry(key, value string, expiresAt time.Time) error {
+	s.SetAt(key, value, expiresAt, time.Now())
+	s.evict()
 	return nil
 }
 
+// SetAt stores a key-value pair written at the given time, expiring at
+// expiresAt (zero for no TTL). Replicas pass the leader's append time so
+// they record identical created and updated timestamps.
+func (s *MemStore) SetAt(key, value string, expiresAt, at time.Time) {
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.put(key, value, at)
+	if expiresAt.IsZero() {
+		delete(sh.expires, key)
+	} else {
+		sh.expires[key] = expiresAt
+	}
+	delete(sh.tombstones, key)
+}
+
+// Touch resets the TTL of an existing key without changing its value.
+func (s *MemStore) Touch(key string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	return s.TouchWithExpiry(key, now.Add(ttl), now), nil
+}
+
+// TouchWithExpiry moves an existing key's deadline to expiresAt.
+// Returns false if the key is missing, expired at time at, or
+// soft-deleted. Replicas pass the leader's append time so they agree on
+// whether a key close to its deadline was still there.
+func (s *MemStore) TouchWithExpiry(key string, expiresAt, at time.Time) bool {
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if _, ok := sh.data[key]; !ok || !sh.visible(key, at) {
+		return false
+	}
+	sh.expires[key] = expiresAt
+	return true
+}
+
+// ExpirePrefix sets a TTL on every key starting with prefix.
+func (s *MemStore) ExpirePrefix(prefix string, ttl time.Duration) (int, error) {
+	now := time.Now()
+	return s.ExpirePrefixAt(prefix, now.Add(ttl), now), nil
+}
+
+// ExpirePrefixAt sets the deadline of every key starting with prefix and
+// visible at the given time to expiresAt, and returns how many keys were
+// affected.
+func (s *MemStore) ExpirePrefixAt(prefix string, expiresAt, at time.Time) int {
+	count := 0
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		for k := range sh.data {
+			if strings.HasPrefix(k, prefix) && sh.visible(k, at) {
+				sh.expires[k] = expiresAt
+				count++
+			}
+		}
+		sh.mu.Unlock()
+	}
+	return count
+}
+
 // Delete removes a key from the store.
 // Always returns nil, even if the key doesn't exist.
 func (s *MemStore) Delete(key string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.DeleteAt(key, time.Now())
+	return nil
+}
+
+// DeleteReport removes key and reports whether it was visible beforehand.
+// MemStore has no commit index, so the index is always 0.
+func (s *MemStore) DeleteReport(key string) (bool, uint64, error) {
+	return s.DeleteAt(key, time.Now()), 0, nil
+}
+
+// DeleteAt removes key and reports whether it was visible at the given
+// time, so replicas agree on whether it had already expired. Expired and
+// soft-deleted leftovers are removed too but don't count as existing.
+func (s *MemStore) DeleteAt(key string, at time.Time) bool {
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
 
-	delete(s.data, key)
+	_, ok := sh.data[key]
+	existed := ok && sh.visible(key, at)
+	sh.purge(key)
+	return existed
+}
+
+// Batch applies ops in order with every shard write-locked, so readers
+// never observe part of a batch. Callers are expected to have checked the
+// ops with kv.ValidateBatch; unknown ops are skipped.
+func (s *MemStore) Batch(ops []kv.BatchOp) error {
+	s.BatchAt(ops, time.Now())
+	s.evict()
 	return nil
 }
+
+// BatchAt is Batch with every set stamped as written at the given time.
+func (s *MemStore) BatchAt(ops []kv.BatchOp, at time.Time) {
+	s.lockAll()
+	defer s.unlockAll()
+
+	for _, op := range ops {
+		sh := s.shardFor(op.Key)
+		switch op.Op {
+		case "set":
+			sh.put(op.Key, op.Value, at)
+			delete(sh.expires, op.Key)
+			delete(sh.tombstones, op.Key)
+		case "delete":
+			sh.purge(op.Key)
+		}
+	}
+}
+
+// SetNXWithTTL stores a key-value pair expiring after ttl if the key is absent.
+func (s *MemStore) SetNXWithTTL(key, value string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	set := s.SetNXAt(key, value, now.Add(ttl), now)
+	s.evict()
+	return set, nil
+}
+
+// SetNXAt stores a key-value pair written at the given time and expiring at
+// expiresAt, unless a visible value already exists. An expired or
+// soft-deleted leftover counts as absent and is replaced entirely.
+// Returns whether the key was set.
+func (s *MemStore) SetNXAt(key, value string, expiresAt, at time.Time) bool {
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if _, ok := sh.data[key]; ok {
+		if sh.visible(key, at) {
+			return false
+		}
+		sh.purge(key)
+	}
+	sh.put(key, value, at)
+	sh.expires[key] = expiresAt
+	return true
+}
+
+// DeleteIf removes key if it is visible and holds value. Unlike a
+// soft-deleting Delete, the key is removed immediately.
+func (s *MemStore) DeleteIf(key, value string) (bool, error) {
+	return s.DeleteIfAt(key, value, time.Now()), nil
+}
+
+// DeleteIfAt is DeleteIf judged at the given time, so replicas agree on
+// whether the key had already expired.
+func (s *MemStore) DeleteIfAt(key, value string, at time.Time) bool {
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if v, ok := sh.data[key]; !ok || v != value || !sh.visible(key, at) {
+		return false
+	}
+	sh.purge(key)
+	return true
+}
+
+// SetAlias points alias at target, or removes alias if target is empty.
+func (s *MemStore) SetAlias(alias, target string) error {
+	return s.SetAliasAt(alias, target, time.Now())
+}
+
+// SetAliasAt is SetAlias with target's existence judged at the given time,
+// so replicas agree on whether it had already expired. An alias may not
+// share its name with an existing key.
+func (s *MemStore) SetAliasAt(alias, target string, at time.Time) error {
+	s.aliasMu.Lock()
+	defer s.aliasMu.Unlock()
+
+	if target == "" {
+		delete(s.aliases, alias)
+		return nil
+	}
+	if s.exists(alias, at) {
+		return fmt.Errorf("%q: %w", alias, kv.ErrAliasIsKey)
+	}
+	// The new alias adds one hop in front of target's chain.
+	if _, err := s.resolveLocked(target, alias, kv.MaxAliasDepth-1, at); err != nil {
+		return err
+	}
+	s.aliases[alias] = target
+	return nil
+}
+
+// ResolveAlias follows key's alias chain to the key it names.
+func (s *MemStore) ResolveAlias(key string) (string, error) {
+	s.aliasMu.RLock()
+	defer s.aliasMu.RUnlock()
+
+	if _, ok := s.aliases[key]; !ok {
+		return key, nil
+	}
+	return s.resolveLocked(key, "", kv.MaxAliasDepth, time.Now())
+}
+
+// resolveLocked follows at most maxHops aliases from key, failing if the
+// chain reaches avoid or ends at a key that doesn't exist at the given
+// time. Callers must hold s.aliasMu.
+func (s *MemStore) resolveLocked(key, avoid string, maxHops int, at time.Time) (string, error) {
+	for hops := 0; ; hops++ {
+		if key == avoid {
+			return "", kv.ErrAliasCycle
+		}
+		next, ok := s.aliases[key]
+		if !ok {
+			break
+		}
+		if hops == maxHops {
+			return "", kv.ErrAliasCycle
+		}
+		key = next
+	}
+	if !s.exists(key, at) {
+		return "", kv.ErrDanglingAlias
+	}
+	return key, nil
+}
+
+// exists reports whether key holds a visible value at the given time.
+func (s *MemStore) exists(key string, at time.Time) bool {
+	sh := s.shardFor(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	_, ok := sh.data[key]
+	return ok && sh.visible(key, at)
+}
+
+// CompareAndSwap replaces key's value with new if it currently equals old.
+func (s *MemStore) CompareAndSwap(key, old, new string) (bool, error) {
+	return s.CompareAndSwapAt(key, old, new, time.Now()), nil
+}
+
+// CompareAndSwapAt is CompareAndSwap judged and stamped at the given time.
+// Only the value changes; the key's TTL and labels are kept.
+func (s *MemStore) CompareAndSwapAt(key, old, new string, at time.Time) bool {
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if v, ok := sh.data[key]; !ok || v != old || !sh.visible(key, at) {
+		return false
+	}
+	sh.put(key, new, at)
+	return true
+}
+
+// Increment adds delta to key's integer value and returns the new total.
+func (s *MemStore) Increment(key string, delta int64) (int64, error) {
+	sum, err := s.IncrementAt(key, delta, time.Now())
+	s.evict()
+	return sum, err
+}
+
+// IncrementAt is Increment judged and stamped at the given time. A missing,
+// expired or soft-deleted key starts from 0 with no TTL; an existing key
+// keeps its TTL and labels.
+func (s *MemStore) IncrementAt(key string, delta int64, at time.Time) (int64, error) {
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	var current int64
+	if v, ok := sh.data[key]; ok {
+		if sh.visible(key, at) {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("incrementing %q: %w", key, kv.ErrNotInteger)
+			}
+			current = n
+		} else {
+			sh.purge(key)
+		}
+	}
+	sum := current + delta
+	if (delta > 0 && sum < current) || (delta < 0 && sum > current) {
+		return 0, fmt.Errorf("incrementing %q: %w", key, kv.ErrNotInteger)
+	}
+	sh.put(key, strconv.FormatInt(sum, 10), at)
+	return sum, nil
+}
+
+// Tombstone soft-deletes a key: it becomes invisible to reads but keeps its
+// value until purgeAt, and can be recovered with UndeleteAt before then.
+// Missing keys are left alone. Returns whether the key was visible at the
+// given time, i.e. whether the soft delete removed anything.
+func (s *MemStore) Tombstone(key string, purgeAt, at time.Time) bool {
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if _, ok := sh.data[key]; !ok {
+		return false
+	}
+	if _, ok := sh.tombstones[key]; ok {
+		return false
+	}
+	existed := !sh.expired(key, at)
+	sh.tombstones[key] = purgeAt
+	return existed
+}
+
+// UndeleteAt restores a soft-deleted key if its purge deadline is after at.
+// Returns true if the key was restored.
+func (s *MemStore) UndeleteAt(key string, at time.Time) bool {
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	purgeAt, ok := sh.tombstones[key]
+	if !ok || !at.Before(purgeAt) {
+		return false
+	}
+	delete(sh.tombstones, key)
+	return true
+}
+
+// SetLabels replaces the labels of a visible key. Labels survive value
+// overwrites and are dropped when the key is deleted or expires.
+// Callers are expected to have checked the labels with kv.ValidateLabels.
+func (s *MemStore) SetLabels(key string, labels map[string]string) (bool, error) {
+	return s.SetLabelsAt(key, labels, time.Now()), nil
+}
+
+// SetLabelsAt is SetLabels judged at the given time, so replicas agree on
+// whether a key close to its deadline could still be labeled.
+func (s *MemStore) SetLabelsAt(key string, labels map[string]string, at time.Time) bool {
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if _, ok := sh.data[key]; !ok || !sh.visible(key, at) {
+		return false
+	}
+	if len(labels) == 0 {
+		delete(sh.labels, key)
+		return true
+	}
+	sh.labels[key] = copyLabels(labels)
+	return true
+}
+
+// GetWithMeta retrieves a key's value, labels, expiry and timestamps.
+// The returned labels are a copy and safe to modify.
+func (s *MemStore) GetWithMeta(key string) (kv.Entry, bool) {
+	sh := s.shardFor(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	val, ok := sh.data[key]
+	if !ok || !sh.visible(key, time.Now()) {
+		return kv.Entry{}, false
+	}
+	sh.used(key)
+	t := sh.times[key]
+	entry := kv.Entry{Value: val, ExpiresAt: sh.expires[key], CreatedAt: t.created, UpdatedAt: t.updated}
+	entry.Labels = copyLabels(sh.labels[key])
+	return entry, true
+}
+
+// ScanLabel returns the visible pairs starting with prefix whose label
+// name equals value, sorted by key. Like Scan, it reads a consistent view.
+func (s *MemStore) ScanLabel(prefix, name, value string, limit int) ([]kv.KeyValue, error) {
+	s.rlockAll()
+	now := time.Now()
+	var pairs []kv.KeyValue
+	for _, sh := range s.shards {
+		for k, labels := range sh.labels {
+			if v, ok := labels[name]; ok && v == value && strings.HasPrefix(k, prefix) && sh.visible(k, now) {
+				pairs = append(pairs, kv.KeyValue{Key: k, Value: sh.data[k]})
+			}
+		}
+	}
+	s.runlockAll()
+
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	if limit > 0 && len(pairs) > limit {
+		pairs = pairs[:limit]
+	}
+	return pairs, nil
+}
+
+// Oldest returns the visible key under prefix with the earliest creation
+// time, or the earliest update time if byUpdate is set. It scans every
+// matching key, so it costs O(n) in the size of the keyspace.
+func (s *MemStore) Oldest(prefix string, byUpdate bool) (string, kv.Entry, bool) {
+	return s.extreme(prefix, byUpdate, func(a, b time.Time) bool { return a.Before(b) })
+}
+
+// Newest is like Oldest but returns the latest created or updated key.
+func (s *MemStore) Newest(prefix string, byUpdate bool) (string, kv.Entry, bool) {
+	return s.extreme(prefix, byUpdate, func(a, b time.Time) bool { return a.After(b) })
+}
+
+// extreme scans keys under prefix with all shards read-locked and returns
+// the one whose timestamp wins under better. Ties go to the smaller key.
+func (s *MemStore) extreme(prefix string, byUpdate bool, better func(a, b time.Time) bool) (string, kv.Entry, bool) {
+	s.rlockAll()
+	defer s.runlockAll()
+
+	now := time.Now()
+	var (
+		bestKey   string
+		bestShard *shard
+		bestAt    time.Time
+	)
+	for _, sh := range s.shards {
+		for k, t := range sh.times {
+			if !strings.HasPrefix(k, prefix) || !sh.visible(k, now) {
+				continue
+			}
+			at := t.created
+			if byUpdate {
+				at = t.updated
+			}
+			if bestShard == nil || better(at, bestAt) || (at.Equal(bestAt) && k < bestKey) {
+				bestKey, bestShard, bestAt = k, sh, at
+			}
+		}
+	}
+	if bestShard == nil {
+		return "", kv.Entry{}, false
+	}
+	t := bestShard.times[bestKey]
+	return bestKey, kv.Entry{
+		Value:     bestShard.data[bestKey],
+		Labels:    copyLabels(bestShard.labels[bestKey]),
+		ExpiresAt: bestShard.expires[bestKey],
+		CreatedAt: t.created,
+		UpdatedAt: t.updated,
+	}, true
+}
+
+// NamespaceUsage returns the keys and bytes held per namespace. Expired
+// and soft-deleted keys count until the sweeper purges them.
+func (s *MemStore) NamespaceUsage() map[string]kv.Usage {
+	s.rlockAll()
+	defer s.runlockAll()
+
+	total := make(map[string]kv.Usage)
+	for _, sh := range s.shards {
+		for ns, u := range sh.usage {
+			t := total[ns]
+			t.Keys += u.Keys
+			t.Bytes += u.Bytes
+			total[ns] = t
+		}
+	}
+	return total
+}
+
+// UsageOf returns the keys and bytes held in one namespace.
+func (s *MemStore) UsageOf(ns string) kv.Usage {
+	var total kv.Usage
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		u := sh.usage[ns]
+		sh.mu.RUnlock()
+		total.Keys += u.Keys
+		total.Bytes += u.Bytes
+	}
+	return total
+}
+
+// Len returns the number of keys held. Like NamespaceUsage it counts
+// expired and soft-deleted keys until the sweeper purges them.
+func (s *MemStore) Len() int {
+	n := 0
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		n += len(sh.data)
+		sh.mu.RUnlock()
+	}
+	return n
+}
+
+// ApproxBytes returns the summed length of every key and value held. It
+// adds up the per-namespace usage kept by put and purge rather than
+// walking the keys.
+func (s *MemStore) ApproxBytes() int64 {
+	var n int64
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		for _, u := range sh.usage {
+			n += u.Bytes
+		}
+		sh.mu.RUnlock()
+	}
+	return n
+}
+
+// storedLen returns the length of key's stored value, including values that
+// are expired or soft-deleted but not yet purged, and whether one exists.
+func (s *MemStore) storedLen(key string) (int, bool) {
+	sh := s.shardFor(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	v, ok := sh.data[key]
+	return len(v), ok
+}
+
+// Scan returns the pairs whose key starts with prefix, sorted by key.
+// All shards are read-locked for the duration of the copy, so the result
+// is a consistent snapshot unaffected by concurrent writes.
+func (s *MemStore) Scan(prefix string, limit int) ([]kv.KeyValue, error) {
+	s.rlockAll()
+	now := time.Now()
+	var pairs []kv.KeyValue
+	for _, sh := range s.shards {
+		for k, v := range sh.data {
+			if strings.HasPrefix(k, prefix) && sh.visible(k, now) {
+				pairs = append(pairs, kv.KeyValue{Key: k, Value: v})
+			}
+		}
+	}
+	s.runlockAll()
+
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	if limit > 0 && len(pairs) > limit {
+		pairs = pairs[:limit]
+	}
+	return pairs, nil
+}
+
+// RunSweeper purges expired keys and tombstones past their purge deadline
+// every interval, judged by the local clock. It is for a standalone
+// MemStore; one behind a RaftStore is swept by RaftStore.RunSweeper, so
+// purges agree with the log.
+func (s *MemStore) RunSweeper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	for range ticker.C {
+		s.sweep(time.Now())
+	}
+}
+
+// sweep purges the keys that are expired, and the tombstones that are past
+// their purge deadline, at now.
+func (s *MemStore) sweep(now time.Time) {
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		for k := range sh.expires {
+			if sh.expired(k, now) {
+				sh.purge(k)
+			}
+		}
+		for k, purgeAt := range sh.tombstones {
+			if !now.Before(purgeAt) {
+				sh.purge(k)
+			}
+		}
+		sh.mu.Unlock()
+	}
+}
+
+// evict purges least recently used keys until a bounded store is back
+// within maxEntries. Writes call it after releasing their shard lock, as
+// purging takes the evicted key's shard lock. A key read between being
+// picked and purged is still evicted; the bound is what matters.
+func (s *MemStore) evict() {
+	if s.lru == nil {
+		return
+	}
+	for {
+		key, ok := s.lru.overflow(s.maxEntries)
+		if !ok {
+			return
+		}
+		sh := s.shardFor(key)
+		sh.mu.Lock()
+		sh.purge(key)
+		sh.mu.Unlock()
+	}
+}
+
+// shardFor returns the shard owning key, chosen by its FNV-1a hash.
+func (s *MemStore) shardFor(key string) *shard {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return s.shards[h%shardCount]
+}
+
+// rlockAll read-locks every shard in a fixed order for a store-wide
+// consistent view. Writers to any shard block until runlockAll.
+func (s *MemStore) rlockAll() {
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+	}
+}
+
+func (s *MemStore) runlockAll() {
+	for _, sh := range s.shards {
+		sh.mu.RUnlock()
+	}
+}
+
+// lockAll write-locks every shard in the same fixed order as rlockAll.
+func (s *MemStore) lockAll() {
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+	}
+}
+
+func (s *MemStore) unlockAll() {
+	for _, sh := range s.shards {
+		sh.mu.Unlock()
+	}
+}
+
+// copyLabels returns a copy of labels, or nil if there are none.
+func copyLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	copied := make(map[string]string, len(labels))
+	for k, v := range labels {
+		copied[k] = v
+	}
+	return copied
+}
+
+// put stores value under key, written at the given time, and updates the
+// namespace usage and the key's timestamps.
+// Callers must hold sh.mu.
+func (sh *shard) put(key, value string, at time.Time) {
+	ns := kv.NamespaceOf(key)
+	u := sh.usage[ns]
+	t := sh.times[key]
+	if old, ok := sh.data[key]; ok {
+		u.Bytes += int64(len(value) - len(old))
+	} else {
+		u.Keys++
+		u.Bytes += int64(len(key) + len(value))
+		t.created = at
+	}
+	t.updated = at
+	sh.usage[ns] = u
+	sh.times[key] = t
+	sh.data[key] = value
+	sh.used(key)
+}
+
+// purge removes every trace of key from the shard.
+// Callers must hold sh.mu.
+func (sh *shard) purge(key string) {
+	if old, ok := sh.data[key]; ok {
+		ns := kv.NamespaceOf(key)
+		u := sh.usage[ns]
+		u.Keys--
+		u.Bytes -= int64(len(key) + len(old))
+		if u.Keys == 0 {
+			delete(sh.usage, ns)
+		} else {
+			sh.usage[ns] = u
+		}
+	}
+	delete(sh.data, key)
+	delete(sh.expires, key)
+	delete(sh.tombstones, key)
+	delete(sh.labels, key)
+	delete(sh.times, key)
+	if sh.lru != nil {
+		sh.lru.remove(key)
+	}
+}
+
+// used marks key as just used in a bounded store.
+// Callers must hold sh.mu, for reading or writing.
+func (sh *shard) used(key string) {
+	if sh.lru != nil {
+		sh.lru.touch(key)
+	}
+}
+
+// visible reports whether key is neither expired nor soft-deleted.
+// Callers must hold sh.mu.
+func (sh *shard) visible(key string, now time.Time) bool {
+	if _, ok := sh.tombstones[key]; ok {
+		return false
+	}
+	return !sh.expired(key, now)
+}
+
+// expired reports whether key has a deadline at or before now.
+// Callers must hold sh.mu.
+func (sh *shard) expired(key string, now time.Time) bool {
+	exp, ok := sh.expires[key]
+	return ok && !now.Before(exp)
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Replicas apply commands long after the leader appended them, so every
+// *At method must judge expiry at the time it is given, not the local clock.
+func TestMemStoreJudgesExpiryAtAppendTime(t *testing.T) {
+	base := time.Now().Add(-time.Hour)
+	deadline := base.Add(time.Minute)
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"before deadline", base.Add(30 * time.Second), true},
+		{"after deadline", base.Add(2 * time.Minute), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemStore()
+			s.SetAt("k", "v", deadline, base)
+			if got := s.TouchWithExpiry("k", tt.at.Add(time.Hour), tt.at); got != tt.want {
+				t.Errorf("TouchWithExpiry = %v, want %v", got, tt.want)
+			}
+
+			s = NewMemStore()
+			s.SetAt("p/a", "v", deadline, base)
+			s.SetAt("p/b", "v", time.Time{}, base)
+			want := 1
+			if tt.want {
+				want = 2
+			}
+			if got := s.ExpirePrefixAt("p/", tt.at.Add(time.Hour), tt.at); got != want {
+				t.Errorf("ExpirePrefixAt = %d, want %d", got, want)
+			}
+
+			s = NewMemStore()
+			s.SetAt("k", "v", deadline, base)
+			if got := s.SetLabelsAt("k", map[string]string{"env": "prod"}, tt.at); got != tt.want {
+				t.Errorf("SetLabelsAt = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// serialized puts one lock around a whole MemStore, so every write waits
+// for every other as they did before MemStore was sharded; it is the
+// baseline for BenchmarkMemStoreConcurrentSet.
+type serialized struct {
+	mu sync.Mutex
+	s  *MemStore
+}
+
+func (l *serialized) Set(key, value string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.s.Set(key, value)
+}
+
+// BenchmarkMemStoreConcurrentSet compares sharded and serialized writes to
+// distinct keys. Sharding only pays off with several CPUs; compare with
+// -cpu 1,4,8.
+func BenchmarkMemStoreConcurrentSet(b *testing.B) {
+	keys := make([]string, 4096)
+	for i := range keys {
+		keys[i] = "key/" + strconv.Itoa(i)
+	}
+	stores := []struct {
+		name string
+		set  func(key, value string) error
+	}{
+		{"sharded", NewMemStore().Set},
+		{"single-lock", (&serialized{s: NewMemStore()}).Set},
+	}
+	for _, st := range stores {
+		b.Run(st.name, func(b *testing.B) {
+			var worker atomic.Int64
+			b.RunParallel(func(pb *testing.PB) {
+				// Each goroutine writes its own stride of keys, so writers
+				// only contend where they share a lock.
+				i := int(worker.Add(1)) * 997
+				for pb.Next() {
+					st.set(keys[i%len(keys)], "v")
+					i++
+				}
+			})
+		})
+	}
+}
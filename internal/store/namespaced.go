@@ -0,0 +1,404 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/heysubinoy/pyazdb/pkg/kv"
+)
+
+// NamespacedStore wraps a kv.Store and confines it to one namespace: every
+// key is stored as namespace + kv.NamespaceSeparator + key, and keys read
+// back (from Scan, watches, aliases) have that prefix stripped again, so
+// callers never see it. Namespaced keys are ordinary keys in the wrapped
+// store, which is why namespace quotas apply to them and why an
+// unnamespaced Scan of the whole keyspace lists them with their prefix.
+//
+// A NamespacedStore is cheap and is meant to be created per request.
+type NamespacedStore struct {
+	store  kv.Store
+	prefix string
+}
+
+// Compile-time checks to ensure NamespacedStore implements kv.Store and
+// forwards the optional store interfaces.
+var (
+	_ kv.Store             = (*NamespacedStore)(nil)
+	_ kv.TTLStore          = (*NamespacedStore)(nil)
+	_ kv.ReplicatedStore   = (*NamespacedStore)(nil)
+	_ kv.UndeleteStore     = (*NamespacedStore)(nil)
+	_ kv.IndexedStore      = (*NamespacedStore)(nil)
+	_ kv.LabelStore        = (*NamespacedStore)(nil)
+	_ kv.BatchStore        = (*NamespacedStore)(nil)
+	_ kv.UsageStore        = (*NamespacedStore)(nil)
+	_ kv.AgeStore          = (*NamespacedStore)(nil)
+	_ kv.ConditionalStore  = (*NamespacedStore)(nil)
+	_ kv.CounterStore      = (*NamespacedStore)(nil)
+	_ kv.DeleteReportStore = (*NamespacedStore)(nil)
+	_ kv.ContextStore      = (*NamespacedStore)(nil)
+	_ kv.WatchStore        = (*NamespacedStore)(nil)
+	_ kv.AliasStore        = (*NamespacedStore)(nil)
+	_ kv.IdempotentStore   = (*NamespacedStore)(nil)
+)
+
+// NewNamespacedStore wraps a store so it only sees keys in namespace.
+// The namespace must be non-empty and must not contain
+// kv.NamespaceSeparator, or namespaces could overlap.
+func NewNamespacedStore(store kv.Store, namespace string) *NamespacedStore {
+	return &NamespacedStore{store: store, prefix: namespace + kv.NamespaceSeparator}
+}
+
+// key returns the wrapped store's name for key.
+func (s *NamespacedStore) key(key string) string {
+	return s.prefix + key
+}
+
+// strip returns the caller's name for a key of the wrapped store.
+func (s *NamespacedStore) strip(key string) string {
+	return strings.TrimPrefix(key, s.prefix)
+}
+
+// stripPairs strips the namespace from every key in pairs, in place.
+func (s *NamespacedStore) stripPairs(pairs []kv.KeyValue) []kv.KeyValue {
+	for i := range pairs {
+		pairs[i].Key = s.strip(pairs[i].Key)
+	}
+	return pairs
+}
+
+// Get retrieves the value for the namespaced key.
+func (s *NamespacedStore) Get(key string) (string, bool) {
+	return s.store.Get(s.key(key))
+}
+
+// MultiGet retrieves the namespaced keys; the result is keyed by the keys
+// as given.
+func (s *NamespacedStore) MultiGet(keys []string) (map[string]string, error) {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = s.key(key)
+	}
+	values, err := s.store.MultiGet(prefixed)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(values))
+	for i, key := range keys {
+		if v, ok := values[prefixed[i]]; ok {
+			out[key] = v
+		}
+	}
+	return out, nil
+}
+
+// Exists checks the namespaced key.
+func (s *NamespacedStore) Exists(key string) (bool, error) {
+	return s.store.Exists(s.key(key))
+}
+
+// Set stores the value under the namespaced key.
+func (s *NamespacedStore) Set(key, value string) error {
+	return s.store.Set(s.key(key), value)
+}
+
+// Delete removes the namespaced key.
+func (s *NamespacedStore) Delete(key string) error {
+	return s.store.Delete(s.key(key))
+}
+
+// Scan returns the pairs under prefix within the namespace, with the
+// namespace stripped from their keys.
+func (s *NamespacedStore) Scan(prefix string, limit int) ([]kv.KeyValue, error) {
+	pairs, err := s.store.Scan(s.key(prefix), limit)
+	return s.stripPairs(pairs), err
+}
+
+// SetWithTTL delegates to the wrapped store if it supports TTLs.
+func (s *NamespacedStore) SetWithTTL(key, value string, ttl time.Duration) error {
+	ts, ok := s.store.(kv.TTLStore)
+	if !ok {
+		return kv.ErrNotSupported
+	}
+	return ts.SetWithTTL(s.key(key), value, ttl)
+}
+
+// Touch delegates to the wrapped store if it supports TTLs.
+func (s *NamespacedStore) Touch(key string, ttl time.Duration) (bool, error) {
+	ts, ok := s.store.(kv.TTLStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	return ts.Touch(s.key(key), ttl)
+}
+
+// ExpirePrefix delegates to the wrapped store if it supports TTLs.
+func (s *NamespacedStore) ExpirePrefix(prefix string, ttl time.Duration) (int, error) {
+	ts, ok := s.store.(kv.TTLStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	return ts.ExpirePrefix(s.key(prefix), ttl)
+}
+
+// SetReplicated delegates to the wrapped store if it supports replica acks.
+func (s *NamespacedStore) SetReplicated(key, value string, replicas int) (int, error) {
+	rs, ok := s.store.(kv.ReplicatedStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	return rs.SetReplicated(s.key(key), value, replicas)
+}
+
+// Undelete delegates to the wrapped store if it supports soft deletes.
+func (s *NamespacedStore) Undelete(key string) (bool, error) {
+	us, ok := s.store.(kv.UndeleteStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	return us.Undelete(s.key(key))
+}
+
+// SetIndexed delegates to the wrapped store if it reports commit indexes.
+func (s *NamespacedStore) SetIndexed(key, value string) (uint64, error) {
+	is, ok := s.store.(kv.IndexedStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	return is.SetIndexed(s.key(key), value)
+}
+
+// DeleteIndexed delegates to the wrapped store if it reports commit indexes.
+func (s *NamespacedStore) DeleteIndexed(key string) (uint64, error) {
+	is, ok := s.store.(kv.IndexedStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	return is.DeleteIndexed(s.key(key))
+}
+
+// DeleteReport delegates to the wrapped store if it reports whether deleted
+// keys existed.
+func (s *NamespacedStore) DeleteReport(key string) (bool, uint64, error) {
+	ds, ok := s.store.(kv.DeleteReportStore)
+	if !ok {
+		return false, 0, kv.ErrNotSupported
+	}
+	return ds.DeleteReport(s.key(key))
+}
+
+// SetCtx delegates to the wrapped store if it supports cancellable writes.
+func (s *NamespacedStore) SetCtx(ctx context.Context, key, value string) (uint64, error) {
+	cs, ok := s.store.(kv.ContextStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	return cs.SetCtx(ctx, s.key(key), value)
+}
+
+// DeleteCtx delegates to the wrapped store if it supports cancellable writes.
+func (s *NamespacedStore) DeleteCtx(ctx context.Context, key string) (bool, uint64, error) {
+	cs, ok := s.store.(kv.ContextStore)
+	if !ok {
+		return false, 0, kv.ErrNotSupported
+	}
+	return cs.DeleteCtx(ctx, s.key(key))
+}
+
+// SetNXWithTTL delegates to the wrapped store if it supports conditional writes.
+func (s *NamespacedStore) SetNXWithTTL(key, value string, ttl time.Duration) (bool, error) {
+	cs, ok := s.store.(kv.ConditionalStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	return cs.SetNXWithTTL(s.key(key), value, ttl)
+}
+
+// DeleteIf delegates to the wrapped store if it supports conditional writes.
+func (s *NamespacedStore) DeleteIf(key, value string) (bool, error) {
+	cs, ok := s.store.(kv.ConditionalStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	return cs.DeleteIf(s.key(key), value)
+}
+
+// Watch delegates to the wrapped store if it supports watches, with the
+// watched key or prefix namespaced and the namespace stripped from the
+// events' keys.
+func (s *NamespacedStore) Watch(ctx context.Context, opts kv.WatchOptions) (<-chan kv.Event, error) {
+	ws, ok := s.store.(kv.WatchStore)
+	if !ok {
+		return nil, kv.ErrNotSupported
+	}
+	if opts.Key != "" {
+		opts.Key = s.key(opts.Key)
+	} else {
+		opts.Prefix = s.key(opts.Prefix)
+	}
+	events, err := ws.Watch(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan kv.Event)
+	go func() {
+		defer close(out)
+		for ev := range events {
+			ev.Key = s.strip(ev.Key)
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// SetAlias delegates to the wrapped store if it supports aliases. Both the
+// alias and its target live in the namespace.
+func (s *NamespacedStore) SetAlias(alias, target string) error {
+	as, ok := s.store.(kv.AliasStore)
+	if !ok {
+		return kv.ErrNotSupported
+	}
+	return as.SetAlias(s.key(alias), s.key(target))
+}
+
+// ResolveAlias delegates to the wrapped store if it supports aliases.
+// Without alias support every key names itself.
+func (s *NamespacedStore) ResolveAlias(key string) (string, error) {
+	as, ok := s.store.(kv.AliasStore)
+	if !ok {
+		return key, nil
+	}
+	resolved, err := as.ResolveAlias(s.key(key))
+	return s.strip(resolved), err
+}
+
+// CompareAndSwap delegates to the wrapped store if it supports conditional writes.
+func (s *NamespacedStore) CompareAndSwap(key, old, new string) (bool, error) {
+	cs, ok := s.store.(kv.ConditionalStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	return cs.CompareAndSwap(s.key(key), old, new)
+}
+
+// Increment delegates to the wrapped store if it supports counters.
+func (s *NamespacedStore) Increment(key string, delta int64) (int64, error) {
+	cs, ok := s.store.(kv.CounterStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	return cs.Increment(s.key(key), delta)
+}
+
+// SetIdempotent delegates to the wrapped store if it deduplicates writes.
+// Request IDs are namespaced like keys, so two namespaces picking the same
+// ID don't collide.
+func (s *NamespacedStore) SetIdempotent(ctx context.Context, requestID, key, value string) (uint64, error) {
+	is, ok := s.store.(kv.IdempotentStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	return is.SetIdempotent(ctx, s.key(requestID), s.key(key), value)
+}
+
+// IncrementIdempotent delegates to the wrapped store if it deduplicates
+// writes, with the request ID namespaced like SetIdempotent's.
+func (s *NamespacedStore) IncrementIdempotent(requestID, key string, delta int64) (int64, error) {
+	is, ok := s.store.(kv.IdempotentStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	return is.IncrementIdempotent(s.key(requestID), s.key(key), delta)
+}
+
+// BatchIdempotent delegates to the wrapped store if it deduplicates
+// writes, with the request ID namespaced like SetIdempotent's.
+func (s *NamespacedStore) BatchIdempotent(requestID string, ops []kv.BatchOp) error {
+	is, ok := s.store.(kv.IdempotentStore)
+	if !ok {
+		return kv.ErrNotSupported
+	}
+	prefixed := make([]kv.BatchOp, len(ops))
+	for i, op := range ops {
+		prefixed[i] = kv.BatchOp{Op: op.Op, Key: s.key(op.Key), Value: op.Value}
+	}
+	return is.BatchIdempotent(s.key(requestID), prefixed)
+}
+
+// Oldest delegates to the wrapped store if it records key timestamps.
+func (s *NamespacedStore) Oldest(prefix string, byUpdate bool) (string, kv.Entry, bool) {
+	as, ok := s.store.(kv.AgeStore)
+	if !ok {
+		return "", kv.Entry{}, false
+	}
+	key, entry, found := as.Oldest(s.key(prefix), byUpdate)
+	return s.strip(key), entry, found
+}
+
+// Newest delegates to the wrapped store if it records key timestamps.
+func (s *NamespacedStore) Newest(prefix string, byUpdate bool) (string, kv.Entry, bool) {
+	as, ok := s.store.(kv.AgeStore)
+	if !ok {
+		return "", kv.Entry{}, false
+	}
+	key, entry, found := as.Newest(s.key(prefix), byUpdate)
+	return s.strip(key), entry, found
+}
+
+// NamespaceUsage delegates to the wrapped store if it tracks usage. It
+// reports every namespace, not just this one, as usage is a node-wide
+// figure.
+func (s *NamespacedStore) NamespaceUsage() map[string]kv.Usage {
+	us, ok := s.store.(kv.UsageStore)
+	if !ok {
+		return nil
+	}
+	return us.NamespaceUsage()
+}
+
+// Batch delegates to the wrapped store if it supports batches, with every
+// op's key namespaced.
+func (s *NamespacedStore) Batch(ops []kv.BatchOp) error {
+	bs, ok := s.store.(kv.BatchStore)
+	if !ok {
+		return kv.ErrNotSupported
+	}
+	prefixed := make([]kv.BatchOp, len(ops))
+	for i, op := range ops {
+		prefixed[i] = kv.BatchOp{Op: op.Op, Key: s.key(op.Key), Value: op.Value}
+	}
+	return bs.Batch(prefixed)
+}
+
+// SetLabels delegates to the wrapped store if it supports labels.
+func (s *NamespacedStore) SetLabels(key string, labels map[string]string) (bool, error) {
+	ls, ok := s.store.(kv.LabelStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	return ls.SetLabels(s.key(key), labels)
+}
+
+// GetWithMeta delegates to the wrapped store if it supports labels.
+func (s *NamespacedStore) GetWithMeta(key string) (kv.Entry, bool) {
+	ls, ok := s.store.(kv.LabelStore)
+	if !ok {
+		return kv.Entry{}, false
+	}
+	return ls.GetWithMeta(s.key(key))
+}
+
+// ScanLabel delegates to the wrapped store if it supports labels, with the
+// namespace stripped from the keys returned.
+func (s *NamespacedStore) ScanLabel(prefix, name, value string, limit int) ([]kv.KeyValue, error) {
+	ls, ok := s.store.(kv.LabelStore)
+	if !ok {
+		return nil, kv.ErrNotSupported
+	}
+	pairs, err := ls.ScanLabel(s.key(prefix), name, value, limit)
+	return s.stripPairs(pairs), err
+}
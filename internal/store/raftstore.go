@@ -1,12 +1,29 @@
 package store
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/raft"
+	"github.com/heysubinoy/pyazdb/pkg/kv"
 )
 
+// DefaultMaxEntryBytes is the largest encoded command RaftStore proposes
+// unless SetMaxEntryBytes says otherwise.
+const DefaultMaxEntryBytes = 4 << 20
+
+// DefaultApplyTimeout is how long a write waits to be committed unless
+// SetApplyTimeout says otherwise.
+const DefaultApplyTimeout = 5 * time.Second
+
 // GetRaft returns the underlying raft.Raft pointer (for API layer leader checks)
 func (rs *RaftStore) GetRaft() *raft.Raft {
 	return rs.raft
@@ -14,62 +31,862 @@ func (rs *RaftStore) GetRaft() *raft.Raft {
 
 // RaftCommand represents a set/delete operation to be applied via Raft.
 type RaftCommand struct {
-	Op    string // "set" or "delete"
-	Key   string
-	Value string // only for set
+	Op        string            // "set", "delete", "softdelete", "undelete", "touch", "expireprefix", "label", "batch", "setnx", "deleteif", "alias", "cas" or "incr"
+	Key       string            // the key prefix for expireprefix; the alias name for alias
+	Value     string            // set, setnx and cas (the new value); the expected value for deleteif; the target for alias
+	Old       string            `json:",omitempty"` // only for cas: the expected current value
+	Delta     int64             `json:",omitempty"` // only for incr
+	ExpiresAt int64             // set/touch/expireprefix: expiry, softdelete: purge deadline; unix milliseconds, 0 = none
+	Labels    map[string]string `json:",omitempty"` // only for label
+	Ops       []kv.BatchOp      `json:",omitempty"` // only for batch
+	RequestID string            `json:",omitempty"` // set, incr and batch: a repeat of a recent ID is not applied again
+}
+
+// knownOps lists the ops Apply understands. ApplyRaw rejects anything else
+// so debugging tools cannot append entries that replicas would misapply.
+var knownOps = map[string]bool{
+	"set": true, "delete": true, "softdelete": true,
+	"undelete": true, "touch": true, "expireprefix": true, "label": true,
+	"batch": true, "setnx": true, "deleteif": true, "alias": true, "cas": true,
+	"incr": true,
 }
 
+// ErrUnknownOp is returned by ApplyRaw for a command Apply does not handle.
+var ErrUnknownOp = errors.New("unknown raft command op")
+
 // RaftStore wraps a Store and applies changes via Raft consensus.
 type RaftStore struct {
-	store *MemStore
-	raft  *raft.Raft
+	store               *MemStore
+	raft                *raft.Raft
+	ttlJitterPercent    int
+	softDeleteWindow    time.Duration
+	snapshotCompression string
+	quotas              map[string]NamespaceQuota
+	maxEntryBytes       int
+	applyTimeout        time.Duration
+
+	// tracker, when set, reports which followers hold an entry; see
+	// SetReplicated.
+	tracker *ReplicationTracker
+
+	// applyMu orders Apply against Watch, so a watch's initial snapshot
+	// and its live events meet at exactly lastApplied. lastAppliedAt is
+	// when the leader appended that entry, which bounds RunSweeper.
+	applyMu       sync.RWMutex
+	lastApplied   uint64
+	lastAppliedAt time.Time
+	watchers      *watchHub
+
+	// requests remembers recent request IDs so retried writes are applied
+	// once. It is part of the FSM state and travels in snapshots.
+	requests *requestLog
+}
+
+// NamespaceQuota bounds the keys and bytes (keys plus values) a namespace
+// may hold. Zero means unlimited.
+type NamespaceQuota struct {
+	MaxKeys  int64
+	MaxBytes int64
 }
 
+// Compile-time checks to ensure RaftStore implements the optional store interfaces.
+var (
+	_ kv.ReplicatedStore   = (*RaftStore)(nil)
+	_ kv.TTLStore          = (*RaftStore)(nil)
+	_ kv.UndeleteStore     = (*RaftStore)(nil)
+	_ kv.IndexedStore      = (*RaftStore)(nil)
+	_ kv.LabelStore        = (*RaftStore)(nil)
+	_ kv.BatchStore        = (*RaftStore)(nil)
+	_ kv.UsageStore        = (*RaftStore)(nil)
+	_ kv.SizeStore         = (*RaftStore)(nil)
+	_ kv.AgeStore          = (*RaftStore)(nil)
+	_ kv.ConditionalStore  = (*RaftStore)(nil)
+	_ kv.CounterStore      = (*RaftStore)(nil)
+	_ kv.DeleteReportStore = (*RaftStore)(nil)
+	_ kv.ContextStore      = (*RaftStore)(nil)
+	_ kv.WatchStore        = (*RaftStore)(nil)
+	_ kv.AliasStore        = (*RaftStore)(nil)
+	_ kv.IdempotentStore   = (*RaftStore)(nil)
+)
+
 func NewRaftStore(store *MemStore, r *raft.Raft) *RaftStore {
-	return &RaftStore{store: store, raft: r}
+	return &RaftStore{store: store, raft: r, maxEntryBytes: DefaultMaxEntryBytes, applyTimeout: DefaultApplyTimeout, watchers: newWatchHub(), requests: newRequestLog()}
+}
+
+// SetRaft attaches the raft instance commands are proposed to. It lets a
+// RaftStore be created first and passed to raft.NewRaft as the FSM.
+func (rs *RaftStore) SetRaft(r *raft.Raft) {
+	rs.raft = r
 }
 
-// Apply applies a Raft log entry to the local store.
+// Apply applies a Raft log entry to the local store and notifies watchers.
+// An entry repeating a remembered request ID changes nothing and returns
+// the first entry's appliedRequest instead.
 func (rs *RaftStore) Apply(log *raft.Log) interface{} {
 	var cmd RaftCommand
 	if err := json.Unmarshal(log.Data, &cmd); err != nil {
 		return err
 	}
+	rs.applyMu.Lock()
+	defer rs.applyMu.Unlock()
+	if cmd.RequestID != "" {
+		if prev, ok := rs.requests.get(cmd.RequestID); ok {
+			rs.lastApplied, rs.lastAppliedAt = log.Index, appendedAt(log)
+			return prev
+		}
+	}
+	resp := rs.applyCommand(log, cmd)
+	rs.lastApplied, rs.lastAppliedAt = log.Index, appendedAt(log)
+	// Failed writes changed nothing, so a retry may as well run again.
+	if _, failed := resp.(error); cmd.RequestID != "" && !failed {
+		sum, _ := resp.(int64)
+		rs.requests.add(appliedRequest{ID: cmd.RequestID, Index: log.Index, Sum: sum})
+	}
+	rs.watchers.publish(rs.events(log.Index, cmd, resp))
+	return resp
+}
+
+// outcome returns the index f's write took effect at and Apply's response
+// to it. For a repeated request ID these are the first write's, with the
+// response for incr rebuilt from the recorded sum.
+func outcome(f raft.ApplyFuture) (uint64, interface{}) {
+	if prev, ok := f.Response().(appliedRequest); ok {
+		return prev.Index, prev.Sum
+	}
+	return f.Index(), f.Response()
+}
+
+// applyCommand applies one decoded command to the local store.
+func (rs *RaftStore) applyCommand(log *raft.Log, cmd RaftCommand) interface{} {
 	switch cmd.Op {
 	case "set":
-		rs.store.Set(cmd.Key, cmd.Value)
+		var expiresAt time.Time
+		if cmd.ExpiresAt != 0 {
+			expiresAt = time.UnixMilli(cmd.ExpiresAt)
+		}
+		rs.store.SetAt(cmd.Key, cmd.Value, expiresAt, appendedAt(log))
 	case "delete":
-		rs.store.Delete(cmd.Key)
+		// Existence is judged here, against the applied state, so the
+		// answer is the same on every replica.
+		return rs.store.DeleteAt(cmd.Key, appendedAt(log))
+	case "touch":
+		return rs.store.TouchWithExpiry(cmd.Key, time.UnixMilli(cmd.ExpiresAt), appendedAt(log))
+	case "expireprefix":
+		return rs.store.ExpirePrefixAt(cmd.Key, time.UnixMilli(cmd.ExpiresAt), appendedAt(log))
+	case "setnx":
+		return rs.store.SetNXAt(cmd.Key, cmd.Value, time.UnixMilli(cmd.ExpiresAt), appendedAt(log))
+	case "deleteif":
+		return rs.store.DeleteIfAt(cmd.Key, cmd.Value, appendedAt(log))
+	case "cas":
+		return rs.store.CompareAndSwapAt(cmd.Key, cmd.Old, cmd.Value, appendedAt(log))
+	case "incr":
+		// The current value is parsed here, against the applied state, so
+		// concurrent increments never lose an update.
+		sum, err := rs.store.IncrementAt(cmd.Key, cmd.Delta, appendedAt(log))
+		if err != nil {
+			return err
+		}
+		return sum
+	case "batch":
+		rs.store.BatchAt(cmd.Ops, appendedAt(log))
+	case "alias":
+		// Cycle and existence checks run here, against the applied state,
+		// so every replica accepts or rejects the alias alike.
+		return rs.store.SetAliasAt(cmd.Key, cmd.Value, appendedAt(log))
+	case "label":
+		return rs.store.SetLabelsAt(cmd.Key, cmd.Labels, appendedAt(log))
+	case "softdelete":
+		return rs.store.Tombstone(cmd.Key, time.UnixMilli(cmd.ExpiresAt), appendedAt(log))
+	case "undelete":
+		// AppendedAt is stamped by the leader, so every replica makes the
+		// same decision about whether the grace period has passed.
+		return rs.store.UndeleteAt(cmd.Key, appendedAt(log))
 	}
 	return nil
 }
 
-// Snapshot and Restore are required for raft.FSM, but can be no-ops for in-memory.
-func (rs *RaftStore) Snapshot() (raft.FSMSnapshot, error) { return &noopSnapshot{}, nil }
-func (rs *RaftStore) Restore(io.ReadCloser) error         { return nil }
+// events returns the watch events for an applied command. Touches, label
+// changes and prefix expiries don't change values and emit nothing.
+func (rs *RaftStore) events(index uint64, cmd RaftCommand, resp interface{}) []kv.Event {
+	changed, _ := resp.(bool)
+	switch cmd.Op {
+	case "set":
+		return []kv.Event{{Type: kv.EventSet, Key: cmd.Key, Value: cmd.Value, Index: index}}
+	case "delete", "softdelete":
+		return []kv.Event{{Type: kv.EventDelete, Key: cmd.Key, Index: index}}
+	case "setnx", "cas":
+		if changed {
+			return []kv.Event{{Type: kv.EventSet, Key: cmd.Key, Value: cmd.Value, Index: index}}
+		}
+	case "deleteif":
+		if changed {
+			return []kv.Event{{Type: kv.EventDelete, Key: cmd.Key, Index: index}}
+		}
+	case "incr":
+		if sum, ok := resp.(int64); ok {
+			return []kv.Event{{Type: kv.EventSet, Key: cmd.Key, Value: strconv.FormatInt(sum, 10), Index: index}}
+		}
+	case "undelete":
+		if value, ok := rs.store.Get(cmd.Key); changed && ok {
+			return []kv.Event{{Type: kv.EventSet, Key: cmd.Key, Value: value, Index: index}}
+		}
+	case "batch":
+		events := make([]kv.Event, len(cmd.Ops))
+		for i, op := range cmd.Ops {
+			events[i] = kv.Event{Type: kv.EventSet, Key: op.Key, Value: op.Value, Index: index}
+			if op.Op == "delete" {
+				events[i] = kv.Event{Type: kv.EventDelete, Key: op.Key, Index: index}
+			}
+		}
+		return events
+	}
+	return nil
+}
 
-type noopSnapshot struct{}
+// Watch streams changes to the keys selected by opts as this node applies
+// them, so followers serve watches too (trailing the leader by their
+// replication lag). With opts.IncludeInitial the matching keys are sent
+// first as INITIAL events, read at the same applied index from which live
+// events start: every write is reflected either in the initial state or
+// as a later event, never both or neither. Events arrive in log order.
+// Keys removed by TTL expiry or tombstone purging emit no event.
+func (rs *RaftStore) Watch(ctx context.Context, opts kv.WatchOptions) (<-chan kv.Event, error) {
+	rs.applyMu.RLock()
+	var initial []kv.Event
+	if opts.IncludeInitial {
+		var pairs []kv.KeyValue
+		if opts.Key != "" {
+			if value, ok := rs.store.Get(opts.Key); ok {
+				pairs = []kv.KeyValue{{Key: opts.Key, Value: value}}
+			}
+		} else {
+			pairs, _ = rs.store.Scan(opts.Prefix, 0)
+		}
+		initial = make([]kv.Event, len(pairs))
+		for i, p := range pairs {
+			initial[i] = kv.Event{Type: kv.EventInitial, Key: p.Key, Value: p.Value, Index: rs.lastApplied}
+		}
+	}
+	w := rs.watchers.add(opts)
+	rs.applyMu.RUnlock()
+	return rs.watchers.stream(ctx, w, initial), nil
+}
 
-func (n *noopSnapshot) Persist(sink raft.SnapshotSink) error { return sink.Close() }
-func (n *noopSnapshot) Release()                             {}
+// appendedAt returns the leader's timestamp for log, falling back to the
+// local clock for entries written before raft recorded append times.
+func appendedAt(log *raft.Log) time.Time {
+	if log.AppendedAt.IsZero() {
+		return time.Now()
+	}
+	return log.AppendedAt
+}
+
+// Snapshot captures a copy of the whole store, including TTLs, tombstones,
+// labels, timestamps and aliases, and the remembered request IDs. raft
+// never calls it concurrently with Apply; the copy is encoded later by
+// Persist.
+func (rs *RaftStore) Snapshot() (raft.FSMSnapshot, error) {
+	state := rs.store.snapshotData()
+	state.Requests = rs.requests.list()
+	state.Index = rs.lastApplied
+	if !rs.lastAppliedAt.IsZero() {
+		state.AppendedAt = rs.lastAppliedAt.UnixNano()
+	}
+	return &fsmSnapshot{state: state, compression: rs.snapshotCompression}, nil
+}
+
+// Restore replaces the store's contents with a snapshot written by
+// Persist, in any supported compression. Open watches are closed, since
+// the jump to the snapshot's state isn't expressed as events; watchers
+// resynchronize by reconnecting with include_initial.
+func (rs *RaftStore) Restore(rc io.ReadCloser) error {
+	defer rc.Close()
+	r, err := newSnapshotReader(rc)
+	if err != nil {
+		return err
+	}
+	var state snapshotState
+	dec := json.NewDecoder(r)
+	if err := dec.Decode(&state); err != nil {
+		return fmt.Errorf("decoding snapshot: %w", err)
+	}
+	// Read to the end of the stream: gzip only verifies its checksum and
+	// length there, so a truncated or corrupt snapshot can still decode.
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		if err == nil {
+			err = errors.New("unexpected data after the snapshot")
+		}
+		return fmt.Errorf("decoding snapshot: %w", err)
+	}
+
+	rs.applyMu.Lock()
+	defer rs.applyMu.Unlock()
+	rs.store.restoreData(state)
+	rs.requests.reset(state.Requests)
+	// raft doesn't pass the snapshot's metadata to Restore, so the index
+	// travels in the snapshot itself.
+	rs.lastApplied = state.Index
+	rs.lastAppliedAt = time.Time{}
+	if state.AppendedAt != 0 {
+		rs.lastAppliedAt = time.Unix(0, state.AppendedAt)
+	}
+	rs.watchers.closeAll()
+	return nil
+}
 
 // Set submits a set command to Raft.
 func (rs *RaftStore) Set(key, value string) error {
-	cmd := RaftCommand{Op: "set", Key: key, Value: value}
-	data, _ := json.Marshal(cmd)
-	f := rs.raft.Apply(data, 0)
-	return f.Error()
+	_, err := rs.SetIndexed(key, value)
+	return err
+}
+
+// SetIndexed submits a set command to Raft and returns its log index.
+func (rs *RaftStore) SetIndexed(key, value string) (uint64, error) {
+	return rs.SetCtx(context.Background(), key, value)
+}
+
+// SetCtx is SetIndexed that stops waiting for the commit once ctx is done,
+// e.g. when the client disconnects during an election.
+func (rs *RaftStore) SetCtx(ctx context.Context, key, value string) (uint64, error) {
+	return rs.SetIdempotent(ctx, "", key, value)
+}
+
+// SetIdempotent is SetCtx tagged with requestID. If a write with the same
+// ID was applied recently, nothing is written and its index is returned.
+// An empty requestID is never deduplicated.
+func (rs *RaftStore) SetIdempotent(ctx context.Context, requestID, key, value string) (uint64, error) {
+	if err := rs.checkQuota(kv.BatchOp{Op: "set", Key: key, Value: value}); err != nil {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	cmd := RaftCommand{Op: "set", Key: key, Value: value, RequestID: requestID}
+	f := rs.apply(cmd)
+	if err := waitFuture(ctx, f); err != nil {
+		return 0, err
+	}
+	index, _ := outcome(f)
+	return index, nil
 }
 
 // Delete submits a delete command to Raft.
+// With soft-delete enabled the key is tombstoned instead, and stays
+// recoverable via Undelete until the leader-computed purge deadline.
 func (rs *RaftStore) Delete(key string) error {
+	_, err := rs.DeleteIndexed(key)
+	return err
+}
+
+// DeleteIndexed submits a delete (or soft-delete) command to Raft and
+// returns its log index.
+func (rs *RaftStore) DeleteIndexed(key string) (uint64, error) {
+	_, index, err := rs.DeleteReport(key)
+	return index, err
+}
+
+// DeleteReport submits a delete (or soft-delete) command to Raft and
+// returns whether the key existed when the entry was applied, and the
+// entry's log index.
+func (rs *RaftStore) DeleteReport(key string) (bool, uint64, error) {
+	return rs.DeleteCtx(context.Background(), key)
+}
+
+// DeleteCtx is DeleteReport that stops waiting for the commit once ctx is
+// done.
+func (rs *RaftStore) DeleteCtx(ctx context.Context, key string) (bool, uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return false, 0, err
+	}
 	cmd := RaftCommand{Op: "delete", Key: key}
-	data, _ := json.Marshal(cmd)
-	f := rs.raft.Apply(data, 0)
+	if rs.softDeleteWindow > 0 {
+		cmd = RaftCommand{Op: "softdelete", Key: key, ExpiresAt: time.Now().Add(rs.softDeleteWindow).UnixMilli()}
+	}
+	f := rs.apply(cmd)
+	if err := waitFuture(ctx, f); err != nil {
+		return false, 0, err
+	}
+	existed, _ := f.Response().(bool)
+	return existed, f.Index(), nil
+}
+
+// SetWithTTL submits a set command carrying an absolute deadline.
+// The deadline is computed here on the leader (including any jitter), so
+// every replica expires the key at the same moment.
+func (rs *RaftStore) SetWithTTL(key, value string, ttl time.Duration) error {
+	if err := rs.checkQuota(kv.BatchOp{Op: "set", Key: key, Value: value}); err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(rs.jitteredTTL(ttl))
+	cmd := RaftCommand{Op: "set", Key: key, Value: value, ExpiresAt: expiresAt.UnixMilli()}
+	f := rs.apply(cmd)
 	return f.Error()
 }
 
+// SetNXWithTTL submits a setnx command: the key is set with a leader-computed
+// deadline only if it is absent when the entry is applied, so the check and
+// the write happen atomically on every replica. This is the lock-acquire
+// primitive; release with DeleteIf.
+func (rs *RaftStore) SetNXWithTTL(key, value string, ttl time.Duration) (bool, error) {
+	if err := rs.checkQuota(kv.BatchOp{Op: "set", Key: key, Value: value}); err != nil {
+		return false, err
+	}
+	// No jitter: lock holders rely on the exact lease length.
+	expiresAt := time.Now().Add(ttl)
+	cmd := RaftCommand{Op: "setnx", Key: key, Value: value, ExpiresAt: expiresAt.UnixMilli()}
+	f := rs.apply(cmd)
+	if err := f.Error(); err != nil {
+		return false, err
+	}
+	set, _ := f.Response().(bool)
+	return set, nil
+}
+
+// DeleteIf submits a deleteif command removing key only if it still holds
+// value when applied.
+func (rs *RaftStore) DeleteIf(key, value string) (bool, error) {
+	cmd := RaftCommand{Op: "deleteif", Key: key, Value: value}
+	f := rs.apply(cmd)
+	if err := f.Error(); err != nil {
+		return false, err
+	}
+	deleted, _ := f.Response().(bool)
+	return deleted, nil
+}
+
+// SetAlias submits an alias command pointing alias at target, or removing
+// alias if target is empty. The FSM rejects cycles and dangling targets.
+func (rs *RaftStore) SetAlias(alias, target string) error {
+	cmd := RaftCommand{Op: "alias", Key: alias, Value: target}
+	f := rs.apply(cmd)
+	if err := f.Error(); err != nil {
+		return err
+	}
+	if err, ok := f.Response().(error); ok {
+		return err
+	}
+	return nil
+}
+
+// CompareAndSwap submits a cas command. The comparison happens in Apply,
+// so it is linearizable with every other write in the log.
+func (rs *RaftStore) CompareAndSwap(key, old, new string) (bool, error) {
+	if err := rs.checkQuota(kv.BatchOp{Op: "set", Key: key, Value: new}); err != nil {
+		return false, err
+	}
+	cmd := RaftCommand{Op: "cas", Key: key, Value: new, Old: old}
+	f := rs.apply(cmd)
+	if err := f.Error(); err != nil {
+		return false, err
+	}
+	swapped, _ := f.Response().(bool)
+	return swapped, nil
+}
+
+// Increment submits an incr command. The addition happens in Apply, so
+// it is linearizable with every other write in the log.
+func (rs *RaftStore) Increment(key string, delta int64) (int64, error) {
+	return rs.IncrementIdempotent("", key, delta)
+}
+
+// IncrementIdempotent is Increment tagged with requestID. If an increment
+// with the same ID was applied recently, nothing is added and the sum it
+// produced is returned.
+func (rs *RaftStore) IncrementIdempotent(requestID, key string, delta int64) (int64, error) {
+	// A counter is at most 20 bytes; checking that worst case keeps the
+	// key within its namespace quota whatever the sum turns out to be.
+	if err := rs.checkQuota(kv.BatchOp{Op: "set", Key: key, Value: strconv.FormatInt(math.MinInt64, 10)}); err != nil {
+		return 0, err
+	}
+	cmd := RaftCommand{Op: "incr", Key: key, Delta: delta, RequestID: requestID}
+	f := rs.apply(cmd)
+	if err := f.Error(); err != nil {
+		return 0, err
+	}
+	_, resp := outcome(f)
+	if err, ok := resp.(error); ok {
+		return 0, err
+	}
+	sum, _ := resp.(int64)
+	return sum, nil
+}
+
+// Touch submits a touch command resetting an existing key's TTL. As with
+// SetWithTTL, the new absolute deadline is computed on the leader.
+func (rs *RaftStore) Touch(key string, ttl time.Duration) (bool, error) {
+	expiresAt := time.Now().Add(rs.jitteredTTL(ttl))
+	cmd := RaftCommand{Op: "touch", Key: key, ExpiresAt: expiresAt.UnixMilli()}
+	f := rs.apply(cmd)
+	if err := f.Error(); err != nil {
+		return false, err
+	}
+	touched, _ := f.Response().(bool)
+	return touched, nil
+}
+
+// ExpirePrefix submits one command that sets the same leader-computed
+// deadline on every key matching prefix, and returns how many keys matched.
+func (rs *RaftStore) ExpirePrefix(prefix string, ttl time.Duration) (int, error) {
+	cmd := RaftCommand{Op: "expireprefix", Key: prefix, ExpiresAt: time.Now().Add(ttl).UnixMilli()}
+	f := rs.apply(cmd)
+	if err := f.Error(); err != nil {
+		return 0, err
+	}
+	count, _ := f.Response().(int)
+	return count, nil
+}
+
+// SetTTLJitter spreads each TTL by up to ±percent so keys written together
+// with the same TTL don't all expire at once. Zero disables jitter.
+func (rs *RaftStore) SetTTLJitter(percent int) {
+	rs.ttlJitterPercent = percent
+}
+
+func (rs *RaftStore) jitteredTTL(ttl time.Duration) time.Duration {
+	if rs.ttlJitterPercent <= 0 {
+		return ttl
+	}
+	spread := float64(ttl) * float64(rs.ttlJitterPercent) / 100
+	return ttl + time.Duration((rand.Float64()*2-1)*spread)
+}
+
+// SetSnapshotCompression selects how snapshot streams are encoded:
+// SnapshotCompressionNone or SnapshotCompressionGzip. Snapshots record their
+// format, so nodes with different settings can still restore each other's.
+func (rs *RaftStore) SetSnapshotCompression(compression string) error {
+	if _, err := newSnapshotWriter(io.Discard, compression); err != nil {
+		return err
+	}
+	rs.snapshotCompression = compression
+	return nil
+}
+
+// RunSweeper purges expired keys and tombstones every interval, judged at
+// the earlier of the local clock and the time the last applied entry was
+// appended. Later entries are appended later still, so they would find
+// anything purged already expired; a lagging follower, or a node replaying
+// its log, never purges a key an entry it has yet to apply still sees.
+func (rs *RaftStore) RunSweeper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	for range ticker.C {
+		if at := rs.sweepTime(); !at.IsZero() {
+			rs.store.sweep(at)
+		}
+	}
+}
+
+// sweepTime returns the time RunSweeper judges expiry at, or the zero
+// time before any entry has been applied.
+func (rs *RaftStore) sweepTime() time.Time {
+	rs.applyMu.RLock()
+	defer rs.applyMu.RUnlock()
+	if now := time.Now(); now.Before(rs.lastAppliedAt) {
+		return now
+	}
+	return rs.lastAppliedAt
+}
+
+// SetMaxEntryBytes bounds the encoded size of a single Raft log entry.
+// Huge entries stall replication and snapshots, so larger commands are
+// rejected with kv.ErrTooLarge before they reach the log. Zero or less
+// restores DefaultMaxEntryBytes.
+func (rs *RaftStore) SetMaxEntryBytes(n int) {
+	if n <= 0 {
+		n = DefaultMaxEntryBytes
+	}
+	rs.maxEntryBytes = n
+}
+
+// SetApplyTimeout bounds how long a write waits to be committed before it
+// fails with kv.ErrApplyTimeout, so writes cannot hang while the cluster
+// has no quorum. Zero or less restores DefaultApplyTimeout.
+func (rs *RaftStore) SetApplyTimeout(d time.Duration) {
+	if d <= 0 {
+		d = DefaultApplyTimeout
+	}
+	rs.applyTimeout = d
+}
+
+// apply encodes cmd and proposes it to Raft, refusing entries larger than
+// maxEntryBytes without touching the log.
+func (rs *RaftStore) apply(cmd RaftCommand) raft.ApplyFuture {
+	data, _ := json.Marshal(cmd)
+	if len(data) > rs.maxEntryBytes {
+		return errorFuture{fmt.Errorf("%w: %s command encodes to %d bytes, limit is %d",
+			kv.ErrTooLarge, cmd.Op, len(data), rs.maxEntryBytes)}
+	}
+	return &timeoutFuture{
+		ApplyFuture: rs.raft.Apply(data, rs.applyTimeout),
+		deadline:    time.Now().Add(rs.applyTimeout),
+	}
+}
+
+// timeoutFuture gives up on a raft.ApplyFuture at a deadline. Raft's own
+// timeout only covers enqueueing the entry, not committing it.
+type timeoutFuture struct {
+	raft.ApplyFuture
+	deadline time.Time
+}
+
+// Error waits for the entry like the wrapped future, but returns
+// kv.ErrApplyTimeout once the deadline passes. The goroutine left waiting
+// exits when Raft resolves the future, which it does when the entry
+// commits or leadership is lost.
+func (f *timeoutFuture) Error() error {
+	done := make(chan error, 1)
+	go func() { done <- f.ApplyFuture.Error() }()
+	timer := time.NewTimer(time.Until(f.deadline))
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		if errors.Is(err, raft.ErrEnqueueTimeout) {
+			return kv.ErrApplyTimeout
+		}
+		return err
+	case <-timer.C:
+		return kv.ErrApplyTimeout
+	}
+}
+
+// waitFuture waits for f like f.Error, but returns ctx.Err() as soon as
+// ctx is done. Raft cannot withdraw a proposal, so the entry may still be
+// applied later; the goroutine left waiting on f exits once Raft resolves
+// it, which it does when the entry commits or leadership is lost.
+func waitFuture(ctx context.Context, f raft.ApplyFuture) error {
+	if ctx.Done() == nil {
+		return f.Error()
+	}
+	done := make(chan error, 1)
+	go func() { done <- f.Error() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// errorFuture is an already-failed raft.ApplyFuture.
+type errorFuture struct{ err error }
+
+func (f errorFuture) Error() error          { return f.err }
+func (f errorFuture) Index() uint64         { return 0 }
+func (f errorFuture) Response() interface{} { return nil }
+
+// SetQuotas installs per-namespace limits. They are checked on the leader
+// before a write is proposed, against the usage tracked in the FSM, so
+// concurrent writes already in flight may overshoot a limit slightly.
+func (rs *RaftStore) SetQuotas(quotas map[string]NamespaceQuota) {
+	rs.quotas = quotas
+}
+
+// checkQuota rejects sets that would take a limited namespace past its
+// quota. Deletes are not credited, so a batch is judged conservatively.
+func (rs *RaftStore) checkQuota(ops ...kv.BatchOp) error {
+	if len(rs.quotas) == 0 {
+		return nil
+	}
+	deltas := make(map[string]kv.Usage)
+	for _, op := range ops {
+		ns := kv.NamespaceOf(op.Key)
+		if _, limited := rs.quotas[ns]; !limited || op.Op != "set" {
+			continue
+		}
+		d := deltas[ns]
+		if old, ok := rs.store.storedLen(op.Key); ok {
+			d.Bytes += int64(len(op.Value) - old)
+		} else {
+			d.Keys++
+			d.Bytes += int64(len(op.Key) + len(op.Value))
+		}
+		deltas[ns] = d
+	}
+	for ns, d := range deltas {
+		q := rs.quotas[ns]
+		u := rs.store.UsageOf(ns)
+		if q.MaxKeys > 0 && d.Keys > 0 && u.Keys+d.Keys > q.MaxKeys {
+			return fmt.Errorf("%w: namespace %q is limited to %d keys", kv.ErrQuotaExceeded, ns, q.MaxKeys)
+		}
+		if q.MaxBytes > 0 && d.Bytes > 0 && u.Bytes+d.Bytes > q.MaxBytes {
+			return fmt.Errorf("%w: namespace %q is limited to %d bytes", kv.ErrQuotaExceeded, ns, q.MaxBytes)
+		}
+	}
+	return nil
+}
+
+// SetSoftDeleteWindow enables soft-delete: deleted keys are kept as
+// tombstones for window before being purged. Zero restores hard deletes.
+func (rs *RaftStore) SetSoftDeleteWindow(window time.Duration) {
+	rs.softDeleteWindow = window
+}
+
+// Undelete submits an undelete command to Raft and reports whether a
+// tombstoned key was restored.
+func (rs *RaftStore) Undelete(key string) (bool, error) {
+	cmd := RaftCommand{Op: "undelete", Key: key}
+	f := rs.apply(cmd)
+	if err := f.Error(); err != nil {
+		return false, err
+	}
+	restored, _ := f.Response().(bool)
+	return restored, nil
+}
+
+// SetReplicationTracker sets the tracker wrapping this node's Raft
+// transport, which SetReplicated needs to count acknowledgments.
+func (rs *RaftStore) SetReplicationTracker(t *ReplicationTracker) {
+	rs.tracker = t
+}
+
+// SetReplicated submits a set command to Raft and, once it is committed,
+// waits up to the apply timeout until at least replicas nodes, leader
+// included, have acknowledged the entry. This is a durability enhancement
+// on top of Raft's quorum commit. It returns how many nodes were
+// confirmed; the write is committed either way.
+func (rs *RaftStore) SetReplicated(key, value string, replicas int) (int, error) {
+	if rs.tracker == nil {
+		return 0, kv.ErrNotSupported
+	}
+	if err := rs.checkQuota(kv.BatchOp{Op: "set", Key: key, Value: value}); err != nil {
+		return 0, err
+	}
+	cmd := RaftCommand{Op: "set", Key: key, Value: value}
+	f := rs.apply(cmd)
+	if err := f.Error(); err != nil {
+		return 0, err
+	}
+	// The term now is at least the entry's, which only makes the count
+	// stricter.
+	return rs.waitForReplicas(f.Index(), rs.raft.CurrentTerm(), replicas), nil
+}
+
+// waitForReplicas waits until replicas nodes, counting this one, hold the
+// entry at index appended in term, or the apply timeout passes, and returns
+// how many do.
+func (rs *RaftStore) waitForReplicas(index, term uint64, replicas int) int {
+	timer := time.NewTimer(rs.applyTimeout)
+	defer timer.Stop()
+	for {
+		n, changed := rs.tracker.Holding(index, term)
+		n++ // the leader applied the entry itself
+		if n >= replicas {
+			return n
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return n
+		}
+	}
+}
+
+// Batch validates ops and submits them to Raft as a single log entry, so
+// the batch commits and applies atomically on every node.
+func (rs *RaftStore) Batch(ops []kv.BatchOp) error {
+	return rs.BatchIdempotent("", ops)
+}
+
+// BatchIdempotent is Batch tagged with requestID. If a batch with the same
+// ID was applied recently, none of ops are applied again.
+func (rs *RaftStore) BatchIdempotent(requestID string, ops []kv.BatchOp) error {
+	if err := kv.ValidateBatch(ops); err != nil {
+		return err
+	}
+	if err := rs.checkQuota(ops...); err != nil {
+		return err
+	}
+	cmd := RaftCommand{Op: "batch", Ops: ops, RequestID: requestID}
+	return rs.apply(cmd).Error()
+}
+
+// SetLabels validates labels and submits a label command to Raft.
+// Returns false if the key doesn't exist when the command is applied.
+func (rs *RaftStore) SetLabels(key string, labels map[string]string) (bool, error) {
+	if err := kv.ValidateLabels(labels); err != nil {
+		return false, err
+	}
+	cmd := RaftCommand{Op: "label", Key: key, Labels: labels}
+	f := rs.apply(cmd)
+	if err := f.Error(); err != nil {
+		return false, err
+	}
+	labeled, _ := f.Response().(bool)
+	return labeled, nil
+}
+
+// ApplyRaw submits cmd to Raft as-is, bypassing the usual API validation,
+// and returns its log index and Apply's response. It exists for operator
+// debugging and recovery; only the op name is checked.
+func (rs *RaftStore) ApplyRaw(cmd RaftCommand) (uint64, interface{}, error) {
+	if !knownOps[cmd.Op] {
+		return 0, nil, fmt.Errorf("%w: %q", ErrUnknownOp, cmd.Op)
+	}
+	f := rs.apply(cmd)
+	if err := f.Error(); err != nil {
+		return 0, nil, err
+	}
+	if err, ok := f.Response().(error); ok {
+		return f.Index(), nil, err
+	}
+	return f.Index(), f.Response(), nil
+}
+
 // Get reads directly from the local store.
 func (rs *RaftStore) Get(key string) (string, bool) {
 	return rs.store.Get(key)
 }
+
+// MultiGet reads directly from the local store.
+func (rs *RaftStore) MultiGet(keys []string) (map[string]string, error) {
+	return rs.store.MultiGet(keys)
+}
+
+// Exists reads directly from the local store.
+func (rs *RaftStore) Exists(key string) (bool, error) {
+	return rs.store.Exists(key)
+}
+
+// ResolveAlias reads directly from the local store.
+func (rs *RaftStore) ResolveAlias(key string) (string, error) {
+	return rs.store.ResolveAlias(key)
+}
+
+// GetWithMeta reads directly from the local store.
+func (rs *RaftStore) GetWithMeta(key string) (kv.Entry, bool) {
+	return rs.store.GetWithMeta(key)
+}
+
+// ScanLabel reads directly from the local store.
+func (rs *RaftStore) ScanLabel(prefix, name, value string, limit int) ([]kv.KeyValue, error) {
+	return rs.store.ScanLabel(prefix, name, value, limit)
+}
+
+// Oldest reads directly from the local store.
+func (rs *RaftStore) Oldest(prefix string, byUpdate bool) (string, kv.Entry, bool) {
+	return rs.store.Oldest(prefix, byUpdate)
+}
+
+// Newest reads directly from the local store.
+func (rs *RaftStore) Newest(prefix string, byUpdate bool) (string, kv.Entry, bool) {
+	return rs.store.Newest(prefix, byUpdate)
+}
+
+// NamespaceUsage reads directly from the local store.
+func (rs *RaftStore) NamespaceUsage() map[string]kv.Usage {
+	return rs.store.NamespaceUsage()
+}
+
+// Len reads directly from the local store.
+func (rs *RaftStore) Len() int {
+	return rs.store.Len()
+}
+
+// ApproxBytes reads directly from the local store.
+func (rs *RaftStore) ApproxBytes() int64 {
+	return rs.store.ApproxBytes()
+}
+
+// Scan reads directly from the local store.
+func (rs *RaftStore) Scan(prefix string, limit int) ([]kv.KeyValue, error) {
+	return rs.store.Scan(prefix, limit)
+}
@@ -0,0 +1,258 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/heysubinoy/pyazdb/pkg/kv"
+)
+
+func TestRaftStoreRejectsOversizedEntries(t *testing.T) {
+	const limit = 1024
+	// overhead is the encoded size of cmd with an empty value.
+	overhead := func(cmd RaftCommand) int {
+		data, _ := json.Marshal(cmd)
+		return len(data)
+	}
+	setRoom := limit - overhead(RaftCommand{Op: "set", Key: "k"})
+	batchRoom := limit - overhead(RaftCommand{Op: "batch", Ops: []kv.BatchOp{{Op: "set", Key: "k"}, {Op: "delete", Key: "d"}}})
+
+	tests := []struct {
+		name     string
+		write    func(rs *RaftStore) error
+		rejected bool
+	}{
+		{"set at the limit", func(rs *RaftStore) error {
+			return rs.Set("k", strings.Repeat("x", setRoom))
+		}, false},
+		{"set one byte over", func(rs *RaftStore) error {
+			return rs.Set("k", strings.Repeat("x", setRoom+1))
+		}, true},
+		{"batch at the limit", func(rs *RaftStore) error {
+			return rs.Batch([]kv.BatchOp{{Op: "set", Key: "k", Value: strings.Repeat("x", batchRoom)}, {Op: "delete", Key: "d"}})
+		}, false},
+		{"batch one byte over", func(rs *RaftStore) error {
+			return rs.Batch([]kv.BatchOp{{Op: "set", Key: "k", Value: strings.Repeat("x", batchRoom+1)}, {Op: "delete", Key: "d"}})
+		}, true},
+	}
+	c := newTestCluster(t, 1)
+	node := c.leader()
+	node.rs.SetMaxEntryBytes(limit)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := node.raft.LastIndex()
+			err := tt.write(node.rs)
+			if !tt.rejected {
+				if err != nil {
+					t.Fatalf("write: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, kv.ErrTooLarge) {
+				t.Fatalf("write = %v, want ErrTooLarge", err)
+			}
+			if after := node.raft.LastIndex(); after != before {
+				t.Errorf("log grew from %d to %d for a rejected entry", before, after)
+			}
+		})
+	}
+}
+
+func TestBatchAppliesAtomicallyOnFollowers(t *testing.T) {
+	c := newTestCluster(t, 3)
+	leader := c.leader()
+	for i := 0; i < 50; i++ {
+		leader.rs.Set(fmt.Sprintf("old/%d", i), "v")
+	}
+
+	ops := make([]kv.BatchOp, 0, 100)
+	for i := 0; i < 50; i++ {
+		ops = append(ops, kv.BatchOp{Op: "set", Key: fmt.Sprintf("new/%d", i), Value: strconv.Itoa(i)})
+		ops = append(ops, kv.BatchOp{Op: "delete", Key: fmt.Sprintf("old/%d", i)})
+	}
+	followers := c.followers()
+	for _, f := range followers {
+		c.waitApplied(f, leader.raft.LastIndex())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watches := make([]<-chan kv.Event, len(followers))
+	for i, f := range followers {
+		ch, err := f.rs.Watch(ctx, kv.WatchOptions{})
+		if err != nil {
+			t.Fatalf("Watch: %v", err)
+		}
+		watches[i] = ch
+	}
+
+	before := leader.raft.LastIndex()
+	if err := leader.rs.Batch(ops); err != nil {
+		t.Fatalf("Batch: %v", err)
+	}
+	index := leader.raft.LastIndex()
+	if index != before+1 {
+		t.Fatalf("batch took log entries %d..%d, want one", before+1, index)
+	}
+
+	for i, f := range followers {
+		// Every change a follower reports belongs to the batch's one
+		// entry, so no state with only part of the batch is ever visible.
+		for n := 0; n < len(ops); n++ {
+			select {
+			case ev := <-watches[i]:
+				if ev.Index != index {
+					t.Fatalf("%s: %s %s applied at index %d, want %d", f.id, ev.Type, ev.Key, ev.Index, index)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s: saw %d of %d batch events", f.id, n, len(ops))
+			}
+		}
+		c.waitApplied(f, index)
+		newKeys, _ := f.rs.Scan("new/", 0)
+		oldKeys, _ := f.rs.Scan("old/", 0)
+		if len(newKeys) != 50 || len(oldKeys) != 0 {
+			t.Errorf("%s holds %d new and %d old keys, want 50 and 0", f.id, len(newKeys), len(oldKeys))
+		}
+		for _, p := range newKeys {
+			if want := strings.TrimPrefix(p.Key, "new/"); p.Value != want {
+				t.Errorf("%s: %s = %q, want %q", f.id, p.Key, p.Value, want)
+			}
+		}
+	}
+}
+
+func TestApplyTimeoutWithoutQuorum(t *testing.T) {
+	c := newTestCluster(t, 3)
+	leader := c.leader()
+	if err := leader.rs.Set("k", "before"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	// Followers that stop accepting entries cut the leader off from a
+	// quorum for writes, while their heartbeats keep it the leader.
+	for _, f := range c.followers() {
+		f.logs.failing.Store(true)
+	}
+	tests := []struct {
+		name  string
+		write func(rs *RaftStore) error
+	}{
+		{"set", func(rs *RaftStore) error { return rs.Set("k", "v") }},
+		{"delete", func(rs *RaftStore) error { return rs.Delete("k") }},
+		{"batch", func(rs *RaftStore) error { return rs.Batch([]kv.BatchOp{{Op: "set", Key: "k", Value: "v"}}) }},
+		{"incr", func(rs *RaftStore) error { _, err := rs.Increment("n", 1); return err }},
+	}
+	const timeout = 200 * time.Millisecond
+	leader.rs.SetApplyTimeout(timeout)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			err := tt.write(leader.rs)
+			if !errors.Is(err, kv.ErrApplyTimeout) {
+				t.Fatalf("write without quorum = %v, want ErrApplyTimeout", err)
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("ErrApplyTimeout does not wrap context.DeadlineExceeded")
+			}
+			if took := time.Since(start); took > timeout+time.Second {
+				t.Errorf("write gave up after %v, want about %v", took, timeout)
+			}
+		})
+	}
+	if leader.raft.State() != raft.Leader {
+		t.Fatal("leader stepped down; the partition did not hold")
+	}
+
+	// Once the followers recover the cluster takes writes again.
+	for _, f := range c.followers() {
+		f.logs.failing.Store(false)
+	}
+	leader.rs.SetApplyTimeout(5 * time.Second)
+	if err := leader.rs.Set("k", "after"); err != nil {
+		t.Fatalf("Set after recovery: %v", err)
+	}
+}
+
+// A lagging follower or a node replaying its log applies entries long
+// after the leader appended them, so its sweeper must not purge anything
+// an entry it has yet to apply still sees.
+func TestSweepNeverRunsAheadOfTheLog(t *testing.T) {
+	base := time.Now().Add(-time.Hour)
+	deadline := base.Add(time.Minute).UnixMilli()
+	tests := []struct {
+		name  string
+		setup RaftCommand
+		later RaftCommand
+	}{
+		{"touch", RaftCommand{Op: "set", Key: "k", Value: "v", ExpiresAt: deadline},
+			RaftCommand{Op: "touch", Key: "k", ExpiresAt: base.Add(time.Hour).UnixMilli()}},
+		{"undelete", RaftCommand{Op: "softdelete", Key: "k", ExpiresAt: deadline},
+			RaftCommand{Op: "undelete", Key: "k"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := NewRaftStore(NewMemStore(), nil)
+			index := uint64(0)
+			apply := func(cmd RaftCommand, at time.Time) interface{} {
+				index++
+				data, _ := json.Marshal(cmd)
+				return rs.Apply(&raft.Log{Index: index, Type: raft.LogCommand, Data: data, AppendedAt: at})
+			}
+			if tt.setup.Op == "softdelete" {
+				apply(RaftCommand{Op: "set", Key: "k", Value: "v"}, base)
+			}
+			apply(tt.setup, base)
+
+			// By the local clock the deadline passed long ago, but the log
+			// has only reached base.
+			if at := rs.sweepTime(); !at.Equal(base) {
+				t.Fatalf("sweepTime = %v, want the last entry's %v", at, base)
+			}
+			rs.store.sweep(rs.sweepTime())
+
+			if resp := apply(tt.later, base.Add(30*time.Second)); resp != true {
+				t.Errorf("%s appended before the deadline = %v, want true", tt.later.Op, resp)
+			}
+		})
+	}
+}
+
+func TestTTLExpiresOnLeaderAndFollower(t *testing.T) {
+	c := newTestCluster(t, 3)
+	leader := c.leader()
+	if err := leader.rs.SetWithTTL("session", "token", 50*time.Millisecond); err != nil {
+		t.Fatalf("SetWithTTL: %v", err)
+	}
+	index := leader.raft.LastIndex()
+	follower := c.followers()[0]
+	c.waitApplied(follower, index)
+	if v, ok := follower.rs.Get("session"); !ok || v != "token" {
+		t.Fatalf("follower Get before expiry = %q, %v; want token", v, ok)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	for _, node := range []*testNode{leader, follower} {
+		if v, ok := node.rs.Get("session"); ok {
+			t.Errorf("%s still returns %q after the TTL", node.id, v)
+		}
+	}
+
+	// Once the log moves past the deadline, both sweepers reclaim the key.
+	if err := leader.rs.Set("other", "v"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	c.waitApplied(follower, leader.raft.LastIndex())
+	for _, node := range []*testNode{leader, follower} {
+		node.rs.store.sweep(node.rs.sweepTime())
+		if n := node.rs.store.Len(); n != 1 {
+			t.Errorf("%s holds %d keys after sweeping, want 1", node.id, n)
+		}
+	}
+}
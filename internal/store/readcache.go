@@ -0,0 +1,502 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/heysubinoy/pyazdb/pkg/kv"
+)
+
+// ReadCacheStore wraps a kv.Store and remembers Get results for a short
+// TTL, so repeated reads of the same key skip the wrapped store. Every
+// write made through the wrapper drops the keys it touches. Writes that
+// bypass it, such as entries a follower receives from the leader or keys
+// expiring by TTL, are only seen once the cached entry expires, so reads
+// may be up to the TTL stale.
+//
+// Only Get is cached; every other call goes straight to the wrapped store.
+type ReadCacheStore struct {
+	store      kv.Store
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]readCacheEntry
+
+	// gen is bumped by every invalidation. A Get only caches what it read
+	// if no invalidation ran meanwhile, so a write racing with the read
+	// can't leave the old value behind.
+	gen atomic.Uint64
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+type readCacheEntry struct {
+	value   string
+	found   bool
+	expires time.Time
+}
+
+// ReadCacheStats counts cache lookups since the cache was created.
+type ReadCacheStats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+}
+
+// HitRatio returns the fraction of lookups answered from the cache, or 0
+// before the first lookup.
+func (s ReadCacheStats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Compile-time checks to ensure ReadCacheStore implements kv.Store and
+// forwards the optional store interfaces.
+var (
+	_ kv.Store             = (*ReadCacheStore)(nil)
+	_ kv.TTLStore          = (*ReadCacheStore)(nil)
+	_ kv.ReplicatedStore   = (*ReadCacheStore)(nil)
+	_ kv.UndeleteStore     = (*ReadCacheStore)(nil)
+	_ kv.IndexedStore      = (*ReadCacheStore)(nil)
+	_ kv.LabelStore        = (*ReadCacheStore)(nil)
+	_ kv.BatchStore        = (*ReadCacheStore)(nil)
+	_ kv.UsageStore        = (*ReadCacheStore)(nil)
+	_ kv.SizeStore         = (*ReadCacheStore)(nil)
+	_ kv.AgeStore          = (*ReadCacheStore)(nil)
+	_ kv.ConditionalStore  = (*ReadCacheStore)(nil)
+	_ kv.CounterStore      = (*ReadCacheStore)(nil)
+	_ kv.DeleteReportStore = (*ReadCacheStore)(nil)
+	_ kv.ContextStore      = (*ReadCacheStore)(nil)
+	_ kv.WatchStore        = (*ReadCacheStore)(nil)
+	_ kv.AliasStore        = (*ReadCacheStore)(nil)
+	_ kv.IdempotentStore   = (*ReadCacheStore)(nil)
+)
+
+// NewReadCacheStore wraps a store with a Get cache holding each result
+// for ttl and at most maxEntries keys. Once full, new keys are not cached
+// until expired entries are swept out.
+func NewReadCacheStore(store kv.Store, ttl time.Duration, maxEntries int) *ReadCacheStore {
+	return &ReadCacheStore{
+		store:      store,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]readCacheEntry),
+	}
+}
+
+// Stats returns the hit and miss counts and the number of cached keys.
+func (s *ReadCacheStore) Stats() ReadCacheStats {
+	s.mu.Lock()
+	n := len(s.entries)
+	s.mu.Unlock()
+	return ReadCacheStats{Hits: s.hits.Load(), Misses: s.misses.Load(), Entries: n}
+}
+
+// Get answers from the cache while the key's entry is fresh, and otherwise
+// reads the wrapped store and caches the result, found or not.
+func (s *ReadCacheStore) Get(key string) (string, bool) {
+	now := time.Now()
+	s.mu.Lock()
+	e, ok := s.entries[key]
+	s.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		s.hits.Add(1)
+		return e.value, e.found
+	}
+	s.misses.Add(1)
+
+	gen := s.gen.Load()
+	value, found := s.store.Get(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.gen.Load() != gen {
+		return value, found
+	}
+	if _, cached := s.entries[key]; !cached && len(s.entries) >= s.maxEntries {
+		s.sweepLocked(now)
+		if len(s.entries) >= s.maxEntries {
+			return value, found
+		}
+	}
+	s.entries[key] = readCacheEntry{value: value, found: found, expires: now.Add(s.ttl)}
+	return value, found
+}
+
+// invalidate drops the cached entries for keys.
+func (s *ReadCacheStore) invalidate(keys ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gen.Add(1)
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+}
+
+// invalidatePrefix drops the cached entries for every key under prefix.
+func (s *ReadCacheStore) invalidatePrefix(prefix string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gen.Add(1)
+	for key := range s.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// invalidateOps drops the cached entries for every key a batch names.
+func (s *ReadCacheStore) invalidateOps(ops []kv.BatchOp) {
+	keys := make([]string, len(ops))
+	for i, op := range ops {
+		keys[i] = op.Key
+	}
+	s.invalidate(keys...)
+}
+
+// sweepLocked drops expired entries. Callers must hold s.mu.
+func (s *ReadCacheStore) sweepLocked(now time.Time) {
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// RunSweeper drops expired entries every interval, so keys read once and
+// never again don't hold memory until the cache fills. It never returns,
+// so callers should start it in its own goroutine.
+func (s *ReadCacheStore) RunSweeper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		s.mu.Lock()
+		s.sweepLocked(now)
+		s.mu.Unlock()
+	}
+}
+
+// MultiGet delegates to the wrapped store.
+func (s *ReadCacheStore) MultiGet(keys []string) (map[string]string, error) {
+	return s.store.MultiGet(keys)
+}
+
+// Exists delegates to the wrapped store.
+func (s *ReadCacheStore) Exists(key string) (bool, error) {
+	return s.store.Exists(key)
+}
+
+// Set delegates to the wrapped store and drops key from the cache.
+func (s *ReadCacheStore) Set(key, value string) error {
+	defer s.invalidate(key)
+	return s.store.Set(key, value)
+}
+
+// Delete delegates to the wrapped store and drops key from the cache.
+func (s *ReadCacheStore) Delete(key string) error {
+	defer s.invalidate(key)
+	return s.store.Delete(key)
+}
+
+// Scan delegates to the wrapped store.
+func (s *ReadCacheStore) Scan(prefix string, limit int) ([]kv.KeyValue, error) {
+	return s.store.Scan(prefix, limit)
+}
+
+// SetWithTTL delegates to the wrapped store if it supports TTLs.
+func (s *ReadCacheStore) SetWithTTL(key, value string, ttl time.Duration) error {
+	ts, ok := s.store.(kv.TTLStore)
+	if !ok {
+		return kv.ErrNotSupported
+	}
+	defer s.invalidate(key)
+	return ts.SetWithTTL(key, value, ttl)
+}
+
+// Touch delegates to the wrapped store if it supports TTLs. The key is
+// dropped from the cache so a shortened TTL is not outlived.
+func (s *ReadCacheStore) Touch(key string, ttl time.Duration) (bool, error) {
+	ts, ok := s.store.(kv.TTLStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	defer s.invalidate(key)
+	return ts.Touch(key, ttl)
+}
+
+// ExpirePrefix delegates to the wrapped store if it supports TTLs.
+func (s *ReadCacheStore) ExpirePrefix(prefix string, ttl time.Duration) (int, error) {
+	ts, ok := s.store.(kv.TTLStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	defer s.invalidatePrefix(prefix)
+	return ts.ExpirePrefix(prefix, ttl)
+}
+
+// SetReplicated delegates to the wrapped store if it supports replica acks.
+func (s *ReadCacheStore) SetReplicated(key, value string, replicas int) (int, error) {
+	rs, ok := s.store.(kv.ReplicatedStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	defer s.invalidate(key)
+	return rs.SetReplicated(key, value, replicas)
+}
+
+// Undelete delegates to the wrapped store if it supports soft deletes.
+func (s *ReadCacheStore) Undelete(key string) (bool, error) {
+	us, ok := s.store.(kv.UndeleteStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	defer s.invalidate(key)
+	return us.Undelete(key)
+}
+
+// SetIndexed delegates to the wrapped store if it reports commit indexes.
+func (s *ReadCacheStore) SetIndexed(key, value string) (uint64, error) {
+	is, ok := s.store.(kv.IndexedStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	defer s.invalidate(key)
+	return is.SetIndexed(key, value)
+}
+
+// DeleteIndexed delegates to the wrapped store if it reports commit indexes.
+func (s *ReadCacheStore) DeleteIndexed(key string) (uint64, error) {
+	is, ok := s.store.(kv.IndexedStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	defer s.invalidate(key)
+	return is.DeleteIndexed(key)
+}
+
+// DeleteReport delegates to the wrapped store if it reports whether deleted
+// keys existed.
+func (s *ReadCacheStore) DeleteReport(key string) (bool, uint64, error) {
+	ds, ok := s.store.(kv.DeleteReportStore)
+	if !ok {
+		return false, 0, kv.ErrNotSupported
+	}
+	defer s.invalidate(key)
+	return ds.DeleteReport(key)
+}
+
+// SetCtx delegates to the wrapped store if it supports cancellable writes.
+func (s *ReadCacheStore) SetCtx(ctx context.Context, key, value string) (uint64, error) {
+	cs, ok := s.store.(kv.ContextStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	defer s.invalidate(key)
+	return cs.SetCtx(ctx, key, value)
+}
+
+// DeleteCtx delegates to the wrapped store if it supports cancellable writes.
+func (s *ReadCacheStore) DeleteCtx(ctx context.Context, key string) (bool, uint64, error) {
+	cs, ok := s.store.(kv.ContextStore)
+	if !ok {
+		return false, 0, kv.ErrNotSupported
+	}
+	defer s.invalidate(key)
+	return cs.DeleteCtx(ctx, key)
+}
+
+// SetNXWithTTL delegates to the wrapped store if it supports conditional writes.
+func (s *ReadCacheStore) SetNXWithTTL(key, value string, ttl time.Duration) (bool, error) {
+	cs, ok := s.store.(kv.ConditionalStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	defer s.invalidate(key)
+	return cs.SetNXWithTTL(key, value, ttl)
+}
+
+// DeleteIf delegates to the wrapped store if it supports conditional writes.
+func (s *ReadCacheStore) DeleteIf(key, value string) (bool, error) {
+	cs, ok := s.store.(kv.ConditionalStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	defer s.invalidate(key)
+	return cs.DeleteIf(key, value)
+}
+
+// CompareAndSwap delegates to the wrapped store if it supports conditional writes.
+func (s *ReadCacheStore) CompareAndSwap(key, old, new string) (bool, error) {
+	cs, ok := s.store.(kv.ConditionalStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	defer s.invalidate(key)
+	return cs.CompareAndSwap(key, old, new)
+}
+
+// Increment delegates to the wrapped store if it supports counters.
+func (s *ReadCacheStore) Increment(key string, delta int64) (int64, error) {
+	cs, ok := s.store.(kv.CounterStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	defer s.invalidate(key)
+	return cs.Increment(key, delta)
+}
+
+// Watch delegates to the wrapped store if it supports watches.
+func (s *ReadCacheStore) Watch(ctx context.Context, opts kv.WatchOptions) (<-chan kv.Event, error) {
+	ws, ok := s.store.(kv.WatchStore)
+	if !ok {
+		return nil, kv.ErrNotSupported
+	}
+	return ws.Watch(ctx, opts)
+}
+
+// SetAlias delegates to the wrapped store if it supports aliases.
+func (s *ReadCacheStore) SetAlias(alias, target string) error {
+	as, ok := s.store.(kv.AliasStore)
+	if !ok {
+		return kv.ErrNotSupported
+	}
+	defer s.invalidate(alias)
+	return as.SetAlias(alias, target)
+}
+
+// ResolveAlias delegates to the wrapped store if it supports aliases.
+// Without alias support every key names itself.
+func (s *ReadCacheStore) ResolveAlias(key string) (string, error) {
+	as, ok := s.store.(kv.AliasStore)
+	if !ok {
+		return key, nil
+	}
+	return as.ResolveAlias(key)
+}
+
+// SetIdempotent delegates to the wrapped store if it deduplicates writes.
+func (s *ReadCacheStore) SetIdempotent(ctx context.Context, requestID, key, value string) (uint64, error) {
+	is, ok := s.store.(kv.IdempotentStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	defer s.invalidate(key)
+	return is.SetIdempotent(ctx, requestID, key, value)
+}
+
+// IncrementIdempotent delegates to the wrapped store if it deduplicates
+// writes.
+func (s *ReadCacheStore) IncrementIdempotent(requestID, key string, delta int64) (int64, error) {
+	is, ok := s.store.(kv.IdempotentStore)
+	if !ok {
+		return 0, kv.ErrNotSupported
+	}
+	defer s.invalidate(key)
+	return is.IncrementIdempotent(requestID, key, delta)
+}
+
+// BatchIdempotent delegates to the wrapped store if it deduplicates
+// writes.
+func (s *ReadCacheStore) BatchIdempotent(requestID string, ops []kv.BatchOp) error {
+	is, ok := s.store.(kv.IdempotentStore)
+	if !ok {
+		return kv.ErrNotSupported
+	}
+	defer s.invalidateOps(ops)
+	return is.BatchIdempotent(requestID, ops)
+}
+
+// Batch delegates to the wrapped store if it supports batches.
+func (s *ReadCacheStore) Batch(ops []kv.BatchOp) error {
+	bs, ok := s.store.(kv.BatchStore)
+	if !ok {
+		return kv.ErrNotSupported
+	}
+	defer s.invalidateOps(ops)
+	return bs.Batch(ops)
+}
+
+// Oldest delegates to the wrapped store if it records key timestamps.
+func (s *ReadCacheStore) Oldest(prefix string, byUpdate bool) (string, kv.Entry, bool) {
+	as, ok := s.store.(kv.AgeStore)
+	if !ok {
+		return "", kv.Entry{}, false
+	}
+	return as.Oldest(prefix, byUpdate)
+}
+
+// Newest delegates to the wrapped store if it records key timestamps.
+func (s *ReadCacheStore) Newest(prefix string, byUpdate bool) (string, kv.Entry, bool) {
+	as, ok := s.store.(kv.AgeStore)
+	if !ok {
+		return "", kv.Entry{}, false
+	}
+	return as.Newest(prefix, byUpdate)
+}
+
+// NamespaceUsage delegates to the wrapped store if it tracks usage.
+// Stores without usage tracking report no namespaces.
+func (s *ReadCacheStore) NamespaceUsage() map[string]kv.Usage {
+	us, ok := s.store.(kv.UsageStore)
+	if !ok {
+		return nil
+	}
+	return us.NamespaceUsage()
+}
+
+// Len delegates to the wrapped store if it reports its size, and returns 0
+// otherwise.
+func (s *ReadCacheStore) Len() int {
+	ss, ok := s.store.(kv.SizeStore)
+	if !ok {
+		return 0
+	}
+	return ss.Len()
+}
+
+// ApproxBytes delegates to the wrapped store if it reports its size, and
+// returns 0 otherwise.
+func (s *ReadCacheStore) ApproxBytes() int64 {
+	ss, ok := s.store.(kv.SizeStore)
+	if !ok {
+		return 0
+	}
+	return ss.ApproxBytes()
+}
+
+// SetLabels delegates to the wrapped store if it supports labels. Labels
+// don't change the value, so the cache is left alone.
+func (s *ReadCacheStore) SetLabels(key string, labels map[string]string) (bool, error) {
+	ls, ok := s.store.(kv.LabelStore)
+	if !ok {
+		return false, kv.ErrNotSupported
+	}
+	return ls.SetLabels(key, labels)
+}
+
+// GetWithMeta delegates to the wrapped store if it supports labels,
+// bypassing the cache.
+func (s *ReadCacheStore) GetWithMeta(key string) (kv.Entry, bool) {
+	ls, ok := s.store.(kv.LabelStore)
+	if !ok {
+		return kv.Entry{}, false
+	}
+	return ls.GetWithMeta(key)
+}
+
+// ScanLabel delegates to the wrapped store if it supports labels.
+func (s *ReadCacheStore) ScanLabel(prefix, name, value string, limit int) ([]kv.KeyValue, error) {
+	ls, ok := s.store.(kv.LabelStore)
+	if !ok {
+		return nil, kv.ErrNotSupported
+	}
+	return ls.ScanLabel(prefix, name, value, limit)
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"io"
+	"sync"
+
+	"github.com/hashicorp/raft"
+)
+
+// ReplicationTracker wraps the leader's Raft transport and records, for
+// every follower, the highest log index it has acknowledged and the term
+// it acknowledged it in. hashicorp/raft keeps its own match indexes
+// private, so this is how SetReplicated learns which nodes hold an entry.
+type ReplicationTracker struct {
+	*raft.NetworkTransport
+
+	mu      sync.Mutex
+	peers   map[raft.ServerID]peerProgress
+	changed chan struct{}
+}
+
+type peerProgress struct {
+	term  uint64
+	index uint64
+}
+
+// NewReplicationTracker wraps trans. Pass the tracker to raft.NewRaft in
+// place of trans, and to RaftStore.SetReplicationTracker.
+func NewReplicationTracker(trans *raft.NetworkTransport) *ReplicationTracker {
+	return &ReplicationTracker{
+		NetworkTransport: trans,
+		peers:            make(map[raft.ServerID]peerProgress),
+		changed:          make(chan struct{}),
+	}
+}
+
+// record notes that id acknowledged the log up to index in term.
+func (t *ReplicationTracker) record(id raft.ServerID, term, index uint64) {
+	if index == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	cur := t.peers[id]
+	if term < cur.term || (term == cur.term && index <= cur.index) {
+		return
+	}
+	t.peers[id] = peerProgress{term: term, index: index}
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+// recordAppend notes a successful AppendEntries: the follower's log now
+// matches the leader's up to the last entry sent, or up to the previous
+// entry when none were.
+func (t *ReplicationTracker) recordAppend(id raft.ServerID, args *raft.AppendEntriesRequest, resp *raft.AppendEntriesResponse) {
+	if !resp.Success {
+		return
+	}
+	index := args.PrevLogEntry
+	if n := len(args.Entries); n > 0 {
+		index = args.Entries[n-1].Index
+	}
+	t.record(id, args.Term, index)
+}
+
+// Holding returns how many followers are known to hold the entry at index,
+// appended in term, and a channel closed on the next acknowledgment.
+//
+// A follower counts once it acknowledged index or later in term or a later
+// one: by Raft's log matching, it then holds whatever that leader held up
+// to there, and a leader of a later term holds every committed entry.
+// Acknowledgments from earlier terms may cover entries since overwritten,
+// so they don't count.
+func (t *ReplicationTracker) Holding(index, term uint64) (int, <-chan struct{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := 0
+	for _, p := range t.peers {
+		if p.term >= term && p.index >= index {
+			n++
+		}
+	}
+	return n, t.changed
+}
+
+// AppendEntries sends an AppendEntries RPC and records its acknowledgment.
+func (t *ReplicationTracker) AppendEntries(id raft.ServerID, target raft.ServerAddress, args *raft.AppendEntriesRequest, resp *raft.AppendEntriesResponse) error {
+	if err := t.NetworkTransport.AppendEntries(id, target, args, resp); err != nil {
+		return err
+	}
+	t.recordAppend(id, args, resp)
+	return nil
+}
+
+// AppendEntriesPipeline opens a pipeline whose acknowledgments are
+// recorded as raft consumes them.
+func (t *ReplicationTracker) AppendEntriesPipeline(id raft.ServerID, target raft.ServerAddress) (raft.AppendPipeline, error) {
+	p, err := t.NetworkTransport.AppendEntriesPipeline(id, target)
+	if err != nil {
+		return nil, err
+	}
+	tp := &trackedPipeline{
+		AppendPipeline: p,
+		id:             id,
+		tracker:        t,
+		consumer:       make(chan raft.AppendFuture),
+		done:           make(chan struct{}),
+	}
+	go tp.run()
+	return tp, nil
+}
+
+// InstallSnapshot sends a snapshot and records the follower as holding
+// everything the snapshot covers.
+func (t *ReplicationTracker) InstallSnapshot(id raft.ServerID, target raft.ServerAddress, args *raft.InstallSnapshotRequest, resp *raft.InstallSnapshotResponse, data io.Reader) error {
+	if err := t.NetworkTransport.InstallSnapshot(id, target, args, resp, data); err != nil {
+		return err
+	}
+	if resp.Success {
+		t.record(id, args.Term, args.LastLogIndex)
+	}
+	return nil
+}
+
+// trackedPipeline relays an AppendPipeline's responses to raft, recording
+// each successful one on the way.
+type trackedPipeline struct {
+	raft.AppendPipeline
+	id       raft.ServerID
+	tracker  *ReplicationTracker
+	consumer chan raft.AppendFuture
+	done     chan struct{}
+	once     sync.Once
+}
+
+func (p *trackedPipeline) run() {
+	for {
+		select {
+		case f := <-p.AppendPipeline.Consumer():
+			if f.Error() == nil {
+				p.tracker.recordAppend(p.id, f.Request(), f.Response())
+			}
+			select {
+			case p.consumer <- f:
+			case <-p.done:
+				return
+			}
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// Consumer returns the relayed responses.
+func (p *trackedPipeline) Consumer() <-chan raft.AppendFuture {
+	return p.consumer
+}
+
+// Close closes the wrapped pipeline and stops relaying.
+func (p *trackedPipeline) Close() error {
+	p.once.Do(func() { close(p.done) })
+	return p.AppendPipeline.Close()
+}
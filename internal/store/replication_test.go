@@ -0,0 +1,83 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+func TestReplicationTrackerHolding(t *testing.T) {
+	tests := []struct {
+		name  string
+		acks  []peerProgress // one per follower
+		index uint64
+		term  uint64
+		want  int
+	}{
+		{"none acknowledged", nil, 10, 2, 0},
+		{"caught up", []peerProgress{{2, 10}, {2, 12}}, 10, 2, 2},
+		{"one behind", []peerProgress{{2, 10}, {2, 9}}, 10, 2, 1},
+		{"later term counts", []peerProgress{{3, 10}}, 10, 2, 1},
+		// An earlier leader's index 10 may hold a different entry.
+		{"earlier term ignored", []peerProgress{{1, 15}}, 10, 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &ReplicationTracker{peers: make(map[raft.ServerID]peerProgress), changed: make(chan struct{})}
+			for i, p := range tt.acks {
+				tr.record(raft.ServerID(rune('a'+i)), p.term, p.index)
+			}
+			if got, _ := tr.Holding(tt.index, tt.term); got != tt.want {
+				t.Errorf("Holding(%d, %d) = %d, want %d", tt.index, tt.term, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplicationTrackerKeepsNewest(t *testing.T) {
+	tr := &ReplicationTracker{peers: make(map[raft.ServerID]peerProgress), changed: make(chan struct{})}
+	tr.record("a", 2, 10)
+	tr.record("a", 2, 8) // a reordered, older response
+	tr.record("a", 1, 20)
+	if got := tr.peers["a"]; got != (peerProgress{2, 10}) {
+		t.Errorf("progress = %+v, want {2 10}", got)
+	}
+	_, changed := tr.Holding(11, 2)
+	tr.record("a", 3, 11)
+	select {
+	case <-changed:
+	default:
+		t.Error("a newer acknowledgment did not signal waiters")
+	}
+}
+
+func TestSetReplicated(t *testing.T) {
+	c := newTestCluster(t, 3)
+	leader := c.leader()
+
+	n, err := leader.rs.SetReplicated("k", "v", 3)
+	if err != nil {
+		t.Fatalf("SetReplicated: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("confirmed on %d nodes, want 3", n)
+	}
+
+	// With a follower down the write still commits on the remaining
+	// quorum, and is reported on 2 nodes rather than failed.
+	down := c.followers()[0]
+	down.raft.Shutdown().Error()
+	down.raft = nil
+	leader.rs.SetApplyTimeout(300 * time.Millisecond)
+	n, err = leader.rs.SetReplicated("k", "v2", 3)
+	if err != nil {
+		t.Fatalf("SetReplicated with a follower down: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("confirmed on %d nodes, want 2", n)
+	}
+	if v, _ := leader.rs.Get("k"); v != "v2" {
+		t.Errorf("Get = %q, want v2", v)
+	}
+}
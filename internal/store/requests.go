@@ -0,0 +1,68 @@
+package store
+
+import "container/list"
+
+// maxRequestIDs is how many request IDs a RaftStore remembers for
+// deduplication. Eviction happens in Apply, so every replica must use the
+// same bound; it is fixed rather than configurable for that reason.
+const maxRequestIDs = 10000
+
+// appliedRequest is the outcome of a write that carried a request ID: the
+// index it was applied at and, for incr, the resulting sum. Apply returns
+// it in place of the usual response when the ID comes round again.
+type appliedRequest struct {
+	ID    string `json:"id"`
+	Index uint64 `json:"i"`
+	Sum   int64  `json:"s,omitempty"`
+}
+
+// requestLog remembers the most recently used request IDs, up to
+// maxRequestIDs. It is only touched by Apply, Snapshot and Restore, which
+// raft never runs concurrently, so it has no lock of its own.
+type requestLog struct {
+	order *list.List // of appliedRequest, most recently used at the back
+	byID  map[string]*list.Element
+}
+
+func newRequestLog() *requestLog {
+	return &requestLog{order: list.New(), byID: make(map[string]*list.Element)}
+}
+
+// get returns the outcome recorded for id and marks it as just used.
+func (l *requestLog) get(id string) (appliedRequest, bool) {
+	e, ok := l.byID[id]
+	if !ok {
+		return appliedRequest{}, false
+	}
+	l.order.MoveToBack(e)
+	return e.Value.(appliedRequest), true
+}
+
+// add records r, forgetting the least recently used IDs beyond
+// maxRequestIDs.
+func (l *requestLog) add(r appliedRequest) {
+	l.byID[r.ID] = l.order.PushBack(r)
+	for l.order.Len() > maxRequestIDs {
+		oldest := l.order.Remove(l.order.Front()).(appliedRequest)
+		delete(l.byID, oldest.ID)
+	}
+}
+
+// list returns the recorded outcomes, least recently used first.
+func (l *requestLog) list() []appliedRequest {
+	rs := make([]appliedRequest, 0, l.order.Len())
+	for e := l.order.Front(); e != nil; e = e.Next() {
+		rs = append(rs, e.Value.(appliedRequest))
+	}
+	return rs
+}
+
+// reset replaces the log's contents with rs, given least recently used
+// first.
+func (l *requestLog) reset(rs []appliedRequest) {
+	l.order.Init()
+	l.byID = make(map[string]*list.Element, len(rs))
+	for _, r := range rs {
+		l.add(r)
+	}
+}
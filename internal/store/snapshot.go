@@ -0,0 +1,229 @@
+package store
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/heysubinoy/pyazdb/pkg/kv"
+)
+
+// Snapshot stream formats. Every snapshot starts with one format byte so
+// Restore can decode it regardless of the compression the writing node
+// was configured with.
+const (
+	snapshotFormatRaw  byte = 0
+	snapshotFormatGzip byte = 1
+)
+
+// Snapshot compression settings accepted by SetSnapshotCompression.
+const (
+	SnapshotCompressionNone = "none"
+	SnapshotCompressionGzip = "gzip"
+)
+
+// nopWriteCloser adapts a writer whose Close has nothing to flush.
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// newSnapshotWriter writes the format byte for compression to w and returns
+// a writer for the snapshot body. Closing it flushes the compressor but
+// leaves w open.
+func newSnapshotWriter(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case "", SnapshotCompressionNone:
+		if _, err := w.Write([]byte{snapshotFormatRaw}); err != nil {
+			return nil, err
+		}
+		return nopWriteCloser{w}, nil
+	case SnapshotCompressionGzip:
+		if _, err := w.Write([]byte{snapshotFormatGzip}); err != nil {
+			return nil, err
+		}
+		return gzip.NewWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unknown snapshot compression %q", compression)
+	}
+}
+
+// newSnapshotReader reads the format byte from r and returns a reader for
+// the decoded snapshot body. Truncated or corrupt compressed streams
+// surface as errors from Read rather than as silently short data.
+func newSnapshotReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	format, err := br.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot header: %w", err)
+	}
+	switch format {
+	case snapshotFormatRaw:
+		return br, nil
+	case snapshotFormatGzip:
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("corrupt gzip snapshot: %w", err)
+		}
+		return corruptOnError{zr}, nil
+	default:
+		return nil, fmt.Errorf("unknown snapshot format byte %d", format)
+	}
+}
+
+// corruptOnError labels decompression failures so a bad snapshot is
+// reported as such instead of as a generic decode error.
+type corruptOnError struct{ r io.Reader }
+
+func (c corruptOnError) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		err = fmt.Errorf("corrupt or truncated snapshot: %w", err)
+	}
+	return n, err
+}
+
+// snapshotEntry is one key in a snapshot body, with its metadata.
+// Times are unix nanoseconds; zero means unset.
+type snapshotEntry struct {
+	Key       string            `json:"k"`
+	Value     string            `json:"v"`
+	ExpiresAt int64             `json:"e,omitempty"`
+	PurgeAt   int64             `json:"p,omitempty"` // set for soft-deleted keys
+	Labels    map[string]string `json:"l,omitempty"`
+	Created   int64             `json:"c,omitempty"`
+	Updated   int64             `json:"u,omitempty"`
+}
+
+// snapshotState is the full contents of a MemStore. Namespace usage is
+// derived from the entries and rebuilt on restore. Requests holds the
+// RaftStore's remembered request IDs, least recently used first, Index
+// the last log index the snapshot includes and AppendedAt when that entry
+// was appended; older snapshots have none of them.
+type snapshotState struct {
+	Entries    []snapshotEntry   `json:"entries"`
+	Aliases    map[string]string `json:"aliases,omitempty"`
+	Requests   []appliedRequest  `json:"requests,omitempty"`
+	Index      uint64            `json:"index,omitempty"`
+	AppendedAt int64             `json:"appended_at,omitempty"`
+}
+
+// snapshotData copies the store's contents with every shard read-locked,
+// so concurrent writers cannot leave the copy half-updated.
+func (s *MemStore) snapshotData() snapshotState {
+	s.aliasMu.RLock()
+	defer s.aliasMu.RUnlock()
+	s.rlockAll()
+	defer s.runlockAll()
+
+	var state snapshotState
+	for _, sh := range s.shards {
+		for key, value := range sh.data {
+			e := snapshotEntry{
+				Key:     key,
+				Value:   value,
+				Labels:  copyLabels(sh.labels[key]),
+				Created: unixNanoOrZero(sh.times[key].created),
+				Updated: unixNanoOrZero(sh.times[key].updated),
+			}
+			if exp, ok := sh.expires[key]; ok {
+				e.ExpiresAt = exp.UnixNano()
+			}
+			if purgeAt, ok := sh.tombstones[key]; ok {
+				e.PurgeAt = purgeAt.UnixNano()
+			}
+			state.Entries = append(state.Entries, e)
+		}
+	}
+	if len(s.aliases) > 0 {
+		state.Aliases = make(map[string]string, len(s.aliases))
+		for alias, target := range s.aliases {
+			state.Aliases[alias] = target
+		}
+	}
+	return state
+}
+
+// restoreData replaces the store's contents with state.
+func (s *MemStore) restoreData(state snapshotState) {
+	s.aliasMu.Lock()
+	defer s.aliasMu.Unlock()
+	s.lockAll()
+	defer s.unlockAll()
+
+	for _, sh := range s.shards {
+		sh.data = make(map[string]string)
+		sh.expires = make(map[string]time.Time)
+		sh.tombstones = make(map[string]time.Time)
+		sh.labels = make(map[string]map[string]string)
+		sh.usage = make(map[string]kv.Usage)
+		sh.times = make(map[string]keyTimes)
+	}
+	for _, e := range state.Entries {
+		sh := s.shardFor(e.Key)
+		sh.put(e.Key, e.Value, time.Time{})
+		sh.times[e.Key] = keyTimes{created: timeOrZero(e.Created), updated: timeOrZero(e.Updated)}
+		if e.ExpiresAt != 0 {
+			sh.expires[e.Key] = time.Unix(0, e.ExpiresAt)
+		}
+		if e.PurgeAt != 0 {
+			sh.tombstones[e.Key] = time.Unix(0, e.PurgeAt)
+		}
+		if len(e.Labels) > 0 {
+			sh.labels[e.Key] = e.Labels
+		}
+	}
+	s.aliases = make(map[string]string, len(state.Aliases))
+	for alias, target := range state.Aliases {
+		s.aliases[alias] = target
+	}
+}
+
+func unixNanoOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func timeOrZero(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+// fsmSnapshot is a point-in-time copy of the store, written out by Persist
+// while Apply carries on.
+type fsmSnapshot struct {
+	state       snapshotState
+	compression string
+}
+
+// Persist encodes the snapshot into sink, cancelling it on any error so raft
+// never keeps a partial snapshot.
+func (f *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
+	if err := f.write(sink); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (f *fsmSnapshot) write(sink io.Writer) error {
+	w, err := newSnapshotWriter(sink, f.compression)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(w).Encode(f.state); err != nil {
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+	return w.Close()
+}
+
+// Release is a no-op; the copy is garbage collected with the snapshot.
+func (f *fsmSnapshot) Release() {}
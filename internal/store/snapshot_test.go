@@ -0,0 +1,154 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+func TestRestartFromSnapshotWithTruncatedLog(t *testing.T) {
+	c := newTestCluster(t, 1)
+	node := c.leader()
+
+	for i := 0; i < 50; i++ {
+		if err := node.rs.Set(fmt.Sprintf("k%d", i), fmt.Sprintf("v%d", i)); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+	}
+	future := node.raft.Snapshot()
+	if err := future.Error(); err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	meta, rc, err := future.Open()
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	rc.Close()
+
+	// Drop every log entry the snapshot covers, so the restarted node can
+	// only get its data back from the snapshot.
+	node.raft.Shutdown().Error()
+	first, _ := node.logs.FirstIndex()
+	if err := node.logs.DeleteRange(first, meta.Index); err != nil {
+		t.Fatalf("DeleteRange: %v", err)
+	}
+	trans, err := raft.NewTCPTransport("127.0.0.1:0", nil, 3, time.Second, io.Discard)
+	if err != nil {
+		t.Fatalf("transport: %v", err)
+	}
+	node.trans = trans
+	c.start(node)
+
+	node.rs.applyMu.Lock()
+	applied := node.rs.lastApplied
+	node.rs.applyMu.Unlock()
+	if applied != meta.Index {
+		t.Errorf("lastApplied = %d after restore, want the snapshot's %d", applied, meta.Index)
+	}
+	for i := 0; i < 50; i++ {
+		key, want := fmt.Sprintf("k%d", i), fmt.Sprintf("v%d", i)
+		if v, ok := node.rs.Get(key); !ok || v != want {
+			t.Errorf("Get(%s) = %q, %v; want %q", key, v, ok, want)
+		}
+	}
+
+	// The restarted node keeps taking writes on top of the snapshot.
+	c.leader()
+	if err := node.rs.Set("after", "restart"); err != nil {
+		t.Fatalf("Set after restart: %v", err)
+	}
+	if v, _ := node.rs.Get("after"); v != "restart" {
+		t.Errorf("Get(after) = %q, want restart", v)
+	}
+}
+
+// snapshotBytes encodes rs's current state as Persist would.
+func snapshotBytes(t testing.TB, rs *RaftStore, compression string) []byte {
+	t.Helper()
+	if err := rs.SetSnapshotCompression(compression); err != nil {
+		t.Fatalf("SetSnapshotCompression: %v", err)
+	}
+	snap, err := rs.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := snap.(*fsmSnapshot).write(&buf); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// fillStore sets n keys with compressible values.
+func fillStore(rs *RaftStore, n int) {
+	for i := 0; i < n; i++ {
+		rs.store.Set(fmt.Sprintf("user/%06d", i), strings.Repeat(fmt.Sprintf(`{"id":%d,"name":"user"}`, i), 4))
+	}
+}
+
+func TestRestoreRejectsDamagedGzipSnapshot(t *testing.T) {
+	src := NewRaftStore(NewMemStore(), nil)
+	fillStore(src, 1000)
+	good := snapshotBytes(t, src, SnapshotCompressionGzip)
+
+	flipped := bytes.Clone(good)
+	flipped[len(flipped)/2] ^= 0xff
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"format byte only", good[:1]},
+		{"cut inside the gzip header", good[:5]},
+		{"cut in half", good[:len(good)/2]},
+		{"missing the gzip trailer", good[:len(good)-8]},
+		{"missing the last byte", good[:len(good)-1]},
+		{"corrupt byte", flipped},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := NewRaftStore(NewMemStore(), nil)
+			dst.store.Set("existing", "v")
+			if err := dst.Restore(io.NopCloser(bytes.NewReader(tt.data))); err == nil {
+				t.Fatal("Restore of a damaged snapshot succeeded")
+			}
+			// Nothing from the damaged snapshot may have been applied.
+			if n := dst.store.Len(); n != 1 {
+				t.Errorf("store holds %d keys after a failed restore, want the 1 it had", n)
+			}
+			if v, _ := dst.store.Get("existing"); v != "v" {
+				t.Errorf("existing key = %q after a failed restore, want v", v)
+			}
+		})
+	}
+
+	dst := NewRaftStore(NewMemStore(), nil)
+	if err := dst.Restore(io.NopCloser(bytes.NewReader(good))); err != nil {
+		t.Fatalf("Restore of the intact snapshot: %v", err)
+	}
+	if n := dst.store.Len(); n != 1000 {
+		t.Errorf("restored %d keys, want 1000", n)
+	}
+}
+
+func BenchmarkSnapshotRestore(b *testing.B) {
+	src := NewRaftStore(NewMemStore(), nil)
+	fillStore(src, 100000)
+	for _, compression := range []string{SnapshotCompressionNone, SnapshotCompressionGzip} {
+		b.Run(compression, func(b *testing.B) {
+			data := snapshotBytes(b, src, compression)
+			b.ReportMetric(float64(len(data)), "snapshot-bytes")
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				dst := NewRaftStore(NewMemStore(), nil)
+				if err := dst.Restore(io.NopCloser(bytes.NewReader(data))); err != nil {
+					b.Fatalf("Restore: %v", err)
+				}
+			}
+		})
+	}
+}
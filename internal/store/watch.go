@@ -0,0 +1,116 @@
+package store
+
+import (
+	"context"
+	"sync"
+
+	"github.com/heysubinoy/pyazdb/pkg/kv"
+)
+
+// watchBuffer is how many live events a watcher may fall behind by before
+// it is dropped. Publishing never blocks the FSM on a slow consumer.
+const watchBuffer = 1024
+
+// watcher is one registered watch.
+type watcher struct {
+	opts kv.WatchOptions
+	ch   chan kv.Event
+}
+
+// watchHub fans applied writes out to watchers.
+type watchHub struct {
+	mu       sync.Mutex
+	watchers map[*watcher]struct{}
+}
+
+func newWatchHub() *watchHub {
+	return &watchHub{watchers: make(map[*watcher]struct{})}
+}
+
+func (h *watchHub) add(opts kv.WatchOptions) *watcher {
+	w := &watcher{opts: opts, ch: make(chan kv.Event, watchBuffer)}
+	h.mu.Lock()
+	h.watchers[w] = struct{}{}
+	h.mu.Unlock()
+	return w
+}
+
+// remove unregisters w and closes its channel, if publish hasn't already.
+func (h *watchHub) remove(w *watcher) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.watchers[w]; ok {
+		delete(h.watchers, w)
+		close(w.ch)
+	}
+}
+
+// closeAll drops every watcher.
+func (h *watchHub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for w := range h.watchers {
+		delete(h.watchers, w)
+		close(w.ch)
+	}
+}
+
+// publish delivers events to every matching watcher. A watcher whose
+// buffer is full is dropped and its channel closed.
+func (h *watchHub) publish(events []kv.Event) {
+	if len(events) == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for w := range h.watchers {
+		for _, e := range events {
+			if !w.opts.Matches(e.Key) {
+				continue
+			}
+			select {
+			case w.ch <- e:
+			default:
+				delete(h.watchers, w)
+				close(w.ch)
+			}
+			if _, ok := h.watchers[w]; !ok {
+				break
+			}
+		}
+	}
+}
+
+// stream sends initial, then w's live events, to the returned channel until
+// ctx is done or w is dropped. Live events queue in w's buffer while the
+// initial events are being consumed.
+func (h *watchHub) stream(ctx context.Context, w *watcher, initial []kv.Event) <-chan kv.Event {
+	out := make(chan kv.Event)
+	go func() {
+		defer close(out)
+		defer h.remove(w)
+		for _, e := range initial {
+			select {
+			case out <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+		for {
+			select {
+			case e, ok := <-w.ch:
+				if !ok {
+					return
+				}
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
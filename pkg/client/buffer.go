@@ -0,0 +1,213 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/heysubinoy/pyazdb/api/proto"
+)
+
+// Buffered write defaults.
+const (
+	DefaultBufferMaxOps        = 500
+	DefaultBufferFlushInterval = 100 * time.Millisecond
+)
+
+// ErrWriterClosed is returned by a BufferedWriter after Close.
+var ErrWriterClosed = errors.New("client: buffered writer is closed")
+
+// BufferOptions configures a BufferedWriter.
+type BufferOptions struct {
+	// MaxOps flushes the buffer once this many writes are pending.
+	// 0 uses DefaultBufferMaxOps.
+	MaxOps int
+
+	// FlushInterval flushes pending writes at least this often.
+	// 0 uses DefaultBufferFlushInterval.
+	FlushInterval time.Duration
+
+	// OnError, if set, receives errors from background flushes along with
+	// the number of writes lost. Otherwise the error is returned by the
+	// next Set, Delete or Flush.
+	OnError func(err error, lost int)
+}
+
+// BufferedWriter accumulates sets and deletes and sends them as atomic
+// batches, either when the buffer fills or on a timer. It trades immediate
+// acknowledgment for throughput: a nil error from Set or Delete only means
+// the write was buffered, and buffered writes are lost if the process
+// exits before Flush or Close.
+type BufferedWriter struct {
+	c    *Client
+	opts BufferOptions
+
+	mu      sync.Mutex
+	pending []*proto.BatchOp
+	err     error // unreported background flush error
+	closed  bool
+
+	flushMu sync.Mutex // keeps batches in submission order
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewBufferedWriter starts a buffered writer that sends batches through the
+// client's preferred node.
+func (c *Client) NewBufferedWriter(opts BufferOptions) *BufferedWriter {
+	if opts.MaxOps <= 0 {
+		opts.MaxOps = DefaultBufferMaxOps
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultBufferFlushInterval
+	}
+	b := &BufferedWriter{
+		c:    c,
+		opts: opts,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Set buffers a set of key to value.
+func (b *BufferedWriter) Set(key, value string) error {
+	return b.add(&proto.BatchOp{Op: "set", Key: key, Value: value})
+}
+
+// Delete buffers a delete of key.
+func (b *BufferedWriter) Delete(key string) error {
+	return b.add(&proto.BatchOp{Op: "delete", Key: key})
+}
+
+// Flush sends all pending writes and waits for the batch to be applied.
+func (b *BufferedWriter) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	err := b.takeErr()
+	b.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return b.flush(ctx)
+}
+
+// Close stops the flush timer and sends any pending writes.
+// Further writes return ErrWriterClosed.
+func (b *BufferedWriter) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrWriterClosed
+	}
+	b.closed = true
+	err := b.takeErr()
+	b.mu.Unlock()
+
+	close(b.stop)
+	<-b.done
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.c.opts.AttemptTimeout)
+	defer cancel()
+	if flushErr := b.flush(ctx); flushErr != nil {
+		return flushErr
+	}
+	return err
+}
+
+func (b *BufferedWriter) add(op *proto.BatchOp) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrWriterClosed
+	}
+	if err := b.takeErr(); err != nil {
+		b.mu.Unlock()
+		return err
+	}
+	b.pending = append(b.pending, op)
+	full := len(b.pending) >= b.opts.MaxOps
+	b.mu.Unlock()
+
+	if !full {
+		return nil
+	}
+	// Flushing in the caller applies backpressure when writes outpace the cluster.
+	ctx, cancel := context.WithTimeout(context.Background(), b.c.opts.AttemptTimeout)
+	defer cancel()
+	return b.flush(ctx)
+}
+
+// run flushes on every tick until Close.
+func (b *BufferedWriter) run() {
+	defer close(b.done)
+	ticker := time.NewTicker(b.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), b.c.opts.AttemptTimeout)
+			b.flushBackground(ctx)
+			cancel()
+		}
+	}
+}
+
+// flushBackground flushes and routes any error to OnError or the next call.
+func (b *BufferedWriter) flushBackground(ctx context.Context) {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	ops := b.swap()
+	if len(ops) == 0 {
+		return
+	}
+	if err := b.send(ctx, ops); err != nil {
+		if b.opts.OnError != nil {
+			b.opts.OnError(err, len(ops))
+			return
+		}
+		b.mu.Lock()
+		if b.err == nil {
+			b.err = err
+		}
+		b.mu.Unlock()
+	}
+}
+
+// flush sends pending writes and returns the error directly.
+func (b *BufferedWriter) flush(ctx context.Context) error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	ops := b.swap()
+	if len(ops) == 0 {
+		return nil
+	}
+	return b.send(ctx, ops)
+}
+
+func (b *BufferedWriter) swap() []*proto.BatchOp {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ops := b.pending
+	b.pending = nil
+	return ops
+}
+
+func (b *BufferedWriter) send(ctx context.Context, ops []*proto.BatchOp) error {
+	_, err := b.c.clients[0].Batch(ctx, &proto.BatchRequest{Ops: ops})
+	return err
+}
+
+// takeErr returns and clears the pending background error.
+// Callers must hold b.mu.
+func (b *BufferedWriter) takeErr() error {
+	err := b.err
+	b.err = nil
+	return err
+}
@@ -0,0 +1,144 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/heysubinoy/pyazdb/api/proto"
+	"google.golang.org/grpc"
+)
+
+// batchRecorder is a node that records the batches it is sent.
+type batchRecorder struct {
+	proto.KVServiceClient
+
+	mu      sync.Mutex
+	batches [][]*proto.BatchOp
+	err     error
+}
+
+func (r *batchRecorder) Batch(ctx context.Context, req *proto.BatchRequest, opts ...grpc.CallOption) (*proto.BatchResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	r.batches = append(r.batches, req.Ops)
+	return &proto.BatchResponse{}, nil
+}
+
+func (r *batchRecorder) sent() (batches, ops int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, b := range r.batches {
+		ops += len(b)
+	}
+	return len(r.batches), ops
+}
+
+func newRecordingClient(node *batchRecorder) *Client {
+	return &Client{
+		nodes:   []string{"node"},
+		clients: []proto.KVServiceClient{node},
+		opts:    Options{AttemptTimeout: time.Second},
+	}
+}
+
+func TestBufferedWriterFlushes(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        BufferOptions
+		writes      int
+		wait        time.Duration
+		close       bool
+		wantBatches int
+	}{
+		{"on close", BufferOptions{MaxOps: 100, FlushInterval: time.Hour}, 10, 0, true, 1},
+		{"when full", BufferOptions{MaxOps: 4, FlushInterval: time.Hour}, 8, 0, false, 2},
+		{"full then rest on close", BufferOptions{MaxOps: 4, FlushInterval: time.Hour}, 10, 0, true, 3},
+		{"on the timer", BufferOptions{MaxOps: 100, FlushInterval: 10 * time.Millisecond}, 5, 200 * time.Millisecond, false, 1},
+		{"nothing pending on close", BufferOptions{}, 0, 0, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &batchRecorder{}
+			w := newRecordingClient(node).NewBufferedWriter(tt.opts)
+			for i := 0; i < tt.writes; i++ {
+				if err := w.Set("k", "v"); err != nil {
+					t.Fatalf("Set: %v", err)
+				}
+			}
+			time.Sleep(tt.wait)
+			if tt.close {
+				if err := w.Close(); err != nil {
+					t.Fatalf("Close: %v", err)
+				}
+			} else {
+				defer w.Close()
+			}
+			batches, ops := node.sent()
+			if batches != tt.wantBatches {
+				t.Errorf("sent %d batches, want %d", batches, tt.wantBatches)
+			}
+			if tt.close && ops != tt.writes {
+				t.Errorf("sent %d writes by Close, want all %d", ops, tt.writes)
+			}
+		})
+	}
+}
+
+func TestBufferedWriterAfterClose(t *testing.T) {
+	w := newRecordingClient(&batchRecorder{}).NewBufferedWriter(BufferOptions{})
+	w.Close()
+	if err := w.Set("k", "v"); !errors.Is(err, ErrWriterClosed) {
+		t.Errorf("Set after Close = %v, want ErrWriterClosed", err)
+	}
+	if err := w.Close(); !errors.Is(err, ErrWriterClosed) {
+		t.Errorf("second Close = %v, want ErrWriterClosed", err)
+	}
+}
+
+func TestBufferedWriterBackgroundErrors(t *testing.T) {
+	failed := errors.New("leader unavailable")
+
+	t.Run("returned by the next write", func(t *testing.T) {
+		node := &batchRecorder{err: failed}
+		w := newRecordingClient(node).NewBufferedWriter(BufferOptions{FlushInterval: 10 * time.Millisecond})
+		defer w.Close()
+		w.Set("k", "v")
+		deadline := time.Now().Add(time.Second)
+		for {
+			err := w.Set("k", "v")
+			if errors.Is(err, failed) {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("background flush error never surfaced, last Set = %v", err)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+
+	t.Run("sent to OnError", func(t *testing.T) {
+		node := &batchRecorder{err: failed}
+		lost := make(chan int, 1)
+		w := newRecordingClient(node).NewBufferedWriter(BufferOptions{
+			FlushInterval: 10 * time.Millisecond,
+			OnError:       func(err error, n int) { lost <- n },
+		})
+		defer w.Close()
+		w.Set("a", "1")
+		w.Delete("b")
+		select {
+		case n := <-lost:
+			if n != 2 {
+				t.Errorf("OnError lost = %d, want 2", n)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("OnError was not called")
+		}
+	})
+}
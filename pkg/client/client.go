@@ -0,0 +1,161 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/heysubinoy/pyazdb/api/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// DefaultAttemptTimeout bounds a single read attempt against one node.
+const DefaultAttemptTimeout = 2 * time.Second
+
+// Options configures read failover.
+type Options struct {
+	// MaxAttempts is how many nodes a read tries before giving up.
+	// 0 tries every node once.
+	MaxAttempts int
+
+	// AttemptTimeout bounds each attempt. 0 uses DefaultAttemptTimeout.
+	AttemptTimeout time.Duration
+
+	// Credentials secures the node connections. nil connects without
+	// transport security.
+	Credentials credentials.TransportCredentials
+}
+
+// Client talks to a PyazDB cluster over gRPC.
+// Reads go to the preferred node (the first in the list) and fail over to
+// the others in order when an attempt errors or times out. Writes go to the
+// preferred node only; any node forwards them to the leader.
+type Client struct {
+	nodes   []string
+	conns   []*grpc.ClientConn
+	clients []proto.KVServiceClient
+	opts    Options
+}
+
+// New creates a client for the given gRPC node addresses (e.g. from mandi
+// or the Raft configuration). Connections are established lazily.
+func New(nodes []string, opts Options) (*Client, error) {
+	if len(nodes) == 0 {
+		return nil, errors.New("client: at least one node address is required")
+	}
+	if opts.MaxAttempts <= 0 || opts.MaxAttempts > len(nodes) {
+		opts.MaxAttempts = len(nodes)
+	}
+	if opts.AttemptTimeout <= 0 {
+		opts.AttemptTimeout = DefaultAttemptTimeout
+	}
+
+	if opts.Credentials == nil {
+		opts.Credentials = insecure.NewCredentials()
+	}
+
+	c := &Client{nodes: nodes, opts: opts}
+	for _, addr := range nodes {
+		conn, err := grpc.NewClient("passthrough:///"+addr, grpc.WithTransportCredentials(opts.Credentials))
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("client: failed to connect to %s: %w", addr, err)
+		}
+		c.conns = append(c.conns, conn)
+		c.clients = append(c.clients, proto.NewKVServiceClient(conn))
+	}
+	return c, nil
+}
+
+// Close closes all node connections.
+func (c *Client) Close() error {
+	var firstErr error
+	for _, conn := range c.conns {
+		if err := conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Get reads key, trying up to MaxAttempts nodes in order.
+// It stops early if ctx is done. The returned error wraps the last failure.
+func (c *Client) Get(ctx context.Context, key string) (string, bool, error) {
+	var lastErr error
+	for i := 0; i < c.opts.MaxAttempts; i++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, c.opts.AttemptTimeout)
+		resp, err := c.clients[i].Get(attemptCtx, &proto.GetRequest{Key: key})
+		cancel()
+		if err == nil {
+			return resp.Value, resp.Found, nil
+		}
+		lastErr = fmt.Errorf("%s: %w", c.nodes[i], err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return "", false, fmt.Errorf("client: get failed: %w", lastErr)
+}
+
+// Set writes key via the preferred node.
+func (c *Client) Set(ctx context.Context, key, value string) error {
+	_, err := c.clients[0].Set(ctx, &proto.SetRequest{Key: key, Value: value})
+	return err
+}
+
+// Delete removes key via the preferred node.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	_, err := c.DeleteExisted(ctx, key)
+	return err
+}
+
+// DeleteExisted removes key via the preferred node and reports whether it
+// existed, as judged by the leader when the delete was applied.
+func (c *Client) DeleteExisted(ctx context.Context, key string) (bool, error) {
+	resp, err := c.clients[0].Delete(ctx, &proto.DeleteRequest{Key: key})
+	if err != nil {
+		return false, err
+	}
+	return resp.Existed, nil
+}
+
+// NodeStatus is one node's answer to Ping.
+type NodeStatus struct {
+	Addr       string
+	NodeID     string
+	RaftState  string // "Leader", "Follower", "Candidate", "Shutdown" or "single-node"
+	LeaderAddr string // the leader's gRPC address, empty if the node knows of none
+	Err        error  // set when the node did not answer
+}
+
+// Ping asks every node for its status at once, each attempt bounded by
+// AttemptTimeout, and returns one NodeStatus per node in the order given
+// to New. Nodes answer locally, so a follower cut off from the leader
+// still reports itself.
+func (c *Client) Ping(ctx context.Context) []NodeStatus {
+	out := make([]NodeStatus, len(c.nodes))
+	var wg sync.WaitGroup
+	for i := range c.nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attemptCtx, cancel := context.WithTimeout(ctx, c.opts.AttemptTimeout)
+			defer cancel()
+			out[i].Addr = c.nodes[i]
+			resp, err := c.clients[i].Ping(attemptCtx, &proto.PingRequest{})
+			if err != nil {
+				out[i].Err = err
+				return
+			}
+			out[i].NodeID = resp.NodeId
+			out[i].RaftState = resp.RaftState
+			out[i].LeaderAddr = resp.LeaderAddr
+		}()
+	}
+	wg.Wait()
+	return out
+}
@@ -2,20 +2,199 @@ package config
 
 import (
 	"fmt"
+	"math"
+	"net"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/heysubinoy/pyazdb/pkg/kv"
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	NodeID     string `yaml:"node_id"`
-	RaftAddr   string `yaml:"raft_addr"`
-	RaftData   string `yaml:"raft_data"`
-	RaftLeader bool   `yaml:"raft_leader"`
-	GRPCAddr   string `yaml:"grpc_addr"`
-	HTTPAddr   string `yaml:"http_addr"`
-	MandiAddr  string `yaml:"mandi_addr"`
+	NodeID     string `yaml:"node_id" json:"node_id"`
+	RaftAddr   string `yaml:"raft_addr" json:"raft_addr"`
+	RaftData   string `yaml:"raft_data" json:"raft_data"`
+	RaftLeader bool   `yaml:"raft_leader" json:"raft_leader"`
+	GRPCAddr   string `yaml:"grpc_addr" json:"grpc_addr"`
+	HTTPAddr   string `yaml:"http_addr" json:"http_addr"`
+	MandiAddr  string `yaml:"mandi_addr" json:"mandi_addr"`
+	AdminToken string `yaml:"admin_token" json:"admin_token"`
+	MandiToken string `yaml:"mandi_token" json:"mandi_token"`
+
+	// AdminAddr, if set, serves /metrics, /stats and /healthz on their own
+	// listener instead of HTTPAddr, so they can be firewalled separately.
+	AdminAddr string `yaml:"admin_addr" json:"admin_addr"`
+
+	// StorageBackend picks where data lives: "mem" (default, replicated
+	// with Raft), "bolt" (a file at StoragePath, without Raft) or "cache"
+	// (in memory without Raft, evicting the least recently used keys past
+	// StorageMaxEntries).
+	StorageBackend    string `yaml:"storage_backend" json:"storage_backend"`
+	StoragePath       string `yaml:"storage_path" json:"storage_path"`
+	StorageMaxEntries int    `yaml:"storage_max_entries" json:"storage_max_entries"`
+
+	// TLSCertFile and TLSKeyFile serve gRPC over TLS; TLSCAFile verifies
+	// the leader when forwarding (default: system roots). All empty = insecure.
+	TLSCertFile string `yaml:"tls_cert_file" json:"tls_cert_file"`
+	TLSKeyFile  string `yaml:"tls_key_file" json:"tls_key_file"`
+	TLSCAFile   string `yaml:"tls_ca_file" json:"tls_ca_file"`
+
+	// TTLJitterPercent spreads key TTLs by up to ±this percentage (0 = off).
+	TTLJitterPercent int `yaml:"ttl_jitter_percent" json:"ttl_jitter_percent"`
+
+	// SoftDeleteWindow keeps deleted keys recoverable for this long (0 = hard deletes).
+	SoftDeleteWindow time.Duration `yaml:"soft_delete_window" json:"soft_delete_window"`
+
+	// MaxForwardHops bounds leader forwarding before a request is rejected as a loop (0 = default of 3).
+	MaxForwardHops int `yaml:"max_forward_hops" json:"max_forward_hops"`
+
+	// MandiStartupTimeout is how long a joining node retries mandi leader
+	// discovery at startup before giving up (0 = don't wait).
+	MandiStartupTimeout time.Duration `yaml:"mandi_startup_timeout" json:"mandi_startup_timeout"`
+	// JoinRequestTTL is how long mandi keeps this node's join request, in
+	// whole seconds up to an hour (0 = mandi's default of 30s).
+	JoinRequestTTL time.Duration `yaml:"join_request_ttl" json:"join_request_ttl"`
+	// FallbackLeaderHTTPAddr and FallbackLeaderGRPCAddr are used for leader
+	// forwarding whenever mandi has no leader to offer.
+	FallbackLeaderHTTPAddr string `yaml:"fallback_leader_http_addr" json:"fallback_leader_http_addr"`
+	FallbackLeaderGRPCAddr string `yaml:"fallback_leader_grpc_addr" json:"fallback_leader_grpc_addr"`
+
+	// ListenBacklog sets the accept queue length of the HTTP and gRPC listeners (0 = OS default).
+	ListenBacklog int `yaml:"listen_backlog" json:"listen_backlog"`
+	// ReusePort sets SO_REUSEPORT on the HTTP and gRPC listeners.
+	ReusePort bool `yaml:"reuse_port" json:"reuse_port"`
+
+	// ShutdownTimeout bounds how long SIGINT/SIGTERM waits for in-flight
+	// requests to drain before closing connections (0 = 10s).
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" json:"shutdown_timeout"`
+
+	// HTTPReadTimeout, HTTPWriteTimeout and HTTPIdleTimeout bound reading a
+	// request, writing its response and keeping an idle connection open
+	// (0 = 30s, 60s and 120s). Watches, dumps and restores are exempt.
+	HTTPReadTimeout  time.Duration `yaml:"http_read_timeout" json:"http_read_timeout"`
+	HTTPWriteTimeout time.Duration `yaml:"http_write_timeout" json:"http_write_timeout"`
+	HTTPIdleTimeout  time.Duration `yaml:"http_idle_timeout" json:"http_idle_timeout"`
+
+	// ForwardTimeout bounds a request a follower relays to the leader,
+	// response body included (0 = 30s).
+	ForwardTimeout time.Duration `yaml:"forward_timeout" json:"forward_timeout"`
+
+	// MetricsExporter selects a push backend for store metrics ("statsd"; empty = off).
+	MetricsExporter       string        `yaml:"metrics_exporter" json:"metrics_exporter"`
+	MetricsExportAddr     string        `yaml:"metrics_export_addr" json:"metrics_export_addr"`
+	MetricsExportInterval time.Duration `yaml:"metrics_export_interval" json:"metrics_export_interval"`
+
+	// MetricsLogInterval logs store metrics periodically (0 = off). With
+	// MetricsLogReset each line covers one interval instead of all time.
+	MetricsLogInterval time.Duration `yaml:"metrics_log_interval" json:"metrics_log_interval"`
+	MetricsLogReset    bool          `yaml:"metrics_log_reset" json:"metrics_log_reset"`
+
+	// MetricsFormat picks the GET /metrics output: "json" (default) or
+	// "prometheus" (text exposition format).
+	MetricsFormat string `yaml:"metrics_format" json:"metrics_format"`
+
+	// HotKeysCapacity tracks the most read keys for GET /metrics/hotkeys,
+	// counting at most this many keys at a time (0 = off).
+	HotKeysCapacity int `yaml:"hot_keys_capacity" json:"hot_keys_capacity"`
+
+	// ReadCacheTTL caches Get results for this long (0 = off). Writes
+	// through this node drop the keys they touch, but writes replicated
+	// from the leader don't, so reads can be up to this stale.
+	// ReadCacheMaxEntries bounds the cached keys (0 = 10000).
+	ReadCacheTTL        time.Duration `yaml:"read_cache_ttl" json:"read_cache_ttl"`
+	ReadCacheMaxEntries int           `yaml:"read_cache_max_entries" json:"read_cache_max_entries"`
+
+	// AccessLog picks the HTTP request log format: "text" (default),
+	// "json" or "off".
+	AccessLog string `yaml:"access_log" json:"access_log"`
+
+	// LogLevel drops log records below it: "debug", "info" (default),
+	// "warn" or "error". LogFormat is "text" (default) or "json".
+	LogLevel  string `yaml:"log_level" json:"log_level"`
+	LogFormat string `yaml:"log_format" json:"log_format"`
+
+	// GzipMinBytes gzip-compresses bulk HTTP read responses of at least
+	// this many bytes for clients that accept it (0 = off).
+	GzipMinBytes int `yaml:"gzip_min_bytes" json:"gzip_min_bytes"`
+
+	// RateLimit allows this many HTTP requests per second per client IP,
+	// in bursts of up to RateLimitBurst (0 = RateLimit); over-limit
+	// requests get 429 (0 = off). TrustForwardedFor takes the client IP
+	// from X-Forwarded-For, for nodes behind a proxy.
+	RateLimit         float64 `yaml:"rate_limit" json:"rate_limit"`
+	RateLimitBurst    int     `yaml:"rate_limit_burst" json:"rate_limit_burst"`
+	TrustForwardedFor bool    `yaml:"trust_forwarded_for" json:"trust_forwarded_for"`
+
+	// MaxConcurrentRequests bounds in-flight unary gRPC calls; the rest
+	// fail with ResourceExhausted (0 = default of 1024).
+	MaxConcurrentRequests int `yaml:"max_concurrent_requests" json:"max_concurrent_requests"`
+
+	// StaleReads decides how a leader that lost quorum contact serves reads:
+	// "allow" (default), "mark", "forward" or "error".
+	StaleReads string `yaml:"stale_reads" json:"stale_reads"`
+
+	// CaseInsensitiveKeys lowercases all keys. Must match on every node.
+	CaseInsensitiveKeys bool `yaml:"case_insensitive_keys" json:"case_insensitive_keys"`
+
+	// EnableRawCommands registers POST /admin/raw-command (debugging only).
+	EnableRawCommands bool `yaml:"enable_raw_commands" json:"enable_raw_commands"`
+
+	// SnapshotCompression encodes Raft snapshots: "none" (default) or "gzip".
+	SnapshotCompression string `yaml:"snapshot_compression" json:"snapshot_compression"`
+
+	// SnapshotInterval, SnapshotThreshold and TrailingLogs tune Raft log
+	// compaction: how often to check, how many new entries trigger a
+	// snapshot, and how many entries to keep after one (0 = Raft default).
+	SnapshotInterval  time.Duration `yaml:"snapshot_interval" json:"snapshot_interval"`
+	SnapshotThreshold int           `yaml:"snapshot_threshold" json:"snapshot_threshold"`
+	TrailingLogs      int           `yaml:"trailing_logs" json:"trailing_logs"`
+
+	// HeartbeatTimeout, ElectionTimeout, LeaderLeaseTimeout and
+	// CommitTimeout tune Raft's failure detection (0 = 2s, 3s, 1s and
+	// 500ms). Raft needs LeaderLeaseTimeout <= HeartbeatTimeout <=
+	// ElectionTimeout.
+	HeartbeatTimeout   time.Duration `yaml:"heartbeat_timeout" json:"heartbeat_timeout"`
+	ElectionTimeout    time.Duration `yaml:"election_timeout" json:"election_timeout"`
+	LeaderLeaseTimeout time.Duration `yaml:"leader_lease_timeout" json:"leader_lease_timeout"`
+	CommitTimeout      time.Duration `yaml:"commit_timeout" json:"commit_timeout"`
+
+	// MaxValueBytes and MaxKeyBytes bound a single /set or Set
+	// (0 = 1 MiB and 4096 bytes).
+	MaxValueBytes int `yaml:"max_value_bytes" json:"max_value_bytes"`
+	MaxKeyBytes   int `yaml:"max_key_bytes" json:"max_key_bytes"`
+
+	// MaxEntryBytes bounds the encoded size of one Raft log entry (0 = 4 MiB).
+	MaxEntryBytes int `yaml:"max_entry_bytes" json:"max_entry_bytes"`
+
+	// ApplyTimeout bounds how long a write waits for Raft to commit it (0 = 5s).
+	ApplyTimeout time.Duration `yaml:"apply_timeout" json:"apply_timeout"`
+
+	// NamespaceQuotas limits keys and bytes per namespace (the part of a
+	// key before the first ':'). Must match on every node.
+	NamespaceQuotas map[string]NamespaceQuota `yaml:"namespace_quotas" json:"namespace_quotas"`
+}
+
+// NamespaceQuota bounds one namespace. Zero means unlimited.
+type NamespaceQuota struct {
+	MaxKeys  int64 `yaml:"max_keys" json:"max_keys"`
+	MaxBytes int64 `yaml:"max_bytes" json:"max_bytes"`
+}
+
+// Redacted returns a copy of the config with secrets masked,
+// suitable for logging or exposing over an admin endpoint.
+func (c *Config) Redacted() Config {
+	out := *c
+	if out.AdminToken != "" {
+		out.AdminToken = "[redacted]"
+	}
+	if out.MandiToken != "" {
+		out.MandiToken = "[redacted]"
+	}
+	return out
 }
 
 // LoadConfig loads configuration from a YAML file if path is provided,
@@ -32,6 +211,9 @@ func LoadConfig(path string) (*Config, error) {
 			}
 			// Apply environment variable overrides
 			applyEnvOverrides(&cfg)
+			if err := validate(&cfg); err != nil {
+				return nil, err
+			}
 			return &cfg, nil
 		}
 		// If path was explicitly provided but file doesn't exist, return error
@@ -44,7 +226,32 @@ func LoadConfig(path string) (*Config, error) {
 	cfg.RaftData = os.Getenv("RAFT_DATA")
 	cfg.GRPCAddr = os.Getenv("GRPC_ADDR")
 	cfg.HTTPAddr = os.Getenv("HTTP_ADDR")
+	cfg.AdminAddr = os.Getenv("ADMIN_ADDR")
 	cfg.MandiAddr = os.Getenv("MANDI_ADDR")
+	cfg.AdminToken = os.Getenv("ADMIN_TOKEN")
+	cfg.MandiToken = os.Getenv("MANDI_TOKEN")
+	cfg.StorageBackend = os.Getenv("STORAGE_BACKEND")
+	cfg.StoragePath = os.Getenv("STORAGE_PATH")
+	if v := os.Getenv("STORAGE_MAX_ENTRIES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid STORAGE_MAX_ENTRIES value: %w", err)
+		}
+		cfg.StorageMaxEntries = n
+	}
+	cfg.TLSCertFile = os.Getenv("TLS_CERT_FILE")
+	cfg.TLSKeyFile = os.Getenv("TLS_KEY_FILE")
+	cfg.TLSCAFile = os.Getenv("TLS_CA_FILE")
+	cfg.FallbackLeaderHTTPAddr = os.Getenv("FALLBACK_LEADER_HTTP_ADDR")
+	cfg.FallbackLeaderGRPCAddr = os.Getenv("FALLBACK_LEADER_GRPC_ADDR")
+	cfg.MetricsExporter = os.Getenv("METRICS_EXPORTER")
+	cfg.MetricsExportAddr = os.Getenv("METRICS_EXPORT_ADDR")
+	cfg.StaleReads = os.Getenv("STALE_READS")
+	cfg.SnapshotCompression = os.Getenv("SNAPSHOT_COMPRESSION")
+	cfg.MetricsFormat = os.Getenv("METRICS_FORMAT")
+	cfg.AccessLog = os.Getenv("ACCESS_LOG")
+	cfg.LogLevel = os.Getenv("LOG_LEVEL")
+	cfg.LogFormat = os.Getenv("LOG_FORMAT")
 
 	// Parse RAFT_LEADER as boolean
 	if leaderStr := os.Getenv("RAFT_LEADER"); leaderStr != "" {
@@ -55,6 +262,275 @@ func LoadConfig(path string) (*Config, error) {
 		cfg.RaftLeader = leader
 	}
 
+	if v := os.Getenv("TTL_JITTER_PERCENT"); v != "" {
+		jitter, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TTL_JITTER_PERCENT value: %w", err)
+		}
+		cfg.TTLJitterPercent = jitter
+	}
+
+	if v := os.Getenv("SOFT_DELETE_WINDOW"); v != "" {
+		window, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SOFT_DELETE_WINDOW value: %w", err)
+		}
+		cfg.SoftDeleteWindow = window
+	}
+
+	if v := os.Getenv("MAX_FORWARD_HOPS"); v != "" {
+		hops, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_FORWARD_HOPS value: %w", err)
+		}
+		cfg.MaxForwardHops = hops
+	}
+
+	if v := os.Getenv("MANDI_STARTUP_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MANDI_STARTUP_TIMEOUT value: %w", err)
+		}
+		cfg.MandiStartupTimeout = timeout
+	}
+
+	if v := os.Getenv("JOIN_REQUEST_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JOIN_REQUEST_TTL value: %w", err)
+		}
+		cfg.JoinRequestTTL = ttl
+	}
+
+	if v := os.Getenv("SHUTDOWN_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SHUTDOWN_TIMEOUT value: %w", err)
+		}
+		cfg.ShutdownTimeout = timeout
+	}
+
+	if v := os.Getenv("HTTP_READ_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HTTP_READ_TIMEOUT value: %w", err)
+		}
+		cfg.HTTPReadTimeout = timeout
+	}
+
+	if v := os.Getenv("HTTP_WRITE_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HTTP_WRITE_TIMEOUT value: %w", err)
+		}
+		cfg.HTTPWriteTimeout = timeout
+	}
+
+	if v := os.Getenv("HTTP_IDLE_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HTTP_IDLE_TIMEOUT value: %w", err)
+		}
+		cfg.HTTPIdleTimeout = timeout
+	}
+
+	if v := os.Getenv("FORWARD_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid FORWARD_TIMEOUT value: %w", err)
+		}
+		cfg.ForwardTimeout = timeout
+	}
+
+	if v := os.Getenv("LISTEN_BACKLOG"); v != "" {
+		backlog, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LISTEN_BACKLOG value: %w", err)
+		}
+		cfg.ListenBacklog = backlog
+	}
+	if v := os.Getenv("REUSE_PORT"); v != "" {
+		reuse, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REUSE_PORT value: %w", err)
+		}
+		cfg.ReusePort = reuse
+	}
+	if v := os.Getenv("CASE_INSENSITIVE_KEYS"); v != "" {
+		fold, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CASE_INSENSITIVE_KEYS value: %w", err)
+		}
+		cfg.CaseInsensitiveKeys = fold
+	}
+	if v := os.Getenv("MAX_ENTRY_BYTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_ENTRY_BYTES value: %w", err)
+		}
+		cfg.MaxEntryBytes = n
+	}
+	if v := os.Getenv("APPLY_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid APPLY_TIMEOUT value: %w", err)
+		}
+		cfg.ApplyTimeout = timeout
+	}
+	if v := os.Getenv("MAX_VALUE_BYTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_VALUE_BYTES value: %w", err)
+		}
+		cfg.MaxValueBytes = n
+	}
+	if v := os.Getenv("MAX_KEY_BYTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_KEY_BYTES value: %w", err)
+		}
+		cfg.MaxKeyBytes = n
+	}
+	if v := os.Getenv("SNAPSHOT_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SNAPSHOT_INTERVAL value: %w", err)
+		}
+		cfg.SnapshotInterval = interval
+	}
+	if v := os.Getenv("SNAPSHOT_THRESHOLD"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SNAPSHOT_THRESHOLD value: %w", err)
+		}
+		cfg.SnapshotThreshold = n
+	}
+	if v := os.Getenv("TRAILING_LOGS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRAILING_LOGS value: %w", err)
+		}
+		cfg.TrailingLogs = n
+	}
+	for _, f := range []struct {
+		env string
+		dst *time.Duration
+	}{
+		{"HEARTBEAT_TIMEOUT", &cfg.HeartbeatTimeout},
+		{"ELECTION_TIMEOUT", &cfg.ElectionTimeout},
+		{"LEADER_LEASE_TIMEOUT", &cfg.LeaderLeaseTimeout},
+		{"COMMIT_TIMEOUT", &cfg.CommitTimeout},
+	} {
+		if v := os.Getenv(f.env); v != "" {
+			timeout, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value: %w", f.env, err)
+			}
+			*f.dst = timeout
+		}
+	}
+	if v := os.Getenv("NAMESPACE_QUOTAS"); v != "" {
+		quotas, err := parseNamespaceQuotas(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid NAMESPACE_QUOTAS value: %w", err)
+		}
+		cfg.NamespaceQuotas = quotas
+	}
+	if v := os.Getenv("ENABLE_RAW_COMMANDS"); v != "" {
+		raw, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ENABLE_RAW_COMMANDS value: %w", err)
+		}
+		cfg.EnableRawCommands = raw
+	}
+
+	if v := os.Getenv("METRICS_EXPORT_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid METRICS_EXPORT_INTERVAL value: %w", err)
+		}
+		cfg.MetricsExportInterval = interval
+	}
+	if v := os.Getenv("METRICS_LOG_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid METRICS_LOG_INTERVAL value: %w", err)
+		}
+		cfg.MetricsLogInterval = interval
+	}
+	if v := os.Getenv("METRICS_LOG_RESET"); v != "" {
+		reset, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid METRICS_LOG_RESET value: %w", err)
+		}
+		cfg.MetricsLogReset = reset
+	}
+	if v := os.Getenv("GZIP_MIN_BYTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GZIP_MIN_BYTES value: %w", err)
+		}
+		cfg.GzipMinBytes = n
+	}
+
+	if v := os.Getenv("MAX_CONCURRENT_REQUESTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_CONCURRENT_REQUESTS value: %w", err)
+		}
+		cfg.MaxConcurrentRequests = n
+	}
+	if v := os.Getenv("RATE_LIMIT"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RATE_LIMIT value: %w", err)
+		}
+		cfg.RateLimit = rate
+	}
+	if v := os.Getenv("RATE_LIMIT_BURST"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RATE_LIMIT_BURST value: %w", err)
+		}
+		cfg.RateLimitBurst = n
+	}
+	if v := os.Getenv("TRUST_FORWARDED_FOR"); v != "" {
+		trust, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRUST_FORWARDED_FOR value: %w", err)
+		}
+		cfg.TrustForwardedFor = trust
+	}
+	if v := os.Getenv("HOT_KEYS_CAPACITY"); v != "" {
+		capacity, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HOT_KEYS_CAPACITY value: %w", err)
+		}
+		cfg.HotKeysCapacity = capacity
+	}
+	if v := os.Getenv("READ_CACHE_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid READ_CACHE_TTL value: %w", err)
+		}
+		cfg.ReadCacheTTL = ttl
+	}
+	if v := os.Getenv("READ_CACHE_MAX_ENTRIES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid READ_CACHE_MAX_ENTRIES value: %w", err)
+		}
+		cfg.ReadCacheMaxEntries = n
+	}
+
+	if err := validate(&cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// validate fills in defaults and checks cfg, however it was loaded.
+func validate(cfg *Config) error {
 	// Set defaults if not provided
 	if cfg.RaftData == "" {
 		cfg.RaftData = fmt.Sprintf("./pyaz/%s", cfg.NodeID)
@@ -62,22 +538,228 @@ func LoadConfig(path string) (*Config, error) {
 	if cfg.MandiAddr == "" {
 		cfg.MandiAddr = "http://127.0.0.1:7000"
 	}
+	if cfg.MetricsExportInterval == 0 {
+		cfg.MetricsExportInterval = 10 * time.Second
+	}
 
 	// Validate required fields
 	if cfg.NodeID == "" {
-		return nil, fmt.Errorf("NODE_ID is required (set via environment or config file)")
+		return fmt.Errorf("NODE_ID is required (set via environment or config file)")
 	}
-	if cfg.RaftAddr == "" {
-		return nil, fmt.Errorf("RAFT_ADDR is required (set via environment or config file)")
+	if cfg.RaftAddr == "" && cfg.StorageBackend != "bolt" && cfg.StorageBackend != "cache" {
+		return fmt.Errorf("RAFT_ADDR is required (set via environment or config file)")
 	}
 	if cfg.GRPCAddr == "" {
-		return nil, fmt.Errorf("GRPC_ADDR is required (set via environment or config file)")
+		return fmt.Errorf("GRPC_ADDR is required (set via environment or config file)")
 	}
 	if cfg.HTTPAddr == "" {
-		return nil, fmt.Errorf("HTTP_ADDR is required (set via environment or config file)")
+		return fmt.Errorf("HTTP_ADDR is required (set via environment or config file)")
+	}
+	if err := validateAddrs(cfg); err != nil {
+		return err
+	}
+	if err := validateRaftTimeouts(cfg); err != nil {
+		return err
+	}
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if cfg.TLSCAFile != "" && cfg.TLSCertFile == "" {
+		return fmt.Errorf("TLS_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	if cfg.TTLJitterPercent < 0 || cfg.TTLJitterPercent > 100 {
+		return fmt.Errorf("TTL_JITTER_PERCENT must be between 0 and 100")
+	}
+	if cfg.MetricsExporter != "" && cfg.MetricsExportAddr == "" {
+		return fmt.Errorf("METRICS_EXPORT_ADDR is required when METRICS_EXPORTER is set")
+	}
+	switch cfg.StaleReads {
+	case "", "allow", "mark", "forward", "error":
+	default:
+		return fmt.Errorf("STALE_READS must be one of allow, mark, forward, error")
+	}
+	switch cfg.SnapshotCompression {
+	case "", "none", "gzip":
+	default:
+		return fmt.Errorf("SNAPSHOT_COMPRESSION must be one of none, gzip")
+	}
+	switch cfg.MetricsFormat {
+	case "", "json", "prometheus":
+	default:
+		return fmt.Errorf("METRICS_FORMAT must be one of json, prometheus")
+	}
+	if cfg.GzipMinBytes < 0 {
+		return fmt.Errorf("GZIP_MIN_BYTES must not be negative")
+	}
+	if cfg.MaxConcurrentRequests < 0 {
+		return fmt.Errorf("MAX_CONCURRENT_REQUESTS must not be negative")
+	}
+	if cfg.RateLimit < 0 || math.IsNaN(cfg.RateLimit) || math.IsInf(cfg.RateLimit, 0) {
+		return fmt.Errorf("RATE_LIMIT must be a non-negative number")
+	}
+	if cfg.RateLimitBurst < 0 {
+		return fmt.Errorf("RATE_LIMIT_BURST must not be negative")
+	}
+	if cfg.HotKeysCapacity < 0 {
+		return fmt.Errorf("HOT_KEYS_CAPACITY must not be negative")
+	}
+	if cfg.ReadCacheTTL < 0 {
+		return fmt.Errorf("READ_CACHE_TTL must not be negative")
+	}
+	if cfg.ReadCacheMaxEntries < 0 {
+		return fmt.Errorf("READ_CACHE_MAX_ENTRIES must not be negative")
+	}
+	switch cfg.StorageBackend {
+	case "", "mem":
+	case "bolt":
+		if cfg.StoragePath == "" {
+			return fmt.Errorf("STORAGE_PATH is required when STORAGE_BACKEND is bolt")
+		}
+	case "cache":
+		if cfg.StorageMaxEntries <= 0 {
+			return fmt.Errorf("STORAGE_MAX_ENTRIES must be positive when STORAGE_BACKEND is cache")
+		}
+	default:
+		return fmt.Errorf("STORAGE_BACKEND must be one of mem, bolt, cache")
+	}
+	if cfg.StorageMaxEntries != 0 && cfg.StorageBackend != "cache" {
+		// Eviction follows each node's own reads, so replicas would diverge.
+		return fmt.Errorf("STORAGE_MAX_ENTRIES only applies to STORAGE_BACKEND=cache")
+	}
+	switch cfg.AccessLog {
+	case "", "text", "json", "off":
+	default:
+		return fmt.Errorf("ACCESS_LOG must be one of text, json, off")
+	}
+	switch strings.ToLower(cfg.LogLevel) {
+	case "", "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("LOG_LEVEL must be one of debug, info, warn, error")
+	}
+	switch cfg.LogFormat {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("LOG_FORMAT must be one of text, json")
+	}
+	if cfg.MetricsLogInterval < 0 {
+		return fmt.Errorf("METRICS_LOG_INTERVAL must not be negative")
+	}
+	if cfg.MaxEntryBytes < 0 {
+		return fmt.Errorf("MAX_ENTRY_BYTES must not be negative")
+	}
+	if cfg.ApplyTimeout < 0 {
+		return fmt.Errorf("APPLY_TIMEOUT must not be negative")
+	}
+	if cfg.MaxValueBytes < 0 {
+		return fmt.Errorf("MAX_VALUE_BYTES must not be negative")
+	}
+	if cfg.MaxKeyBytes < 0 {
+		return fmt.Errorf("MAX_KEY_BYTES must not be negative")
+	}
+	if cfg.MaxKeyBytes > kv.MaxKeyLength {
+		return fmt.Errorf("MAX_KEY_BYTES must not exceed %d", kv.MaxKeyLength)
+	}
+	if cfg.SnapshotInterval < 0 {
+		return fmt.Errorf("SNAPSHOT_INTERVAL must not be negative")
+	}
+	if cfg.SnapshotThreshold < 0 {
+		return fmt.Errorf("SNAPSHOT_THRESHOLD must not be negative")
+	}
+	if cfg.TrailingLogs < 0 {
+		return fmt.Errorf("TRAILING_LOGS must not be negative")
+	}
+	if cfg.SoftDeleteWindow < 0 {
+		return fmt.Errorf("SOFT_DELETE_WINDOW must not be negative")
+	}
+	if cfg.ShutdownTimeout < 0 {
+		return fmt.Errorf("SHUTDOWN_TIMEOUT must not be negative")
+	}
+	if cfg.HTTPReadTimeout < 0 {
+		return fmt.Errorf("HTTP_READ_TIMEOUT must not be negative")
+	}
+	if cfg.HTTPWriteTimeout < 0 {
+		return fmt.Errorf("HTTP_WRITE_TIMEOUT must not be negative")
+	}
+	if cfg.HTTPIdleTimeout < 0 {
+		return fmt.Errorf("HTTP_IDLE_TIMEOUT must not be negative")
+	}
+	if cfg.ForwardTimeout < 0 {
+		return fmt.Errorf("FORWARD_TIMEOUT must not be negative")
+	}
+	if cfg.JoinRequestTTL < 0 || cfg.JoinRequestTTL > time.Hour {
+		return fmt.Errorf("JOIN_REQUEST_TTL must be between 0 and 1h")
+	}
+	for ns, q := range cfg.NamespaceQuotas {
+		if q.MaxKeys < 0 || q.MaxBytes < 0 {
+			return fmt.Errorf("NAMESPACE_QUOTAS for %q must not be negative", ns)
+		}
 	}
 
-	return &cfg, nil
+	return nil
+}
+
+// validateAddrs checks that the listen addresses are host:port and that
+// MANDI_ADDR is a URL with a scheme, so a typo fails at startup rather
+// than deep inside Raft or a forwarded request. Empty fields are skipped.
+func validateAddrs(cfg *Config) error {
+	for _, f := range []struct{ name, value string }{
+		{"RAFT_ADDR", cfg.RaftAddr},
+		{"GRPC_ADDR", cfg.GRPCAddr},
+		{"HTTP_ADDR", cfg.HTTPAddr},
+		{"ADMIN_ADDR", cfg.AdminAddr},
+	} {
+		if f.value == "" {
+			continue
+		}
+		_, port, err := net.SplitHostPort(f.value)
+		if err != nil {
+			return fmt.Errorf("%s %q must be host:port: %w", f.name, f.value, err)
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+			return fmt.Errorf("%s %q has invalid port %q", f.name, f.value, port)
+		}
+	}
+	if cfg.MandiAddr != "" {
+		u, err := url.Parse(cfg.MandiAddr)
+		if err != nil {
+			return fmt.Errorf("MANDI_ADDR %q is not a valid URL: %w", cfg.MandiAddr, err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("MANDI_ADDR %q must be a URL with a scheme and host, e.g. http://127.0.0.1:7000", cfg.MandiAddr)
+		}
+	}
+	return nil
+}
+
+// validateRaftTimeouts fills in the default Raft timeouts and checks the
+// limits raft.ValidateConfig enforces, so a bad combination is reported by
+// name at startup instead of by raft.NewRaft.
+func validateRaftTimeouts(cfg *Config) error {
+	for _, f := range []struct {
+		name string
+		dst  *time.Duration
+		def  time.Duration
+		min  time.Duration
+	}{
+		{"HEARTBEAT_TIMEOUT", &cfg.HeartbeatTimeout, 2 * time.Second, 5 * time.Millisecond},
+		{"ELECTION_TIMEOUT", &cfg.ElectionTimeout, 3 * time.Second, 5 * time.Millisecond},
+		{"LEADER_LEASE_TIMEOUT", &cfg.LeaderLeaseTimeout, time.Second, 5 * time.Millisecond},
+		{"COMMIT_TIMEOUT", &cfg.CommitTimeout, 500 * time.Millisecond, time.Millisecond},
+	} {
+		if *f.dst == 0 {
+			*f.dst = f.def
+		}
+		if *f.dst < f.min {
+			return fmt.Errorf("%s must be at least %s", f.name, f.min)
+		}
+	}
+	if cfg.LeaderLeaseTimeout > cfg.HeartbeatTimeout {
+		return fmt.Errorf("LEADER_LEASE_TIMEOUT (%s) must not exceed HEARTBEAT_TIMEOUT (%s)", cfg.LeaderLeaseTimeout, cfg.HeartbeatTimeout)
+	}
+	if cfg.ElectionTimeout < cfg.HeartbeatTimeout {
+		return fmt.Errorf("ELECTION_TIMEOUT (%s) must be at least HEARTBEAT_TIMEOUT (%s)", cfg.ElectionTimeout, cfg.HeartbeatTimeout)
+	}
+	return nil
 }
 
 // applyEnvOverrides allows environment variables to override YAML config values
@@ -97,12 +779,274 @@ func applyEnvOverrides(cfg *Config) {
 	if v := os.Getenv("HTTP_ADDR"); v != "" {
 		cfg.HTTPAddr = v
 	}
+	if v := os.Getenv("ADMIN_ADDR"); v != "" {
+		cfg.AdminAddr = v
+	}
 	if v := os.Getenv("MANDI_ADDR"); v != "" {
 		cfg.MandiAddr = v
 	}
+	if v := os.Getenv("ADMIN_TOKEN"); v != "" {
+		cfg.AdminToken = v
+	}
+	if v := os.Getenv("MANDI_TOKEN"); v != "" {
+		cfg.MandiToken = v
+	}
+	if v := os.Getenv("TLS_CERT_FILE"); v != "" {
+		cfg.TLSCertFile = v
+	}
+	if v := os.Getenv("TLS_KEY_FILE"); v != "" {
+		cfg.TLSKeyFile = v
+	}
+	if v := os.Getenv("TLS_CA_FILE"); v != "" {
+		cfg.TLSCAFile = v
+	}
+	if v := os.Getenv("TTL_JITTER_PERCENT"); v != "" {
+		if jitter, err := strconv.Atoi(v); err == nil {
+			cfg.TTLJitterPercent = jitter
+		}
+	}
+	if v := os.Getenv("SOFT_DELETE_WINDOW"); v != "" {
+		if window, err := time.ParseDuration(v); err == nil {
+			cfg.SoftDeleteWindow = window
+		}
+	}
+	if v := os.Getenv("MAX_FORWARD_HOPS"); v != "" {
+		if hops, err := strconv.Atoi(v); err == nil {
+			cfg.MaxForwardHops = hops
+		}
+	}
+	if v := os.Getenv("MANDI_STARTUP_TIMEOUT"); v != "" {
+		if timeout, err := time.ParseDuration(v); err == nil {
+			cfg.MandiStartupTimeout = timeout
+		}
+	}
+	if v := os.Getenv("JOIN_REQUEST_TTL"); v != "" {
+		if ttl, err := time.ParseDuration(v); err == nil {
+			cfg.JoinRequestTTL = ttl
+		}
+	}
+	if v := os.Getenv("SHUTDOWN_TIMEOUT"); v != "" {
+		if timeout, err := time.ParseDuration(v); err == nil {
+			cfg.ShutdownTimeout = timeout
+		}
+	}
+	if v := os.Getenv("HTTP_READ_TIMEOUT"); v != "" {
+		if timeout, err := time.ParseDuration(v); err == nil {
+			cfg.HTTPReadTimeout = timeout
+		}
+	}
+	if v := os.Getenv("HTTP_WRITE_TIMEOUT"); v != "" {
+		if timeout, err := time.ParseDuration(v); err == nil {
+			cfg.HTTPWriteTimeout = timeout
+		}
+	}
+	if v := os.Getenv("HTTP_IDLE_TIMEOUT"); v != "" {
+		if timeout, err := time.ParseDuration(v); err == nil {
+			cfg.HTTPIdleTimeout = timeout
+		}
+	}
+	if v := os.Getenv("FORWARD_TIMEOUT"); v != "" {
+		if timeout, err := time.ParseDuration(v); err == nil {
+			cfg.ForwardTimeout = timeout
+		}
+	}
+	if v := os.Getenv("FALLBACK_LEADER_HTTP_ADDR"); v != "" {
+		cfg.FallbackLeaderHTTPAddr = v
+	}
+	if v := os.Getenv("FALLBACK_LEADER_GRPC_ADDR"); v != "" {
+		cfg.FallbackLeaderGRPCAddr = v
+	}
+	if v := os.Getenv("LISTEN_BACKLOG"); v != "" {
+		if backlog, err := strconv.Atoi(v); err == nil {
+			cfg.ListenBacklog = backlog
+		}
+	}
+	if v := os.Getenv("REUSE_PORT"); v != "" {
+		if reuse, err := strconv.ParseBool(v); err == nil {
+			cfg.ReusePort = reuse
+		}
+	}
+	if v := os.Getenv("CASE_INSENSITIVE_KEYS"); v != "" {
+		if fold, err := strconv.ParseBool(v); err == nil {
+			cfg.CaseInsensitiveKeys = fold
+		}
+	}
+	if v := os.Getenv("MAX_ENTRY_BYTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.MaxEntryBytes = n
+		}
+	}
+	if v := os.Getenv("APPLY_TIMEOUT"); v != "" {
+		if timeout, err := time.ParseDuration(v); err == nil {
+			cfg.ApplyTimeout = timeout
+		}
+	}
+	if v := os.Getenv("MAX_VALUE_BYTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.MaxValueBytes = n
+		}
+	}
+	if v := os.Getenv("MAX_KEY_BYTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.MaxKeyBytes = n
+		}
+	}
+	if v := os.Getenv("SNAPSHOT_INTERVAL"); v != "" {
+		if interval, err := time.ParseDuration(v); err == nil {
+			cfg.SnapshotInterval = interval
+		}
+	}
+	if v := os.Getenv("SNAPSHOT_THRESHOLD"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.SnapshotThreshold = n
+		}
+	}
+	if v := os.Getenv("TRAILING_LOGS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.TrailingLogs = n
+		}
+	}
+	for _, f := range []struct {
+		env string
+		dst *time.Duration
+	}{
+		{"HEARTBEAT_TIMEOUT", &cfg.HeartbeatTimeout},
+		{"ELECTION_TIMEOUT", &cfg.ElectionTimeout},
+		{"LEADER_LEASE_TIMEOUT", &cfg.LeaderLeaseTimeout},
+		{"COMMIT_TIMEOUT", &cfg.CommitTimeout},
+	} {
+		if v := os.Getenv(f.env); v != "" {
+			if timeout, err := time.ParseDuration(v); err == nil {
+				*f.dst = timeout
+			}
+		}
+	}
+	if v := os.Getenv("NAMESPACE_QUOTAS"); v != "" {
+		if quotas, err := parseNamespaceQuotas(v); err == nil {
+			cfg.NamespaceQuotas = quotas
+		}
+	}
+	if v := os.Getenv("ENABLE_RAW_COMMANDS"); v != "" {
+		if raw, err := strconv.ParseBool(v); err == nil {
+			cfg.EnableRawCommands = raw
+		}
+	}
+	if v := os.Getenv("METRICS_EXPORTER"); v != "" {
+		cfg.MetricsExporter = v
+	}
+	if v := os.Getenv("METRICS_EXPORT_ADDR"); v != "" {
+		cfg.MetricsExportAddr = v
+	}
+	if v := os.Getenv("METRICS_EXPORT_INTERVAL"); v != "" {
+		if interval, err := time.ParseDuration(v); err == nil {
+			cfg.MetricsExportInterval = interval
+		}
+	}
+	if v := os.Getenv("METRICS_LOG_INTERVAL"); v != "" {
+		if interval, err := time.ParseDuration(v); err == nil {
+			cfg.MetricsLogInterval = interval
+		}
+	}
+	if v := os.Getenv("METRICS_LOG_RESET"); v != "" {
+		if reset, err := strconv.ParseBool(v); err == nil {
+			cfg.MetricsLogReset = reset
+		}
+	}
+	if v := os.Getenv("HOT_KEYS_CAPACITY"); v != "" {
+		if capacity, err := strconv.Atoi(v); err == nil {
+			cfg.HotKeysCapacity = capacity
+		}
+	}
+	if v := os.Getenv("READ_CACHE_TTL"); v != "" {
+		if ttl, err := time.ParseDuration(v); err == nil {
+			cfg.ReadCacheTTL = ttl
+		}
+	}
+	if v := os.Getenv("READ_CACHE_MAX_ENTRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.ReadCacheMaxEntries = n
+		}
+	}
+	if v := os.Getenv("STALE_READS"); v != "" {
+		cfg.StaleReads = v
+	}
+	if v := os.Getenv("SNAPSHOT_COMPRESSION"); v != "" {
+		cfg.SnapshotCompression = v
+	}
+	if v := os.Getenv("METRICS_FORMAT"); v != "" {
+		cfg.MetricsFormat = v
+	}
+	if v := os.Getenv("ACCESS_LOG"); v != "" {
+		cfg.AccessLog = v
+	}
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		cfg.LogLevel = v
+	}
+	if v := os.Getenv("LOG_FORMAT"); v != "" {
+		cfg.LogFormat = v
+	}
+	if v := os.Getenv("GZIP_MIN_BYTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.GzipMinBytes = n
+		}
+	}
+	if v := os.Getenv("MAX_CONCURRENT_REQUESTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.MaxConcurrentRequests = n
+		}
+	}
+	if v := os.Getenv("RATE_LIMIT"); v != "" {
+		if rate, err := strconv.ParseFloat(v, 64); err == nil {
+			cfg.RateLimit = rate
+		}
+	}
+	if v := os.Getenv("RATE_LIMIT_BURST"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.RateLimitBurst = n
+		}
+	}
+	if v := os.Getenv("TRUST_FORWARDED_FOR"); v != "" {
+		if trust, err := strconv.ParseBool(v); err == nil {
+			cfg.TrustForwardedFor = trust
+		}
+	}
+	if v := os.Getenv("STORAGE_BACKEND"); v != "" {
+		cfg.StorageBackend = v
+	}
+	if v := os.Getenv("STORAGE_PATH"); v != "" {
+		cfg.StoragePath = v
+	}
+	if v := os.Getenv("STORAGE_MAX_ENTRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.StorageMaxEntries = n
+		}
+	}
 	if v := os.Getenv("RAFT_LEADER"); v != "" {
 		if leader, err := strconv.ParseBool(v); err == nil {
 			cfg.RaftLeader = leader
 		}
 	}
 }
+
+// parseNamespaceQuotas parses "ns=maxkeys:maxbytes" pairs separated by
+// commas, e.g. "tenant-a=10000:67108864,tenant-b=500:0".
+func parseNamespaceQuotas(v string) (map[string]NamespaceQuota, error) {
+	quotas := make(map[string]NamespaceQuota)
+	for _, entry := range strings.Split(v, ",") {
+		ns, limits, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		keys, bytes, ok2 := strings.Cut(limits, ":")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("%q is not ns=maxkeys:maxbytes", entry)
+		}
+		maxKeys, err := strconv.ParseInt(keys, 10, 64)
+		if err != nil || maxKeys < 0 {
+			return nil, fmt.Errorf("%q: invalid max keys", entry)
+		}
+		maxBytes, err := strconv.ParseInt(bytes, 10, 64)
+		if err != nil || maxBytes < 0 {
+			return nil, fmt.Errorf("%q: invalid max bytes", entry)
+		}
+		quotas[ns] = NamespaceQuota{MaxKeys: maxKeys, MaxBytes: maxBytes}
+	}
+	return quotas, nil
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const baseYAML = `node_id: node1
+raft_addr: "127.0.0.1:12000"
+grpc_addr: ":9090"
+http_addr: ":8080"
+`
+
+// loadYAML writes extra after a valid base config and loads it.
+func loadYAML(t *testing.T, extra string) (*Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "node.yaml")
+	if err := os.WriteFile(path, []byte(baseYAML+extra), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return LoadConfig(path)
+}
+
+// The YAML path must reject everything the environment path does.
+func TestLoadConfigYAMLValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		extra   string
+		wantErr string // empty = valid
+	}{
+		{"valid", "", ""},
+		{"cert without key", "tls_cert_file: c.pem\n", "TLS_CERT_FILE and TLS_KEY_FILE"},
+		{"unknown backend", "storage_backend: rocks\n", "STORAGE_BACKEND"},
+		{"bolt without path", "storage_backend: bolt\n", "STORAGE_PATH"},
+		{"cache without bound", "storage_backend: cache\n", "STORAGE_MAX_ENTRIES"},
+		{"jitter over 100", "ttl_jitter_percent: 150\n", "TTL_JITTER_PERCENT"},
+		{"key limit over max", "max_key_bytes: 100000\n", "MAX_KEY_BYTES"},
+		{"join ttl over 1h", "join_request_ttl: 2h\n", "JOIN_REQUEST_TTL"},
+		{"unknown stale reads", "stale_reads: sometimes\n", "STALE_READS"},
+		{"unknown log level", "log_level: loud\n", "LOG_LEVEL"},
+		{"negative gzip", "gzip_min_bytes: -1\n", "GZIP_MIN_BYTES"},
+		{"negative apply timeout", "apply_timeout: -1s\n", "APPLY_TIMEOUT"},
+		{"negative quota", "namespace_quotas:\n  a:\n    max_keys: -1\n", "NAMESPACE_QUOTAS"},
+		{"bad admin addr", "admin_addr: \"9100\"\n", "ADMIN_ADDR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadYAML(t, tt.extra)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("LoadConfig: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadConfig error = %v, want one mentioning %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigYAMLDefaults(t *testing.T) {
+	cfg, err := loadYAML(t, "")
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.RaftData != "./pyaz/node1" {
+		t.Errorf("RaftData = %q, want ./pyaz/node1", cfg.RaftData)
+	}
+	if cfg.MandiAddr != "http://127.0.0.1:7000" {
+		t.Errorf("MandiAddr = %q, want the local default", cfg.MandiAddr)
+	}
+	if cfg.HeartbeatTimeout == 0 {
+		t.Error("HeartbeatTimeout default not applied")
+	}
+}
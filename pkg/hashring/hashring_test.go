@@ -0,0 +1,132 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+)
+
+func keys(n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = fmt.Sprintf("user/%d", i)
+	}
+	return out
+}
+
+func TestDistribution(t *testing.T) {
+	tests := []struct {
+		nodes    int
+		replicas int
+	}{
+		{3, 0},
+		{5, 0},
+		{10, 256},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d nodes", tt.nodes), func(t *testing.T) {
+			r := New(tt.replicas)
+			for i := 0; i < tt.nodes; i++ {
+				r.Add(fmt.Sprintf("node%d", i))
+			}
+			counts := make(map[string]int)
+			ks := keys(100000)
+			for _, k := range ks {
+				counts[r.Get(k)]++
+			}
+			if len(counts) != tt.nodes {
+				t.Fatalf("keys landed on %d nodes, want %d", len(counts), tt.nodes)
+			}
+			// Every node should own its fair share to within a third.
+			fair := len(ks) / tt.nodes
+			for node, n := range counts {
+				if n < fair*2/3 || n > fair*4/3 {
+					t.Errorf("%s owns %d keys, fair share is %d", node, n, fair)
+				}
+			}
+		})
+	}
+}
+
+func TestMinimalRemapping(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*Ring)
+		// only reports whether a key that moved went where it should.
+		only func(before, after string) bool
+	}{
+		{
+			name:   "add",
+			change: func(r *Ring) { r.Add("node4") },
+			only:   func(before, after string) bool { return after == "node4" },
+		},
+		{
+			name:   "remove",
+			change: func(r *Ring) { r.Remove("node1") },
+			only:   func(before, after string) bool { return before == "node1" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New(0)
+			for i := 0; i < 4; i++ {
+				r.Add(fmt.Sprintf("node%d", i))
+			}
+			ks := keys(50000)
+			before := make([]string, len(ks))
+			for i, k := range ks {
+				before[i] = r.Get(k)
+			}
+			tt.change(r)
+			moved := 0
+			for i, k := range ks {
+				after := r.Get(k)
+				if after == before[i] {
+					continue
+				}
+				moved++
+				if !tt.only(before[i], after) {
+					t.Fatalf("%s moved from %s to %s", k, before[i], after)
+				}
+			}
+			// Four nodes becoming five (or three) should move about a
+			// fifth (or a quarter) of the keys, never most of them.
+			if moved == 0 || moved > len(ks)/3 {
+				t.Errorf("%d of %d keys moved", moved, len(ks))
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	r := New(0)
+	if got := r.Get("k"); got != "" {
+		t.Errorf("Get on an empty ring = %q, want empty", got)
+	}
+	r.Add("a")
+	r.Add("a")
+	if got := r.Nodes(); len(got) != 1 {
+		t.Errorf("Nodes after adding a twice = %v", got)
+	}
+	if got := r.Get("k"); got != "a" {
+		t.Errorf("Get with one node = %q, want a", got)
+	}
+	r.Remove("missing")
+	r.Remove("a")
+	if got := r.Get("k"); got != "" {
+		t.Errorf("Get after removing the only node = %q, want empty", got)
+	}
+
+	// Rings built from the same nodes in a different order agree.
+	x, y := New(0), New(0)
+	for _, n := range []string{"a", "b", "c"} {
+		x.Add(n)
+	}
+	for _, n := range []string{"c", "a", "b"} {
+		y.Add(n)
+	}
+	for _, k := range keys(1000) {
+		if x.Get(k) != y.Get(k) {
+			t.Fatalf("rings disagree on %s: %s vs %s", k, x.Get(k), y.Get(k))
+		}
+	}
+}
@@ -1,5 +1,82 @@
 package kv
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrNotSupported is returned by store wrappers when the wrapped store
+// lacks an optional capability such as TTLs.
+var ErrNotSupported = errors.New("operation not supported by this store")
+
+// ErrQuotaExceeded is returned when a write would take a namespace past
+// its configured key or byte limit.
+var ErrQuotaExceeded = errors.New("namespace quota exceeded")
+
+// ErrTooLarge is returned when a write is too large to replicate.
+var ErrTooLarge = errors.New("write too large")
+
+// ErrApplyTimeout is returned when a replicated write is not committed
+// within the store's apply timeout, e.g. because the cluster lost quorum.
+// It matches context.DeadlineExceeded with errors.Is. The write may still
+// be applied later.
+var ErrApplyTimeout = fmt.Errorf("write not committed in time: %w", context.DeadlineExceeded)
+
+// ErrAliasCycle is returned when an alias would point back at itself or
+// its chain would be longer than MaxAliasDepth.
+var ErrAliasCycle = errors.New("alias cycle or chain too deep")
+
+// ErrDanglingAlias is returned when an alias chain ends at a missing key.
+var ErrDanglingAlias = errors.New("alias target does not exist")
+
+// ErrAliasIsKey is returned when an alias would take the name of an
+// existing key.
+var ErrAliasIsKey = errors.New("alias name is already a key")
+
+// ErrNotInteger is returned when incrementing a key whose value is not a
+// base-10 integer, or when the result would overflow an int64.
+var ErrNotInteger = errors.New("value is not an integer or would overflow")
+
+// NamespaceSeparator ends the namespace part of a key, as in "tenant-a:foo".
+const NamespaceSeparator = ":"
+
+// NamespaceOf returns the namespace of key: the part before the first
+// NamespaceSeparator, or "" (the global namespace) if there is none.
+func NamespaceOf(key string) string {
+	ns, _, ok := strings.Cut(key, NamespaceSeparator)
+	if !ok {
+		return ""
+	}
+	return ns
+}
+
+// MaxKeyLength is the longest key any store accepts, in bytes: the limit
+// of the bolt backend. Servers usually enforce a lower, configurable one.
+const MaxKeyLength = 32 << 10
+
+// ValidateKey checks that key is non-empty, at most MaxKeyLength bytes and
+// free of control characters such as newlines and NUL, which break
+// line-based output and C-string consumers downstream. Other Unicode is
+// allowed.
+func ValidateKey(key string) error {
+	if key == "" {
+		return errors.New("key is required")
+	}
+	if len(key) > MaxKeyLength {
+		return fmt.Errorf("key is %d bytes, limit is %d", len(key), MaxKeyLength)
+	}
+	for i, r := range key {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("key contains control character %U at byte %d", r, i)
+		}
+	}
+	return nil
+}
+
 // Store defines the interface for a key-value store.
 // Implementations of this interface can be swapped out,
 // allowing for different storage backends (e.g., in-memory, Raft-replicated).
@@ -15,4 +92,307 @@ type Store interface {
 	// Delete removes a key from the store.
 	// Returns an error if the operation fails.
 	Delete(key string) error
+
+	// Scan returns the key-value pairs whose key starts with prefix, sorted by key.
+	// At most limit pairs are returned; a limit <= 0 means no limit.
+	Scan(prefix string, limit int) ([]KeyValue, error)
+
+	// Exists reports whether key is present, without copying its value.
+	Exists(key string) (bool, error)
+
+	// MultiGet retrieves several keys at once. Missing keys are absent
+	// from the returned map.
+	MultiGet(keys []string) (map[string]string, error)
+}
+
+// KeyValue is a single key-value pair returned by Scan.
+type KeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// ReplicatedStore is implemented by stores that can hold back a write's
+// acknowledgment until it has reached a minimum number of replicas.
+type ReplicatedStore interface {
+	// SetReplicated stores a key-value pair and waits, for a bounded time,
+	// until at least replicas nodes (leader included) are known to hold
+	// it. It returns how many were confirmed, which is below replicas if
+	// the wait ran out. An error means the write itself failed; once it
+	// is committed, falling short of replicas is not an error.
+	SetReplicated(key, value string, replicas int) (int, error)
+}
+
+// TTLStore is implemented by stores that support key expiration.
+type TTLStore interface {
+	// SetWithTTL stores a key-value pair that is treated as missing once ttl has elapsed.
+	SetWithTTL(key, value string, ttl time.Duration) error
+
+	// Touch resets an existing key's TTL without changing its value.
+	// Returns false if the key doesn't exist.
+	Touch(key string, ttl time.Duration) (bool, error)
+
+	// ExpirePrefix sets the same TTL on every key starting with prefix.
+	// Returns the number of keys affected.
+	ExpirePrefix(prefix string, ttl time.Duration) (int, error)
+}
+
+// ConditionalStore is implemented by stores that can make a write depend
+// on the key's current state atomically, as needed for distributed locks.
+type ConditionalStore interface {
+	// SetNXWithTTL stores a key-value pair expiring after ttl only if the key
+	// doesn't exist. Returns whether the key was set.
+	SetNXWithTTL(key, value string, ttl time.Duration) (bool, error)
+
+	// DeleteIf deletes key only if its current value equals value.
+	// Returns whether the key was deleted.
+	DeleteIf(key, value string) (bool, error)
+
+	// CompareAndSwap replaces key's value with new only if its current
+	// value equals old; a missing key never matches. Returns whether the
+	// swap happened. A mismatch is not an error.
+	CompareAndSwap(key, old, new string) (bool, error)
+}
+
+// CounterStore is implemented by stores with atomic integer counters.
+type CounterStore interface {
+	// Increment adds delta to key's integer value and returns the new
+	// total. A missing key counts as 0. Returns ErrNotInteger if the
+	// current value is not an integer or the sum would overflow.
+	Increment(key string, delta int64) (int64, error)
+}
+
+// MaxAliasDepth is the most aliases a chain may pass through before
+// reaching a key.
+const MaxAliasDepth = 8
+
+// AliasStore is implemented by stores that let one name point at another
+// key, as in "current" -> "v42".
+type AliasStore interface {
+	// SetAlias points alias at target, which may itself be an alias; an
+	// empty target removes the alias. Returns ErrAliasCycle or
+	// ErrDanglingAlias if target does not resolve to an existing key, and
+	// ErrAliasIsKey if alias names one.
+	SetAlias(alias, target string) error
+
+	// ResolveAlias returns the key that key ultimately names: key itself
+	// if it isn't an alias.
+	ResolveAlias(key string) (string, error)
+}
+
+// UndeleteStore is implemented by stores that soft-delete keys and can
+// recover them within a grace period.
+type UndeleteStore interface {
+	// Undelete restores a soft-deleted key.
+	// Returns false if the key has no tombstone or its grace period has passed.
+	Undelete(key string) (bool, error)
+}
+
+// DeleteReportStore is implemented by stores that can tell a delete that
+// removed a key from one that found nothing, e.g. for idempotency checks.
+type DeleteReportStore interface {
+	// DeleteReport behaves like Delete and reports whether key existed
+	// (was visible) when the delete took effect, along with the write's
+	// commit index on replicated stores (0 otherwise).
+	DeleteReport(key string) (existed bool, index uint64, err error)
+}
+
+// ContextStore is implemented by stores whose writes can block, e.g. on
+// consensus, and can stop waiting when ctx is done. The write may still
+// take effect after the caller gave up; only the wait is abandoned.
+type ContextStore interface {
+	// SetCtx behaves like SetIndexed but returns ctx.Err() if ctx is done
+	// before the write commits.
+	SetCtx(ctx context.Context, key, value string) (uint64, error)
+	// DeleteCtx behaves like DeleteReport but returns ctx.Err() if ctx is
+	// done before the delete commits.
+	DeleteCtx(ctx context.Context, key string) (existed bool, index uint64, err error)
+}
+
+// MaxRequestIDLength is the longest request ID IdempotentStore accepts, in
+// bytes. UUIDs and similar tokens fit comfortably.
+const MaxRequestIDLength = 128
+
+// ValidateRequestID checks that id is non-empty and at most
+// MaxRequestIDLength bytes.
+func ValidateRequestID(id string) error {
+	if id == "" {
+		return errors.New("request ID is required")
+	}
+	if len(id) > MaxRequestIDLength {
+		return fmt.Errorf("request ID is %d bytes, limit is %d", len(id), MaxRequestIDLength)
+	}
+	return nil
+}
+
+// IdempotentStore is implemented by stores that can deduplicate retried
+// writes. Each write carries a client-chosen request ID; a write whose ID
+// was applied recently is not applied again, and returns the result of
+// the first one instead. IDs must be unique per write: reusing one for a
+// different write returns the old result without performing it.
+type IdempotentStore interface {
+	// SetIdempotent behaves like SetCtx.
+	SetIdempotent(ctx context.Context, requestID, key, value string) (uint64, error)
+	// IncrementIdempotent behaves like Increment.
+	IncrementIdempotent(requestID, key string, delta int64) (int64, error)
+	// BatchIdempotent behaves like Batch.
+	BatchIdempotent(requestID string, ops []BatchOp) error
+}
+
+// IndexedStore is implemented by replicated stores that can report the log
+// index at which a write was committed, e.g. for read-your-writes checks.
+type IndexedStore interface {
+	// SetIndexed behaves like Set and returns the write's commit index.
+	SetIndexed(key, value string) (uint64, error)
+
+	// DeleteIndexed behaves like Delete and returns the write's commit index.
+	DeleteIndexed(key string) (uint64, error)
+}
+
+// BatchOp is a single set or delete inside a batch.
+type BatchOp struct {
+	Op    string // "set" or "delete"
+	Key   string
+	Value string // only for set
+}
+
+// BatchStore is implemented by stores that can apply several writes atomically.
+type BatchStore interface {
+	// Batch applies ops in order as one atomic write: readers see either
+	// none or all of them.
+	Batch(ops []BatchOp) error
+}
+
+// ValidateBatch checks that every op is a set or delete with a valid key.
+func ValidateBatch(ops []BatchOp) error {
+	for i, op := range ops {
+		if op.Op != "set" && op.Op != "delete" {
+			return fmt.Errorf("op %d: unknown op %q", i, op.Op)
+		}
+		if err := ValidateKey(op.Key); err != nil {
+			return fmt.Errorf("op %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Event types delivered to watchers.
+const (
+	EventInitial = "INITIAL" // a key present when the watch started
+	EventSet     = "SET"
+	EventDelete  = "DELETE"
+)
+
+// Event is a change to a watched key. Index is the log index of the write,
+// or for INITIAL events the index the initial snapshot was taken at.
+type Event struct {
+	Type  string
+	Key   string
+	Value string // empty for DELETE
+	Index uint64
+}
+
+// WatchOptions selects the keys a watch observes: Key for a single key,
+// otherwise every key starting with Prefix.
+type WatchOptions struct {
+	Key            string
+	Prefix         string
+	IncludeInitial bool // first send the matching keys as INITIAL events
+}
+
+// Matches reports whether key is selected by o.
+func (o WatchOptions) Matches(key string) bool {
+	if o.Key != "" {
+		return key == o.Key
+	}
+	return strings.HasPrefix(key, o.Prefix)
+}
+
+// WatchStore is implemented by stores that can stream changes to keys.
+type WatchStore interface {
+	// Watch streams events for the keys selected by opts until ctx is done.
+	// The channel is closed when ctx is done, or early if the consumer
+	// falls too far behind; callers tell the two apart by checking ctx.
+	Watch(ctx context.Context, opts WatchOptions) (<-chan Event, error)
+}
+
+// Usage is the number of keys and bytes (keys plus values) held in a namespace.
+type Usage struct {
+	Keys  int64 `json:"keys"`
+	Bytes int64 `json:"bytes"`
+}
+
+// UsageStore is implemented by stores that track usage per namespace.
+type UsageStore interface {
+	// NamespaceUsage returns the current usage of every non-empty namespace.
+	NamespaceUsage() map[string]Usage
+}
+
+// SizeStore is implemented by stores that can report their size without
+// walking every key.
+type SizeStore interface {
+	// Len returns the number of keys held.
+	Len() int
+	// ApproxBytes returns the summed length of every key and value held,
+	// leaving out per-entry overhead.
+	ApproxBytes() int64
+}
+
+// AgeStore is implemented by stores that record when keys were created and
+// last updated, and can find the extremes within a prefix.
+type AgeStore interface {
+	// Oldest returns the key under prefix with the earliest creation time,
+	// or the earliest update time if byUpdate is set.
+	Oldest(prefix string, byUpdate bool) (string, Entry, bool)
+
+	// Newest returns the key under prefix with the latest creation time,
+	// or the latest update time if byUpdate is set.
+	Newest(prefix string, byUpdate bool) (string, Entry, bool)
+}
+
+// Label limits keep per-key metadata small; labels are replicated with
+// every key and held in memory on every node.
+const (
+	MaxLabelsPerKey = 16
+	MaxLabelName    = 64
+	MaxLabelValue   = 256
+)
+
+// Entry is a value together with its metadata, as returned by GetWithMeta.
+type Entry struct {
+	Value     string
+	Labels    map[string]string
+	ExpiresAt time.Time // zero if the key has no TTL
+	CreatedAt time.Time // zero if the store doesn't record timestamps
+	UpdatedAt time.Time
+}
+
+// LabelStore is implemented by stores that can attach small key-value
+// labels to keys and query keys by label.
+type LabelStore interface {
+	// SetLabels replaces the labels of an existing key; an empty map clears them.
+	// Returns false if the key doesn't exist.
+	SetLabels(key string, labels map[string]string) (bool, error)
+
+	// GetWithMeta retrieves a key's value along with its labels and expiry.
+	GetWithMeta(key string) (Entry, bool)
+
+	// ScanLabel returns the pairs whose key starts with prefix and carries
+	// the label name=value, sorted by key. A limit <= 0 means no limit.
+	ScanLabel(prefix, name, value string, limit int) ([]KeyValue, error)
+}
+
+// ValidateLabels checks labels against the per-key limits.
+func ValidateLabels(labels map[string]string) error {
+	if len(labels) > MaxLabelsPerKey {
+		return fmt.Errorf("at most %d labels per key", MaxLabelsPerKey)
+	}
+	for name, value := range labels {
+		if name == "" || len(name) > MaxLabelName {
+			return fmt.Errorf("label names must be 1-%d bytes", MaxLabelName)
+		}
+		if len(value) > MaxLabelValue {
+			return fmt.Errorf("label %q: values must be at most %d bytes", name, MaxLabelValue)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,42 @@
+package kv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		valid bool
+	}{
+		{"ascii", "user/42", true},
+		{"spaces and punctuation", "a b:c-d_e.f", true},
+		{"accented", "café", true},
+		{"cjk", "用户/配置", true},
+		{"emoji", "🔑/🚀", true},
+		{"right-to-left", "مفتاح", true},
+		{"at the length limit", strings.Repeat("k", MaxKeyLength), true},
+		{"empty", "", false},
+		{"over the length limit", strings.Repeat("k", MaxKeyLength+1), false},
+		{"newline", "a\nb", false},
+		{"carriage return", "a\rb", false},
+		{"tab", "a\tb", false},
+		{"nul", "a\x00b", false},
+		{"escape", "\x1b[31m", false},
+		{"delete", "a\x7fb", false},
+		{"c1 control", "a\u0085b", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateKey(tt.key)
+			if tt.valid && err != nil {
+				t.Errorf("ValidateKey(%q) = %v, want nil", tt.key, err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("ValidateKey(%q) = nil, want an error", tt.key)
+			}
+		})
+	}
+}